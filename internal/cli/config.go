@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/charliek/codely/internal/config"
+	"github.com/charliek/codely/internal/constants"
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/pathutil"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	configInitForce     bool
+	configShowOrigin    bool
+	configMigrateDryRun bool
+)
+
+// configCmd groups config file subcommands
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the codely config file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check a config file for errors and warnings",
+	Long: `Strictly parse a config file and report unknown keys, invalid values and
+warnings with line numbers. Defaults to the file given by --config. A
+per-project .codely.yaml is checked as merged over the global config.
+
+Exits non-zero if the file has errors.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigValidate,
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a commented default config file",
+	Long: `Write the default configuration, with every option documented, to the path
+given by --config. Refuses to overwrite an existing file unless --force is set.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigInit,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective config",
+	Long: `Print the effective configuration after merging built-in defaults, the system
+config file (` + constants.SystemConfigPath + `), the user config file given by --config
+and CODELY_* environment variables.
+
+With --origin, print each value with the layer it came from.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigShow,
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate [file]",
+	Short: "Upgrade a config file to the current schema version",
+	Long: `Rewrite a config file written for an older schema version in the current
+format, keeping its comments and behavior. Defaults to the file given by
+--config. Older files are also migrated in memory whenever they are loaded.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigMigrate,
+}
+
+func init() {
+	configMigrateCmd.Flags().BoolVar(&configMigrateDryRun, "dry-run", false, "Print the migrated file instead of writing it")
+	configShowCmd.Flags().BoolVar(&configShowOrigin, "origin", false, "Show where each value came from")
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "Overwrite an existing config file")
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configMigrateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	path := configPath
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	out := cmd.OutOrStdout()
+	cfg, err := loadConfigForValidate(path)
+	if err != nil {
+		cmd.SilenceUsage = true
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			for _, d := range verr.Diagnostics {
+				fmt.Fprintf(out, "error: %s\n", d)
+			}
+			return fmt.Errorf("%s: %d error(s)", path, len(verr.Diagnostics))
+		}
+		if errors.Is(err, domain.ErrConfigNotFound) {
+			return fmt.Errorf("%w (run `codely config init` to create one)", err)
+		}
+		return err
+	}
+
+	for _, w := range cfg.Warnings {
+		fmt.Fprintf(out, "warning: %s\n", w)
+	}
+	fmt.Fprintf(out, "%s: ok (%d warning(s))\n", path, len(cfg.Warnings))
+	return nil
+}
+
+// loadConfigForValidate loads a global config file, or a per-project
+// .codely.yaml merged over the global config
+func loadConfigForValidate(path string) (*config.Config, error) {
+	if filepath.Base(path) != config.ProjectConfigFile {
+		return config.Load(path)
+	}
+
+	global, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrConfigNotFound, path)
+	}
+	pc, err := config.LoadProject(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	return global.WithProject(pc), nil
+}
+
+func runConfigInit(cmd *cobra.Command, args []string) error {
+	path := pathutil.ExpandPath(configPath)
+
+	if _, err := os.Stat(path); err == nil && !configInitForce {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+
+	data, err := config.DefaultYAML()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing config file: %w", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s\n", path)
+	return nil
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if !configShowOrigin {
+		data, err := yaml.Marshal(cfg)
+		if err != nil {
+			return fmt.Errorf("encoding config: %w", err)
+		}
+		_, err = out.Write(data)
+		return err
+	}
+
+	values, err := cfg.Values()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tORIGIN")
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		origin, ok := cfg.Origins[key]
+		if !ok {
+			origin = config.OriginDefault
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", key, values[key], origin)
+	}
+	return w.Flush()
+}
+
+func runConfigMigrate(cmd *cobra.Command, args []string) error {
+	path := configPath
+	if len(args) == 1 {
+		path = args[0]
+	}
+	path = pathutil.ExpandPath(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", domain.ErrConfigNotFound, path)
+		}
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	migrated, from, err := config.Migrate(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	out := cmd.OutOrStdout()
+	if configMigrateDryRun {
+		_, err := out.Write(migrated)
+		return err
+	}
+	if from == config.CurrentVersion {
+		fmt.Fprintf(out, "%s: already at version %d\n", path, from)
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("checking config file: %w", err)
+	}
+	if err := os.WriteFile(path, migrated, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("writing config file: %w", err)
+	}
+	fmt.Fprintf(out, "%s: migrated from version %d to %d\n", path, from, config.CurrentVersion)
+	return nil
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/charliek/codely/internal/control"
+	"github.com/spf13/cobra"
+)
+
+// ctlCmd sends a raw request to the running TUI's control socket
+var ctlCmd = &cobra.Command{
+	Use:   "ctl [request-json]",
+	Short: "Send a control request to the running codely",
+	Long: `Send a JSON request to the control socket of a running codely and print
+the JSON response. The request is read from stdin if no argument is given.
+
+Examples:
+  codely ctl '{"action":"list"}'
+  codely ctl '{"action":"spawn","project":"my-service","command":"claude"}'
+  codely ctl '{"action":"focus","session_id":"…"}'`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCtl,
+}
+
+func init() {
+	rootCmd.AddCommand(ctlCmd)
+}
+
+func runCtl(cmd *cobra.Command, args []string) error {
+	var data []byte
+	if len(args) == 1 {
+		data = []byte(args[0])
+	} else {
+		var err error
+		data, err = io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return fmt.Errorf("reading request: %w", err)
+		}
+	}
+
+	var req control.Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("parsing request: %w", err)
+	}
+
+	ws, err := currentWorkspace()
+	if err != nil {
+		return err
+	}
+
+	resp, err := control.Call(controlSocket(ws), req)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(resp); err != nil {
+		return err
+	}
+	if !resp.OK {
+		return fmt.Errorf("%s", resp.Error)
+	}
+	return nil
+}
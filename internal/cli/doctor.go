@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/charliek/codely/internal/config"
+	"github.com/charliek/codely/internal/doctor"
+	"github.com/charliek/codely/internal/pathutil"
+	"github.com/spf13/cobra"
+)
+
+var doctorJSON bool
+
+// doctorCmd checks the environment codely depends on
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check tmux, shed, commands, config and state",
+	Long: `Check everything codely depends on and print actionable findings:
+tmux version and capabilities, shed CLI and server reachability, whether each
+configured command is on PATH, config parsing and state file integrity.
+
+Exits non-zero if any check fails.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	ws, err := currentWorkspace()
+	if err != nil {
+		return err
+	}
+
+	env := doctor.DefaultEnv(config.DefaultSources(configPath), pathutil.ExpandPath(ws.StatePath))
+	findings := doctor.Run(env)
+
+	out := cmd.OutOrStdout()
+	if doctorJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(findings); err != nil {
+			return fmt.Errorf("encoding findings: %w", err)
+		}
+	} else {
+		writeFindings(out, findings)
+	}
+
+	if failed := doctor.Failed(findings); failed > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+func writeFindings(w io.Writer, findings []doctor.Finding) {
+	for _, f := range findings {
+		fmt.Fprintf(w, "%s %-16s %s\n", severityMarker(f.Severity), f.Check, f.Message)
+		if f.Hint != "" {
+			fmt.Fprintf(w, "  %-16s → %s\n", "", f.Hint)
+		}
+	}
+}
+
+func severityMarker(s doctor.Severity) string {
+	switch s {
+	case doctor.SeverityFail:
+		return "✗"
+	case doctor.SeverityWarn:
+		return "!"
+	default:
+		return "✓"
+	}
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/history"
+	"github.com/charliek/codely/internal/status"
+	"github.com/charliek/codely/internal/statuslog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	eventsProject string
+	eventsSince   time.Duration
+	eventsLimit   int
+	eventsSummary bool
+	eventsJSON    bool
+)
+
+// eventsCmd lists logged status transitions
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "List session status changes",
+	Long: `List the session status changes logged by the TUI, oldest first. DURATION is
+how long the session had been in the status it left. With --summary, print the
+total time sessions spent in each status instead, counting only time within
+--since and each running session's current status up to now.`,
+	Example: `  codely events --since 1h
+  codely events --summary --since 24h`,
+	Args: cobra.NoArgs,
+	RunE: runEvents,
+}
+
+func init() {
+	eventsCmd.Flags().StringVarP(&eventsProject, "project", "p", "", "Only sessions in projects whose name or ID contains this")
+	eventsCmd.Flags().DurationVar(&eventsSince, "since", 0, "Only changes within this long, e.g. 24h")
+	eventsCmd.Flags().IntVarP(&eventsLimit, "limit", "n", 50, "Maximum changes to list, most recent kept (0 for all)")
+	eventsCmd.Flags().BoolVar(&eventsSummary, "summary", false, "Print the total time spent in each status")
+	eventsCmd.Flags().BoolVar(&eventsJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(eventsCmd)
+}
+
+func runEvents(cmd *cobra.Command, args []string) error {
+	ws, err := currentWorkspace()
+	if err != nil {
+		return err
+	}
+
+	all, err := statuslog.Load(ws.EventsPath)
+	if err != nil {
+		return fmt.Errorf("loading status log: %w", err)
+	}
+
+	// Durations and totals need changes before --since: a change's duration
+	// starts at the session's previous change
+	project := strings.ToLower(eventsProject)
+	var inProject []status.Transition
+	for _, t := range all {
+		if project != "" && !strings.Contains(strings.ToLower(t.Project), project) && !strings.Contains(strings.ToLower(t.ProjectID), project) {
+			continue
+		}
+		inProject = append(inProject, t)
+	}
+	now := time.Now()
+	var since time.Time
+	if eventsSince > 0 {
+		since = now.Add(-eventsSince)
+	}
+
+	if eventsSummary {
+		totals := statuslog.Totals(inProject, since, now)
+		if eventsJSON {
+			seconds := make(map[domain.Status]int64, len(totals))
+			for s, d := range totals {
+				seconds[s] = int64(d.Seconds())
+			}
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(seconds)
+		}
+		return writeEventsSummary(cmd.OutOrStdout(), totals)
+	}
+
+	allDurations := statuslog.Durations(inProject)
+	var transitions []status.Transition
+	var durations []time.Duration
+	for i, t := range inProject {
+		if t.Time.Before(since) {
+			continue
+		}
+		transitions = append(transitions, t)
+		durations = append(durations, allDurations[i])
+	}
+
+	if eventsLimit > 0 && len(transitions) > eventsLimit {
+		transitions = transitions[len(transitions)-eventsLimit:]
+		durations = durations[len(durations)-eventsLimit:]
+	}
+	if eventsJSON {
+		if transitions == nil {
+			transitions = []status.Transition{}
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(transitions)
+	}
+	return writeEventsTable(cmd.OutOrStdout(), transitions, durations)
+}
+
+// writeEventsTable prints transitions as an aligned table.
+func writeEventsTable(out io.Writer, transitions []status.Transition, durations []time.Duration) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tPROJECT\tSESSION\tFROM\tTO\tEXIT\tDURATION")
+	for i, t := range transitions {
+		from := string(t.From)
+		if from == "" {
+			from = "-"
+		}
+		exit := "-"
+		if t.ExitCode != nil {
+			exit = strconv.Itoa(*t.ExitCode)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			t.Time.Local().Format("2006-01-02 15:04:05"),
+			t.Project,
+			t.Session,
+			from,
+			t.To,
+			exit,
+			history.FormatDuration(durations[i]))
+	}
+	return w.Flush()
+}
+
+// writeEventsSummary prints the time spent in each status, longest first.
+func writeEventsSummary(out io.Writer, totals map[domain.Status]time.Duration) error {
+	statuses := slices.SortedFunc(maps.Keys(totals), func(a, b domain.Status) int {
+		return cmp.Or(cmp.Compare(totals[b], totals[a]), cmp.Compare(a, b))
+	})
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tTIME")
+	for _, s := range statuses {
+		fmt.Fprintf(w, "%s\t%s\n", s, history.FormatDuration(totals[s]))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charliek/codely/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyProject string
+	historyCommand string
+	historySince   time.Duration
+	historyGrep    string
+	historyLimit   int
+	historyOutput  bool
+	historyJSON    bool
+)
+
+// historyCmd lists archived sessions
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List closed and exited sessions",
+	Long: `List sessions archived when they were closed or exited, newest first, with
+their command, how they ended, how long they ran and the tail of their output.
+Relaunch a past session from the TUI history view (H).`,
+	Example: `  codely history --project api --since 24h
+  codely history --grep "tests passed" --output`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+func init() {
+	historyCmd.Flags().StringVarP(&historyProject, "project", "p", "", "Only sessions in projects whose name, ID or directory contains this")
+	historyCmd.Flags().StringVar(&historyCommand, "command", "", "Only sessions running this command ID")
+	historyCmd.Flags().DurationVar(&historySince, "since", 0, "Only sessions that ended within this long, e.g. 24h")
+	historyCmd.Flags().StringVar(&historyGrep, "grep", "", "Only sessions whose project, name, command or output contains this")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Maximum sessions to list (0 for all)")
+	historyCmd.Flags().BoolVar(&historyOutput, "output", false, "Print each session's captured output")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	ws, err := currentWorkspace()
+	if err != nil {
+		return err
+	}
+
+	entries, err := history.Load(ws.HistoryPath)
+	if err != nil {
+		return fmt.Errorf("loading history: %w", err)
+	}
+
+	filter := history.Filter{Project: historyProject, Command: historyCommand, Text: historyGrep}
+	if historySince > 0 {
+		filter.Since = time.Now().Add(-historySince)
+	}
+	entries = filter.Apply(entries)
+	if historyLimit > 0 && len(entries) > historyLimit {
+		entries = entries[:historyLimit]
+	}
+
+	if historyJSON {
+		if entries == nil {
+			entries = []history.Entry{}
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	if historyOutput {
+		return writeHistoryOutput(cmd.OutOrStdout(), entries)
+	}
+	return writeHistoryTable(cmd.OutOrStdout(), entries)
+}
+
+// writeHistoryTable prints entries as an aligned table.
+func writeHistoryTable(out io.Writer, entries []history.Entry) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ENDED\tPROJECT\tSESSION\tCOMMAND\tRESULT\tDURATION")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.EndedAt.Local().Format("2006-01-02 15:04"),
+			e.ProjectName,
+			e.Command.Name(),
+			e.Command.ID,
+			e.Result(),
+			history.FormatDuration(e.Duration()))
+	}
+	return w.Flush()
+}
+
+// writeHistoryOutput prints each entry with its captured output.
+func writeHistoryOutput(out io.Writer, entries []history.Entry) error {
+	for i, e := range entries {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "%s  %s / %s (%s)  %s  %s\n",
+			e.EndedAt.Local().Format("2006-01-02 15:04"),
+			e.ProjectName,
+			e.Command.Name(),
+			e.Command.ID,
+			e.Result(),
+			history.FormatDuration(e.Duration()))
+		for _, line := range strings.Split(e.Output, "\n") {
+			if _, err := fmt.Fprintf(out, "  | %s\n", line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
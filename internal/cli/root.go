@@ -1,14 +1,13 @@
 package cli
 
 import (
-	"errors"
 	"fmt"
 	"os"
 
 	"github.com/charliek/codely/internal/config"
 	"github.com/charliek/codely/internal/constants"
-	"github.com/charliek/codely/internal/domain"
 	"github.com/charliek/codely/internal/tui"
+	"github.com/charliek/codely/internal/workspace"
 	"github.com/spf13/cobra"
 )
 
@@ -21,6 +20,8 @@ var (
 	debugMode  bool
 	debugFile  string
 	skinFlag   string
+	socketPath string
+	wsName     string
 )
 
 // rootCmd represents the base command
@@ -53,33 +54,70 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&debugMode, "debug", "d", false, "Enable debug logging to file")
 	rootCmd.PersistentFlags().StringVar(&debugFile, "debug-file", "~/.local/state/codely/debug.log", "Debug log file path")
 	rootCmd.PersistentFlags().StringVar(&skinFlag, "skin", "", "UI skin: tree or flat (default from config or \"tree\")")
+	rootCmd.PersistentFlags().StringVar(&socketPath, "socket", "", "Control socket path (default is the workspace's socket)")
+	rootCmd.PersistentFlags().StringVarP(&wsName, "workspace", "w", "", "Workspace to use (default $"+constants.WorkspaceEnv+" or \"default\")")
 
 	// Set version template
 	rootCmd.SetVersionTemplate("codely version {{.Version}}\n")
 }
 
+// loadConfig resolves the layered configuration: built-in defaults, the
+// system file, the user file from --config and CODELY_* variables.
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadLayered(config.DefaultSources(configPath))
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	return cfg, nil
+}
+
+// currentWorkspace resolves the workspace named by --workspace, or else by
+// CODELY_WORKSPACE
+func currentWorkspace() (workspace.Workspace, error) {
+	name := wsName
+	if name == "" {
+		name = os.Getenv(constants.WorkspaceEnv)
+	}
+	ws, err := workspace.Resolve(name)
+	if err != nil {
+		return workspace.Workspace{}, fmt.Errorf("resolving workspace: %w", err)
+	}
+	return ws, nil
+}
+
+// controlSocket returns the socket from --socket, or else the workspace's
+func controlSocket(ws workspace.Workspace) string {
+	if socketPath != "" {
+		return socketPath
+	}
+	return ws.SocketPath
+}
+
 // runApp launches the TUI application
 func runApp(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load(configPath)
+	ws, err := currentWorkspace()
 	if err != nil {
-		if errors.Is(err, domain.ErrConfigNotFound) {
-			// Use defaults if no config file
-			cfg = config.Default()
-		} else {
-			return fmt.Errorf("loading config: %w", err)
-		}
+		return err
 	}
 
-	// Resolve skin: CLI flag overrides config, default to "tree"
-	skin := tui.SkinName(cfg.UI.Skin)
-	if skinFlag != "" {
-		skin = tui.SkinName(skinFlag)
-	}
-	if skin == "" {
-		skin = tui.SkinTree
+	// Load configuration
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
 	}
 
 	// Run TUI
-	return tui.Run(cfg, constants.DefaultStatePath, debugMode, debugFile, skin)
+	return tui.Run(cfg, tui.Options{
+		ConfigSources: config.DefaultSources(configPath),
+		Workspace:     ws,
+		StorePath:     ws.StatePath,
+		FolderCache:   constants.DefaultFolderCachePath,
+		FrecencyPath:  constants.DefaultFrecencyPath,
+		HistoryPath:   ws.HistoryPath,
+		EventsPath:    ws.EventsPath,
+		SocketPath:    controlSocket(ws),
+		Debug:         debugMode,
+		DebugFile:     debugFile,
+		Skin:          tui.SkinName(skinFlag),
+	})
 }
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charliek/codely/internal/store"
+	"github.com/charliek/codely/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+var sendNoEnter bool
+
+// sendCmd types text into a session's pane
+var sendCmd = &cobra.Command{
+	Use:   "send <project>[/<session>] [text...]",
+	Short: "Send text to a session's pane",
+	Long: `Type text into a session's pane and press Enter, without switching focus.
+Works for local and shed sessions. The text is read from stdin if not given.
+
+The project is matched by ID or name. The session is matched by ID, ID prefix,
+1-based index, display name or command ID, and may be omitted if the project
+has a single session.
+
+Examples:
+  codely send my-service/claude "run the tests"
+  codely send my-service/2 continue
+  echo "continue" | codely send my-service`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSend,
+}
+
+func init() {
+	sendCmd.Flags().BoolVarP(&sendNoEnter, "no-enter", "n", false, "Don't press Enter after the text")
+	rootCmd.AddCommand(sendCmd)
+}
+
+func runSend(cmd *cobra.Command, args []string) error {
+	var text string
+	if len(args) > 1 {
+		text = strings.Join(args[1:], " ")
+	} else {
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return fmt.Errorf("reading input: %w", err)
+		}
+		text = strings.TrimRight(string(data), "\n")
+	}
+	if text == "" && sendNoEnter {
+		return fmt.Errorf("nothing to send")
+	}
+
+	ws, err := currentWorkspace()
+	if err != nil {
+		return err
+	}
+
+	st := store.New(ws.StatePath)
+	if err := st.Load(); err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	// Reattach in memory only; the running TUI owns the state file
+	tmuxClient := tmux.NewClient()
+	st.ReconnectSessions(tmuxClient, ws.Name)
+
+	proj, sess, err := st.ResolveSession(args[0])
+	if err != nil {
+		return err
+	}
+	if sess.PaneID == 0 {
+		return fmt.Errorf("session %s/%s is not running", proj.Name, sess.Command.Name())
+	}
+
+	return tmuxClient.SendKeys(sess.PaneID, text, !sendNoEnter)
+}
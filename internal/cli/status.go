@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/status"
+	"github.com/charliek/codely/internal/store"
+	"github.com/charliek/codely/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+var statusJSON bool
+
+// statusCmd prints session status without starting the TUI
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Print project and session status",
+	Long: `Print every project and session with its detected status, exit code and
+tmux pane. Useful for shell prompts, scripts and tmux status lines.`,
+	Args: cobra.NoArgs,
+	RunE: runStatus,
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(statusCmd)
+}
+
+// statusReport is the JSON document printed by `codely status --json`
+type statusReport struct {
+	Projects []projectStatus `json:"projects"`
+}
+
+type projectStatus struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Path     string          `json:"path"`
+	Sessions []sessionStatus `json:"sessions"`
+}
+
+type sessionStatus struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Command  string        `json:"command"`
+	Status   domain.Status `json:"status"`
+	ExitCode *int          `json:"exit_code"`
+	PaneID   *int          `json:"pane_id"`
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	ws, err := currentWorkspace()
+	if err != nil {
+		return err
+	}
+
+	st := store.New(ws.StatePath)
+	if err := st.Load(); err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	// Reattach in memory only; the running TUI owns the state file
+	tmuxClient := tmux.NewClient()
+	st.ReconnectSessions(tmuxClient, ws.Name)
+
+	snap := status.Collect(tmuxClient, st.Projects(), func(sess *domain.Session) string {
+		return cfg.DetectionMode(sess.Command)
+	})
+	report := buildStatusReport(st.Projects(), snap)
+
+	if statusJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return writeStatusTable(cmd.OutOrStdout(), report)
+}
+
+// buildStatusReport combines stored projects with a status snapshot.
+func buildStatusReport(projects []*domain.Project, snap status.Snapshot) statusReport {
+	report := statusReport{Projects: []projectStatus{}}
+	for _, p := range projects {
+		ps := projectStatus{
+			ID:       p.ID,
+			Name:     p.Name,
+			Type:     string(p.Type),
+			Path:     p.DisplayPath(),
+			Sessions: []sessionStatus{},
+		}
+		for _, sess := range p.Sessions {
+			ss := sessionStatus{
+				ID:      sess.ID,
+				Name:    sess.Command.Name(),
+				Command: sess.Command.ID,
+				Status:  domain.StatusUnknown,
+			}
+			if s, ok := snap.Updates[sess.ID]; ok {
+				ss.Status = s
+			}
+			ss.ExitCode = snap.ExitCodes[sess.ID]
+			if sess.PaneID > 0 {
+				paneID := sess.PaneID
+				ss.PaneID = &paneID
+			}
+			ps.Sessions = append(ps.Sessions, ss)
+		}
+		report.Projects = append(report.Projects, ps)
+	}
+	return report
+}
+
+// writeStatusTable prints the report as an aligned table.
+func writeStatusTable(out io.Writer, report statusReport) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECT\tSESSION\tCOMMAND\tSTATUS\tEXIT\tPANE")
+	for _, p := range report.Projects {
+		if len(p.Sessions) == 0 {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\n", p.Name)
+			continue
+		}
+		for _, s := range p.Sessions {
+			exit := "-"
+			if s.ExitCode != nil {
+				exit = strconv.Itoa(*s.ExitCode)
+			}
+			pane := "-"
+			if s.PaneID != nil {
+				pane = fmt.Sprintf("%%%d", *s.PaneID)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Name, s.Name, s.Command, s.Status, exit, pane)
+		}
+	}
+	return w.Flush()
+}
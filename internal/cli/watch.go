@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/status"
+	"github.com/charliek/codely/internal/store"
+	"github.com/charliek/codely/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+var watchInterval time.Duration
+
+// watchCmd streams status transitions as NDJSON
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Stream session status transitions as JSON lines",
+	Long: `Poll every session like the TUI does and print one JSON object per line
+whenever a session's status changes. Each session's current status is printed
+once at startup (with an empty "from"). A session whose pane disappears is
+reported as exited.
+
+Runs until interrupted. Pipe the output into notifiers, loggers or dashboards.`,
+	Args: cobra.NoArgs,
+	RunE: runWatch,
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 0, "Poll interval (default from ui.status_poll_interval)")
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	ws, err := currentWorkspace()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	interval := watchInterval
+	if interval <= 0 {
+		interval = cfg.StatusPollIntervalDuration()
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	tmuxClient := tmux.NewClient()
+	tracker := status.NewTracker()
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	modeFor := func(sess *domain.Session) string {
+		return cfg.DetectionMode(sess.Command)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Reload every tick so sessions added or closed in the TUI are picked up
+		st := store.New(ws.StatePath)
+		if err := st.Load(); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "loading state: %v\n", err)
+		} else {
+			st.ReconnectSessions(tmuxClient, ws.Name)
+			snap := status.Collect(tmuxClient, st.Projects(), modeFor)
+			for _, t := range tracker.Observe(st.Projects(), snap) {
+				if err := enc.Encode(t); err != nil {
+					return fmt.Errorf("writing event: %w", err)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/charliek/codely/internal/pathutil"
+	"github.com/charliek/codely/internal/store"
+	"github.com/charliek/codely/internal/tmux"
+	"github.com/charliek/codely/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var workspaceListJSON bool
+
+// workspaceCmd groups workspace subcommands
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manage named workspaces",
+	Long: `Workspaces keep separate project lists. Each has its own state file, control
+socket, session history and tmux session. Select one with --workspace (-w) or
+CODELY_WORKSPACE; a workspace is created the first time codely runs in it.`,
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List workspaces",
+	Args:    cobra.NoArgs,
+	RunE:    runWorkspaceList,
+}
+
+// workspaceStatus is the JSON form of a workspace in the list
+type workspaceStatus struct {
+	Name        string `json:"name"`
+	Current     bool   `json:"current"`
+	Running     bool   `json:"running"`
+	TmuxSession string `json:"tmux_session"`
+	StatePath   string `json:"state_path"`
+	Projects    int    `json:"projects"`
+	Sessions    int    `json:"sessions"`
+	Error       string `json:"error,omitempty"`
+}
+
+func init() {
+	workspaceListCmd.Flags().BoolVar(&workspaceListJSON, "json", false, "Output as JSON")
+	workspaceCmd.AddCommand(workspaceListCmd)
+	rootCmd.AddCommand(workspaceCmd)
+}
+
+func runWorkspaceList(cmd *cobra.Command, args []string) error {
+	current, err := currentWorkspace()
+	if err != nil {
+		return err
+	}
+	names, err := workspace.List()
+	if err != nil {
+		return err
+	}
+
+	tmuxClient := tmux.NewClient()
+	list := make([]workspaceStatus, 0, len(names))
+	for _, name := range names {
+		ws, err := workspace.Resolve(name)
+		if err != nil {
+			return err
+		}
+		list = append(list, readWorkspaceStatus(ws, ws.Name == current.Name, tmuxClient))
+	}
+
+	if workspaceListJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+	return writeWorkspaceTable(cmd.OutOrStdout(), list)
+}
+
+// readWorkspaceStatus loads a workspace's state without modifying it
+func readWorkspaceStatus(ws workspace.Workspace, current bool, tmuxClient tmux.Client) workspaceStatus {
+	status := workspaceStatus{
+		Name:        ws.Name,
+		Current:     current,
+		Running:     tmuxClient.HasSession(ws.TmuxSession),
+		TmuxSession: ws.TmuxSession,
+		StatePath:   pathutil.ExpandPath(ws.StatePath),
+	}
+
+	st := store.New(ws.StatePath)
+	if err := st.Load(); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	for _, p := range st.Projects() {
+		status.Projects++
+		status.Sessions += len(p.Sessions)
+	}
+	return status
+}
+
+// writeWorkspaceTable prints workspaces as an aligned table, marking the
+// current one with "*"
+func writeWorkspaceTable(out io.Writer, list []workspaceStatus) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tWORKSPACE\tPROJECTS\tSESSIONS\tTMUX SESSION\tRUNNING")
+	for _, ws := range list {
+		mark := ""
+		if ws.Current {
+			mark = "*"
+		}
+		running := "no"
+		if ws.Running {
+			running = "yes"
+		}
+		if ws.Error != "" {
+			fmt.Fprintf(w, "%s\t%s\t-\t-\t%s\t%s (%s)\n", mark, ws.Name, ws.TmuxSession, running, ws.Error)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", mark, ws.Name, ws.Projects, ws.Sessions, ws.TmuxSession, running)
+	}
+	return w.Flush()
+}
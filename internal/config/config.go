@@ -1,7 +1,10 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -13,11 +16,23 @@ import (
 
 // Config represents the top-level codely configuration
 type Config struct {
+	Version        int                `yaml:"version"`
 	WorkspaceRoots []string           `yaml:"workspace_roots"`
+	Discovery      DiscoveryConfig    `yaml:"discovery"`
 	Commands       map[string]Command `yaml:"commands"`
 	DefaultCommand string             `yaml:"default_command"`
 	UI             UIConfig           `yaml:"ui"`
 	Shed           ShedConfig         `yaml:"shed"`
+	// TrustedProjects lists directories whose .codely.yaml may change what
+	// runs: exec, args, resume_args, env, env_from and env_file
+	TrustedProjects []string `yaml:"trusted_projects"`
+
+	// Warnings holds non-fatal problems found while parsing
+	Warnings []Diagnostic `yaml:"-"`
+
+	// Origins maps each key path (e.g. "ui.skin") to where its value came
+	// from; set by LoadLayered
+	Origins map[string]Origin `yaml:"-"`
 }
 
 // Command represents a command configuration
@@ -26,26 +41,78 @@ type Command struct {
 	Exec        string            `yaml:"exec"`
 	Args        []string          `yaml:"args"`
 	Env         map[string]string `yaml:"env,omitempty"`
+	// EnvFrom resolves environment variables at launch, keeping secrets
+	// out of the config and state files
+	EnvFrom map[string]EnvSource `yaml:"env_from,omitempty"`
+	// EnvFile is a .env file loaded at launch, relative to the project
+	// directory; a missing file is ignored
+	EnvFile string `yaml:"env_file,omitempty"`
 	// StatusDetection controls tool-specific status heuristics.
 	// Supported: auto, generic, claude, opencode, codex, shell
 	StatusDetection string `yaml:"status_detection,omitempty"`
+	// ResumeArgs replace Args when a session lost with the tmux server is
+	// restarted. Unset uses the tool's resume adapter, if any; an empty list
+	// restarts the command as is.
+	ResumeArgs []string `yaml:"resume_args,omitempty"`
+}
+
+// EnvSource reads an environment value at launch from exactly one of a
+// file, another environment variable or a shell command's output
+type EnvSource struct {
+	File    string `yaml:"file,omitempty"`
+	Env     string `yaml:"env,omitempty"`
+	Command string `yaml:"command,omitempty"`
+}
+
+// DiscoveryConfig controls how the folder picker searches workspace roots
+type DiscoveryConfig struct {
+	MaxDepth int      `yaml:"max_depth"` // Levels below each root searched for git repositories
+	Ignore   []string `yaml:"ignore"`    // Directory name or root-relative path globs to skip
 }
 
 // UIConfig represents UI preferences
 type UIConfig struct {
 	ManagerWidth       int    `yaml:"manager_width"`
 	StatusPollInterval string `yaml:"status_poll_interval"`
-	ShowDirectory      bool   `yaml:"show_directory"`
-	AutoExpandProjects bool   `yaml:"auto_expand_projects"`
+	ShowDirectory      *bool  `yaml:"show_directory"`       // nil means unset (default true)
+	AutoExpandProjects *bool  `yaml:"auto_expand_projects"` // nil means unset (default true)
 	Skin               string `yaml:"skin"`
 }
 
 // ShedConfig represents shed integration settings
 type ShedConfig struct {
-	Enabled       bool   `yaml:"enabled"`
+	Enabled       *bool  `yaml:"enabled"` // nil means unset (default true)
 	DefaultServer string `yaml:"default_server"`
 }
 
+// Bool returns a pointer to b, for setting tri-state config fields
+func Bool(b bool) *bool {
+	return &b
+}
+
+// boolOr returns *p, or def if p is unset
+func boolOr(p *bool, def bool) bool {
+	if p == nil {
+		return def
+	}
+	return *p
+}
+
+// ShowsDirectory reports whether project paths are shown
+func (u UIConfig) ShowsDirectory() bool {
+	return boolOr(u.ShowDirectory, true)
+}
+
+// AutoExpands reports whether projects are expanded by default
+func (u UIConfig) AutoExpands() bool {
+	return boolOr(u.AutoExpandProjects, true)
+}
+
+// IsEnabled reports whether shed projects are enabled
+func (s ShedConfig) IsEnabled() bool {
+	return boolOr(s.Enabled, true)
+}
+
 // Load reads and parses a configuration file
 func Load(path string) (*Config, error) {
 	// Expand ~ in path
@@ -67,16 +134,83 @@ func Load(path string) (*Config, error) {
 	return Parse(data)
 }
 
-// Parse parses configuration from YAML bytes
+// Parse parses configuration from YAML bytes, migrating older schema
+// versions. Unknown keys and invalid values are reported together as a
+// *ValidationError; non-fatal problems are returned in Config.Warnings.
 func Parse(data []byte) (*Config, error) {
+	config, root, diags, err := decodeConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	applyDefaults(config)
+
+	errs, warnings := validate(config, root)
+	diags = append(diags, errs...)
+	if len(diags) > 0 {
+		return nil, &ValidationError{Diagnostics: diags}
+	}
+	config.Warnings = warnings
+
+	return config, nil
+}
+
+// decodeConfig strictly decodes a single config file and migrates it to
+// CurrentVersion. The returned node tree is the migrated document; keys
+// added by a migration have no line number.
+func decodeConfig(data []byte) (*Config, *yaml.Node, []Diagnostic, error) {
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("parsing yaml: %w", err)
+	root, diags, err := decodeStrict(data, &config)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	applyDefaults(&config)
+	from, versionDiags := migrate(root)
+	diags = append(diags, versionDiags...)
+	if from < CurrentVersion && len(diags) == 0 {
+		config = Config{}
+		if err := root.Decode(&config); err != nil {
+			return nil, nil, nil, fmt.Errorf("decoding migrated config: %w", err)
+		}
+	}
 
-	return &config, nil
+	return &config, root, diags, nil
+}
+
+// decodeLayer strictly decodes one layer of a layered config and upgrades
+// it to CurrentVersion. Values the layer's version assumed for absent keys
+// are returned rather than filled in.
+func decodeLayer(data []byte) (*yaml.Node, []assumedValue, []Diagnostic, error) {
+	var config Config
+	root, diags, err := decodeStrict(data, &config)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	_, assumed, versionDiags := upgrade(root)
+	return root, assumed, append(diags, versionDiags...), nil
+}
+
+// decodeStrict decodes YAML into out, rejecting unknown keys. Syntax errors
+// are returned as err; unknown keys and type mismatches as diagnostics. The
+// returned node tree is used to look up line numbers.
+func decodeStrict(data []byte, out any) (*yaml.Node, []Diagnostic, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, fmt.Errorf("parsing yaml: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, nil, fmt.Errorf("parsing yaml: %w", err)
+		}
+		return &root, typeErrorDiagnostics(typeErr), nil
+	}
+
+	return &root, nil, nil
 }
 
 // Default returns a config with default values
@@ -97,6 +231,14 @@ func applyDefaults(config *Config) {
 		}
 	}
 
+	// Discovery defaults
+	if config.Discovery.MaxDepth == 0 {
+		config.Discovery.MaxDepth = constants.DefaultDiscoveryMaxDepth
+	}
+	if config.Discovery.Ignore == nil {
+		config.Discovery.Ignore = []string{"node_modules", "vendor"}
+	}
+
 	// Default commands
 	if config.Commands == nil {
 		config.Commands = make(map[string]Command)
@@ -152,14 +294,20 @@ func applyDefaults(config *Config) {
 	if config.UI.Skin == "" {
 		config.UI.Skin = "tree"
 	}
-	// ShowDirectory and AutoExpandProjects default to false (zero value)
-	// but we want them to default to true
-	// Since we can't distinguish between "not set" and "explicitly set to false"
-	// we'll apply these defaults only in Default() or when the config is empty
+	if config.UI.ShowDirectory == nil {
+		config.UI.ShowDirectory = Bool(true)
+	}
+	if config.UI.AutoExpandProjects == nil {
+		config.UI.AutoExpandProjects = Bool(true)
+	}
 
 	// Shed defaults
-	if !config.Shed.Enabled {
-		config.Shed.Enabled = true
+	if config.Shed.Enabled == nil {
+		config.Shed.Enabled = Bool(true)
+	}
+
+	if config.Version == 0 {
+		config.Version = CurrentVersion
 	}
 }
 
@@ -172,13 +320,40 @@ func (c *Config) StatusPollIntervalDuration() time.Duration {
 	return d
 }
 
+// DetectionMode returns the status_detection mode for a session's command:
+// the mode recorded on the command when it was launched (which includes
+// per-project overrides), else the mode configured for its ID, else "" (auto).
+func (c *Config) DetectionMode(cmd domain.Command) string {
+	if cmd.StatusDetection != "" {
+		return cmd.StatusDetection
+	}
+	if cfgCmd, ok := c.Commands[cmd.ID]; ok {
+		return cfgCmd.StatusDetection
+	}
+	return ""
+}
+
 // ToDomainCommand converts a config Command to a domain Command
 func (c Command) ToDomainCommand(id string) domain.Command {
 	return domain.Command{
-		ID:          id,
-		DisplayName: c.DisplayName,
-		Exec:        c.Exec,
-		Args:        c.Args,
-		Env:         c.Env,
+		ID:              id,
+		DisplayName:     c.DisplayName,
+		Exec:            c.Exec,
+		Args:            c.Args,
+		Env:             c.Env,
+		EnvFrom:         c.domainEnvFrom(),
+		EnvFile:         c.EnvFile,
+		StatusDetection: c.StatusDetection,
+	}
+}
+
+func (c Command) domainEnvFrom() map[string]domain.EnvSource {
+	if c.EnvFrom == nil {
+		return nil
+	}
+	envFrom := make(map[string]domain.EnvSource, len(c.EnvFrom))
+	for key, src := range c.EnvFrom {
+		envFrom[key] = domain.EnvSource(src)
 	}
+	return envFrom
 }
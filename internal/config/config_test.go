@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/charliek/codely/internal/constants"
+	"github.com/charliek/codely/internal/domain"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -28,7 +29,7 @@ func TestLoad_DefaultConfig(t *testing.T) {
 	assert.Equal(t, "1s", cfg.UI.StatusPollInterval)
 
 	// Check shed config
-	assert.True(t, cfg.Shed.Enabled)
+	assert.True(t, cfg.Shed.IsEnabled())
 }
 
 func TestParse_AppliesDefaults(t *testing.T) {
@@ -119,3 +120,146 @@ func TestParse_InvalidYAML(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "parsing yaml")
 }
+
+func TestDetectionMode(t *testing.T) {
+	cfg, err := Parse([]byte(`
+commands:
+  claude:
+    exec: claude
+    status_detection: generic
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "generic", cfg.DetectionMode(domain.Command{ID: "claude"}))
+	assert.Equal(t, "", cfg.DetectionMode(domain.Command{ID: "bash"}))
+	assert.Equal(t, "", cfg.DetectionMode(domain.Command{ID: "missing"}))
+	assert.Equal(t, "shell", cfg.DetectionMode(domain.Command{ID: "claude", StatusDetection: "shell"}))
+}
+
+func TestParse_UnknownKeys(t *testing.T) {
+	_, err := Parse([]byte(`default_command: claude
+ui:
+  manager_widht: 40
+commandz: {}
+`))
+	require.Error(t, err)
+
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Len(t, verr.Diagnostics, 2)
+	assert.Equal(t, 3, verr.Diagnostics[0].Line)
+	assert.Equal(t, `unknown key "manager_widht"`, verr.Diagnostics[0].Message)
+	assert.Equal(t, 4, verr.Diagnostics[1].Line)
+	assert.Contains(t, err.Error(), `line 4: unknown key "commandz"`)
+}
+
+func TestParse_InvalidValues(t *testing.T) {
+	_, err := Parse([]byte(`commands:
+  claude:
+    exec: claude
+    status_detection: claud
+  broken:
+    display_name: Broken
+ui:
+  status_poll_interval: fast
+  skin: cards
+`))
+	require.Error(t, err)
+
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+
+	var got []string
+	for _, d := range verr.Diagnostics {
+		got = append(got, d.String())
+	}
+	assert.Equal(t, []string{
+		"line 6: commands.broken: exec is required",
+		`line 4: commands.claude.status_detection: unknown mode "claud" (expected one of auto, generic, claude, opencode, codex, shell)`,
+		`line 8: ui.status_poll_interval: invalid duration "fast" (e.g. 500ms, 1s)`,
+		`line 9: ui.skin: unknown skin "cards" (expected one of tree, flat)`,
+	}, got)
+}
+
+func TestParse_Warnings(t *testing.T) {
+	cfg, err := Parse([]byte(`default_command: aider
+ui:
+  status_poll_interval: 10ms
+`))
+	require.NoError(t, err)
+	require.Len(t, cfg.Warnings, 2)
+	assert.Equal(t, 1, cfg.Warnings[0].Line)
+	assert.Contains(t, cfg.Warnings[0].Message, `"aider" is not a configured command`)
+	assert.Equal(t, "ui.status_poll_interval", cfg.Warnings[1].Path)
+}
+
+func TestParse_EmptyDocument(t *testing.T) {
+	cfg, err := Parse([]byte(""))
+	require.NoError(t, err)
+	assert.Equal(t, constants.DefaultCommand, cfg.DefaultCommand)
+	assert.Empty(t, cfg.Warnings)
+}
+
+func TestDefaultYAML_RoundTrips(t *testing.T) {
+	data, err := DefaultYAML()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# Width of the manager panel in columns.")
+
+	cfg, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, Default().Commands, cfg.Commands)
+	assert.Empty(t, cfg.Warnings)
+}
+
+func TestParse_InvalidTemplates(t *testing.T) {
+	_, err := Parse([]byte(`commands:
+  claude:
+    exec: claude
+    args: ["--log", "{{.Project.Path}}"]
+    env:
+      AGENT: "{{.Session.ID"
+    resume_args: ["--resume", "{{.Session.Id}}"]
+`))
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Len(t, verr.Diagnostics, 3)
+	assert.Equal(t, "commands.claude.args[1]", verr.Diagnostics[0].Path)
+	assert.Equal(t, 4, verr.Diagnostics[0].Line)
+	assert.Equal(t, "commands.claude.resume_args[1]", verr.Diagnostics[1].Path)
+	assert.Equal(t, 7, verr.Diagnostics[1].Line)
+	assert.Equal(t, "commands.claude.env.AGENT", verr.Diagnostics[2].Path)
+	assert.Equal(t, 6, verr.Diagnostics[2].Line)
+}
+
+func TestParse_EnvFrom(t *testing.T) {
+	cfg, err := Parse([]byte(`commands:
+  claude:
+    exec: claude
+    env_from:
+      ANTHROPIC_API_KEY:
+        command: pass show anthropic
+`))
+	require.NoError(t, err)
+	cmd := cfg.Commands["claude"].ToDomainCommand("claude")
+	assert.Equal(t, domain.EnvSource{Command: "pass show anthropic"}, cmd.EnvFrom["ANTHROPIC_API_KEY"])
+
+	_, err = Parse([]byte(`commands:
+  claude:
+    exec: claude
+    env:
+      TOKEN: plain
+    env_from:
+      TOKEN:
+        env: GITHUB_TOKEN
+      KEY:
+        file: ~/.key
+        command: cat ~/.key
+`))
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Len(t, verr.Diagnostics, 2)
+	assert.Equal(t, "commands.claude.env_from.KEY", verr.Diagnostics[0].Path)
+	assert.Equal(t, "set exactly one of file, env or command", verr.Diagnostics[0].Message)
+	assert.Equal(t, "commands.claude.env_from.TOKEN", verr.Diagnostics[1].Path)
+	assert.Equal(t, 8, verr.Diagnostics[1].Line)
+}
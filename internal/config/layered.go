@@ -0,0 +1,441 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charliek/codely/internal/constants"
+	"github.com/charliek/codely/internal/pathutil"
+	"gopkg.in/yaml.v3"
+)
+
+// Origin records where an effective config value came from
+type Origin struct {
+	Source string `json:"source"`         // "default", a file path, or "env"
+	Line   int    `json:"line,omitempty"` // Line in the file
+	Env    string `json:"env,omitempty"`  // Environment variable name
+	Note   string `json:"note,omitempty"` // Why a file without the key set it
+}
+
+// OriginDefault marks built-in default values
+var OriginDefault = Origin{Source: "default"}
+
+func (o Origin) String() string {
+	switch {
+	case o.Env != "":
+		return "env " + o.Env
+	case o.Line > 0:
+		return fmt.Sprintf("%s:%d", o.Source, o.Line)
+	case o.Note != "":
+		return fmt.Sprintf("%s (%s)", o.Source, o.Note)
+	default:
+		return o.Source
+	}
+}
+
+// Sources lists the layers read by LoadLayered, lowest precedence first:
+// built-in defaults, then each file in order, then CODELY_* variables.
+type Sources struct {
+	Files   []string // Config files; missing files are skipped
+	Environ []string // Environment in os.Environ form
+}
+
+// DefaultSources returns the system config file, the given user config file
+// and the process environment.
+func DefaultSources(userPath string) Sources {
+	return Sources{
+		Files:   []string{constants.SystemConfigPath, userPath},
+		Environ: os.Environ(),
+	}
+}
+
+// envVar maps a CODELY_* environment variable to a config key
+type envVar struct {
+	Name string
+	Path string
+	Tag  string // YAML tag of the value; "!!seq" splits on the path list separator
+}
+
+// EnvVars lists the supported environment overrides
+var EnvVars = []envVar{
+	{"CODELY_WORKSPACE_ROOTS", "workspace_roots", "!!seq"},
+	{"CODELY_DISCOVERY_MAX_DEPTH", "discovery.max_depth", "!!int"},
+	{"CODELY_DEFAULT_COMMAND", "default_command", "!!str"},
+	{"CODELY_UI_MANAGER_WIDTH", "ui.manager_width", "!!int"},
+	{"CODELY_UI_STATUS_POLL_INTERVAL", "ui.status_poll_interval", "!!str"},
+	{"CODELY_UI_SHOW_DIRECTORY", "ui.show_directory", "!!bool"},
+	{"CODELY_UI_AUTO_EXPAND_PROJECTS", "ui.auto_expand_projects", "!!bool"},
+	{"CODELY_UI_SKIN", "ui.skin", "!!str"},
+	{"CODELY_SHED_ENABLED", "shed.enabled", "!!bool"},
+	{"CODELY_SHED_DEFAULT_SERVER", "shed.default_server", "!!str"},
+}
+
+// LoadLayered resolves the effective config from all sources. Every file is
+// parsed strictly; errors from all layers are reported together as a
+// *ValidationError. The origin of each value is recorded in Config.Origins.
+func LoadLayered(src Sources) (*Config, error) {
+	base, err := defaultsNode()
+	if err != nil {
+		return nil, err
+	}
+	origins := make(map[string]Origin)
+	recordLeaves(base, "", func(string, *yaml.Node) Origin { return OriginDefault }, origins)
+
+	var diags []Diagnostic
+	entries := make(map[string]Origin) // Where each command entry was first defined
+	assumed := make(map[string]Origin)
+	var assumedValues []assumedValue
+
+	for _, file := range src.Files {
+		path := pathutil.ExpandPath(file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+
+		root, fileAssumed, fileDiags, err := decodeLayer(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for i := range fileDiags {
+			fileDiags[i].Source = path
+		}
+		diags = append(diags, fileDiags...)
+		for _, a := range fileAssumed {
+			if _, seen := assumed[a.Path]; !seen {
+				assumedValues = append(assumedValues, a)
+			}
+			assumed[a.Path] = Origin{Source: path, Note: fmt.Sprintf("version %d default", a.Version)}
+		}
+
+		if node := documentBody(root); node != nil && node.Kind == yaml.MappingNode {
+			recordCommandEntries(node, path, entries)
+			mergeNode(base, node, "", func(_ string, n *yaml.Node) Origin {
+				return Origin{Source: path, Line: n.Line}
+			}, origins)
+		}
+	}
+
+	envNode, envNames, envDiags := environNode(src.Environ)
+	diags = append(diags, envDiags...)
+	mergeNode(base, envNode, "", func(p string, _ *yaml.Node) Origin {
+		return Origin{Source: "env", Env: envNames[p]}
+	}, origins)
+
+	// Values an old file's version assumed for absent keys apply only when
+	// no layer sets the key
+	for _, a := range assumedValues {
+		if origins[a.Path] == OriginDefault {
+			setPath(base, strings.Split(a.Path, "."), a.Value)
+			origins[a.Path] = assumed[a.Path]
+		}
+	}
+
+	var config Config
+	if err := base.Decode(&config); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("decoding config: %w", err)
+		}
+		diags = append(diags, typeErrorDiagnostics(typeErr)...)
+	}
+
+	applyDefaults(&config)
+	recordDefaultCommands(&config, origins)
+	recordUnsetCommandFields(&config, entries, origins)
+
+	errs, warnings := validate(&config, base)
+	locate(errs, origins)
+	locate(warnings, origins)
+	diags = append(diags, errs...)
+	if len(diags) > 0 {
+		return nil, &ValidationError{Diagnostics: diags}
+	}
+
+	config.Warnings = warnings
+	config.Origins = origins
+	return &config, nil
+}
+
+// Values returns each effective value keyed by path, formatted for display.
+// Sequences are shown in YAML flow style.
+func (c *Config) Values() (map[string]string, error) {
+	var node yaml.Node
+	if err := node.Encode(c); err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	values := make(map[string]string)
+	flattenValues(&node, "", values)
+	return values, nil
+}
+
+func flattenValues(node *yaml.Node, path string, values map[string]string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			flattenValues(node.Content[i+1], joinPath(path, node.Content[i].Value), values)
+		}
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			items = append(items, item.Value)
+		}
+		values[path] = "[" + strings.Join(items, ", ") + "]"
+	default:
+		values[path] = node.Value
+	}
+}
+
+// defaultsNode encodes the built-in defaults as a YAML mapping. Default
+// commands are left out: applyDefaults adds those not defined by any layer,
+// so a file that defines a command replaces the default entry entirely.
+func defaultsNode() (*yaml.Node, error) {
+	defaults := Default()
+	defaults.Commands = nil
+
+	var node yaml.Node
+	if err := node.Encode(defaults); err != nil {
+		return nil, fmt.Errorf("encoding defaults: %w", err)
+	}
+	return &node, nil
+}
+
+// recordDefaultCommands records default origins for commands added by
+// applyDefaults
+func recordDefaultCommands(c *Config, origins map[string]Origin) {
+	for id, cmd := range c.Commands {
+		prefix := "commands." + id
+		defined := slices.ContainsFunc(slices.Collect(maps.Keys(origins)), func(p string) bool {
+			return strings.HasPrefix(p, prefix+".")
+		})
+		if defined {
+			continue
+		}
+		var node yaml.Node
+		if err := node.Encode(cmd); err == nil {
+			recordLeaves(&node, prefix, func(string, *yaml.Node) Origin { return OriginDefault }, origins)
+		}
+	}
+}
+
+// recordCommandEntries records where each command entry in a file's
+// top-level node is defined, unless an earlier file defined it
+func recordCommandEntries(node *yaml.Node, path string, entries map[string]Origin) {
+	commands := mappingValue(node, "commands")
+	if commands == nil || commands.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(commands.Content); i += 2 {
+		key, value := commands.Content[i], commands.Content[i+1]
+		prefix := "commands." + key.Value
+		if _, ok := entries[prefix]; ok || value.Kind != yaml.MappingNode {
+			continue
+		}
+		entries[prefix] = Origin{Source: path, Line: key.Line}
+	}
+}
+
+// recordUnsetCommandFields gives fields no layer set in a command defined by
+// a file the origin of that command's entry, since no default supplied them
+func recordUnsetCommandFields(c *Config, entries map[string]Origin, origins map[string]Origin) {
+	for id, cmd := range c.Commands {
+		prefix := "commands." + id
+		entry, ok := entries[prefix]
+		if !ok {
+			continue
+		}
+		var node yaml.Node
+		if err := node.Encode(cmd); err != nil {
+			continue
+		}
+		fields := make(map[string]Origin)
+		recordLeaves(&node, prefix, func(string, *yaml.Node) Origin { return entry }, fields)
+		for p, origin := range fields {
+			if _, set := origins[p]; !set {
+				origins[p] = origin
+			}
+		}
+	}
+}
+
+// documentBody returns the top-level node of a parsed document
+func documentBody(root *yaml.Node) *yaml.Node {
+	if root == nil {
+		return nil
+	}
+	if root.Kind == yaml.DocumentNode {
+		if len(root.Content) == 0 {
+			return nil
+		}
+		return root.Content[0]
+	}
+	return root
+}
+
+// environNode builds a YAML mapping from CODELY_* variables. It also returns
+// the variable name for each key path and diagnostics for invalid values.
+func environNode(environ []string) (*yaml.Node, map[string]string, []Diagnostic) {
+	values := make(map[string]string)
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(name, "CODELY_") {
+			values[name] = value
+		}
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	names := make(map[string]string)
+	var diags []Diagnostic
+
+	for _, v := range EnvVars {
+		value, ok := values[v.Name]
+		if !ok {
+			continue
+		}
+
+		var leaf *yaml.Node
+		switch v.Tag {
+		case "!!seq":
+			leaf = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for _, item := range filepath.SplitList(value) {
+				if item != "" {
+					leaf.Content = append(leaf.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+				}
+			}
+		case "!!int":
+			if _, err := strconv.Atoi(value); err != nil {
+				diags = append(diags, Diagnostic{Source: "env " + v.Name, Path: v.Path, Message: fmt.Sprintf("invalid integer %q", value)})
+				continue
+			}
+			leaf = &yaml.Node{Kind: yaml.ScalarNode, Tag: v.Tag, Value: value}
+		case "!!bool":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				diags = append(diags, Diagnostic{Source: "env " + v.Name, Path: v.Path, Message: fmt.Sprintf("invalid boolean %q", value)})
+				continue
+			}
+			leaf = &yaml.Node{Kind: yaml.ScalarNode, Tag: v.Tag, Value: strconv.FormatBool(b)}
+		default:
+			leaf = &yaml.Node{Kind: yaml.ScalarNode, Tag: v.Tag, Value: value}
+		}
+
+		setPath(node, strings.Split(v.Path, "."), leaf)
+		names[v.Path] = v.Name
+	}
+
+	return node, names, diags
+}
+
+// setPath sets a value in a mapping node, creating intermediate mappings
+func setPath(node *yaml.Node, keys []string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == keys[0] {
+			if len(keys) == 1 {
+				node.Content[i+1] = value
+			} else {
+				setPath(node.Content[i+1], keys[1:], value)
+			}
+			return
+		}
+	}
+
+	child := value
+	if len(keys) > 1 {
+		child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setPath(child, keys[1:], value)
+	}
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: keys[0]},
+		child,
+	)
+}
+
+// mergeNode merges the mapping src into dst. Nested mappings are merged key by
+// key; scalars and sequences replace the existing value. Null values are
+// treated as unset. Origins of replaced leaves are updated.
+func mergeNode(dst, src *yaml.Node, prefix string, originFor func(path string, n *yaml.Node) Origin, origins map[string]Origin) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		if value.Tag == "!!null" {
+			continue
+		}
+		path := joinPath(prefix, key.Value)
+
+		existing := -1
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value == key.Value {
+				existing = j + 1
+				break
+			}
+		}
+
+		switch {
+		case existing < 0:
+			dst.Content = append(dst.Content, key, value)
+			recordLeaves(value, path, originFor, origins)
+		case dst.Content[existing].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeNode(dst.Content[existing], value, path, originFor, origins)
+		default:
+			for p := range origins {
+				if p == path || strings.HasPrefix(p, path+".") {
+					delete(origins, p)
+				}
+			}
+			dst.Content[existing] = value
+			recordLeaves(value, path, originFor, origins)
+		}
+	}
+}
+
+// recordLeaves records the origin of every scalar or sequence under node
+func recordLeaves(node *yaml.Node, path string, originFor func(path string, n *yaml.Node) Origin, origins map[string]Origin) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			recordLeaves(node.Content[i+1], joinPath(path, node.Content[i].Value), originFor, origins)
+		}
+		return
+	}
+	origins[path] = originFor(path, node)
+}
+
+// locate points diagnostics from validation at the layer that set the value
+func locate(diags []Diagnostic, origins map[string]Origin) {
+	for i := range diags {
+		path, _, _ := strings.Cut(diags[i].Path, "[") // list items share the list's origin
+		origin, ok := origins[path]
+		if !ok {
+			// Whole-entry diagnostics (e.g. a command): use its first field
+			for _, p := range slices.Sorted(maps.Keys(origins)) {
+				if strings.HasPrefix(p, path+".") {
+					origin, ok = origins[p], true
+					break
+				}
+			}
+		}
+		if !ok || origin == OriginDefault {
+			continue
+		}
+		if origin.Env != "" {
+			diags[i].Source = "env " + origin.Env
+			diags[i].Line = 0
+		} else {
+			diags[i].Source = origin.Source
+			diags[i].Line = origin.Line
+		}
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLayer(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadLayered(t *testing.T) {
+	dir := t.TempDir()
+	system := writeLayer(t, dir, "system.yaml", `workspace_roots:
+  - /srv/src
+commands:
+  claude:
+    display_name: Claude (team)
+    exec: claude
+    args: ["--model", "sonnet"]
+ui:
+  manager_width: 40
+`)
+	user := writeLayer(t, dir, "user.yaml", `commands:
+  claude:
+    args: ["--model", "opus"]
+ui:
+  skin: flat
+`)
+
+	cfg, err := LoadLayered(Sources{
+		Files:   []string{system, user, filepath.Join(dir, "missing.yaml")},
+		Environ: []string{"CODELY_UI_SKIN=tree", "CODELY_WORKSPACE_ROOTS=/a:/b", "HOME=/home/me"},
+	})
+	require.NoError(t, err)
+
+	// Files merge field by field; later layers win
+	assert.Equal(t, "Claude (team)", cfg.Commands["claude"].DisplayName)
+	assert.Equal(t, []string{"--model", "opus"}, cfg.Commands["claude"].Args)
+	assert.Equal(t, 40, cfg.UI.ManagerWidth)
+	// Environment wins over files
+	assert.Equal(t, "tree", cfg.UI.Skin)
+	assert.Equal(t, []string{"/a", "/b"}, cfg.WorkspaceRoots)
+	// Defaults fill the rest
+	assert.Equal(t, "1s", cfg.UI.StatusPollInterval)
+	assert.Contains(t, cfg.Commands, "bash")
+
+	assert.Equal(t, Origin{Source: system, Line: 5}, cfg.Origins["commands.claude.display_name"])
+	assert.Equal(t, Origin{Source: user, Line: 3}, cfg.Origins["commands.claude.args"])
+	assert.Equal(t, Origin{Source: system, Line: 9}, cfg.Origins["ui.manager_width"])
+	assert.Equal(t, "env CODELY_UI_SKIN", cfg.Origins["ui.skin"].String())
+	assert.Equal(t, "env CODELY_WORKSPACE_ROOTS", cfg.Origins["workspace_roots"].String())
+	assert.Equal(t, OriginDefault, cfg.Origins["ui.status_poll_interval"])
+	assert.Equal(t, OriginDefault, cfg.Origins["commands.bash.exec"])
+
+	values, err := cfg.Values()
+	require.NoError(t, err)
+	assert.Equal(t, "[/a, /b]", values["workspace_roots"])
+	assert.Equal(t, "40", values["ui.manager_width"])
+	assert.Equal(t, "claude", values["commands.claude.exec"])
+}
+
+func TestLoadLayered_UnsetCommandFieldOrigins(t *testing.T) {
+	dir := t.TempDir()
+	system := writeLayer(t, dir, "system.yaml", `commands:
+  review:
+    exec: claude
+`)
+	user := writeLayer(t, dir, "user.yaml", `ui:
+  skin: flat
+commands:
+  claude:
+    exec: claude
+  review:
+    display_name: Review
+`)
+
+	cfg, err := LoadLayered(Sources{Files: []string{system, user}})
+	require.NoError(t, err)
+
+	// Fields a file left unset come from the file that defined the entry,
+	// not from a default layer
+	assert.Equal(t, Origin{Source: user, Line: 5}, cfg.Origins["commands.claude.exec"])
+	assert.Equal(t, Origin{Source: user, Line: 4}, cfg.Origins["commands.claude.display_name"])
+	assert.Equal(t, Origin{Source: user, Line: 7}, cfg.Origins["commands.review.display_name"])
+	assert.Equal(t, Origin{Source: system, Line: 2}, cfg.Origins["commands.review.args"])
+	// Default commands keep the default origin
+	assert.Equal(t, OriginDefault, cfg.Origins["commands.bash.display_name"])
+}
+
+func TestLoadLayered_DefaultCommandReplacedWhole(t *testing.T) {
+	dir := t.TempDir()
+	user := writeLayer(t, dir, "user.yaml", `commands:
+  claude:
+    exec: claude
+`)
+
+	cfg, err := LoadLayered(Sources{Files: []string{user}})
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Commands["claude"].Args)
+	assert.Empty(t, cfg.Commands["claude"].DisplayName)
+}
+
+func TestLoadLayered_Errors(t *testing.T) {
+	dir := t.TempDir()
+	system := writeLayer(t, dir, "system.yaml", "ui:\n  skin: cards\n")
+	user := writeLayer(t, dir, "user.yaml", "colour: blue\n")
+
+	_, err := LoadLayered(Sources{
+		Files:   []string{system, user},
+		Environ: []string{"CODELY_UI_MANAGER_WIDTH=wide"},
+	})
+
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+
+	var got []string
+	for _, d := range verr.Diagnostics {
+		got = append(got, d.String())
+	}
+	assert.Equal(t, []string{
+		user + `:1: unknown key "colour"`,
+		`env CODELY_UI_MANAGER_WIDTH: ui.manager_width: invalid integer "wide"`,
+		system + `:2: ui.skin: unknown skin "cards" (expected one of tree, flat)`,
+	}, got)
+}
+
+func TestLoadLayered_NoFiles(t *testing.T) {
+	cfg, err := LoadLayered(Sources{})
+	require.NoError(t, err)
+	assert.Equal(t, Default().Commands, cfg.Commands)
+	assert.Equal(t, Default().WorkspaceRoots, cfg.WorkspaceRoots)
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config schema version understood by this release.
+// Files without a version key are version 1.
+const CurrentVersion = 2
+
+// migration upgrades a config from one version to the next
+type migration struct {
+	// apply rewrites a mapping node in place; nil if no keys changed
+	apply func(body *yaml.Node)
+	// assumed holds the values the older version used for absent keys,
+	// by dotted path. They are filled in where no layer sets the key.
+	assumed []assumedValue
+}
+
+// assumedValue is a value an older version used for an absent key
+type assumedValue struct {
+	Path    string
+	Value   *yaml.Node
+	Version int // Version that assumed it; set by upgrade
+}
+
+// migrations upgrade a config one version at a time; migrations[i] upgrades
+// version i+1 to i+2
+var migrations = []migration{
+	// Version 1 treated an absent ui.show_directory or ui.auto_expand_projects
+	// as false; both now default to true
+	{assumed: []assumedValue{
+		{Path: "ui.show_directory", Value: &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "false"}},
+		{Path: "ui.auto_expand_projects", Value: &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "false"}},
+	}},
+}
+
+// migrate upgrades a parsed config document to CurrentVersion in place,
+// filling in the values older versions assumed for absent keys. It returns
+// the version the document was written for and diagnostics for an invalid
+// or unsupported version. An empty document is already current.
+func migrate(root *yaml.Node) (int, []Diagnostic) {
+	from, assumed, diags := upgrade(root)
+	if body := documentBody(root); body != nil && body.Kind == yaml.MappingNode {
+		for _, a := range assumed {
+			assume(body, a)
+		}
+	}
+	return from, diags
+}
+
+// upgrade is migrate without filling in assumed values, which it returns
+// instead. LoadLayered fills them in after merging every layer, so an old
+// file does not override a value set in another layer.
+func upgrade(root *yaml.Node) (int, []assumedValue, []Diagnostic) {
+	body := documentBody(root)
+	if body == nil {
+		return CurrentVersion, nil, nil
+	}
+	if body.Kind != yaml.MappingNode {
+		// Reported by the strict decode
+		return CurrentVersion, nil, nil
+	}
+
+	from := 1
+	if v := mappingValue(body, "version"); v != nil {
+		n, err := strconv.Atoi(v.Value)
+		if err != nil {
+			// Reported by the strict decode as a type error
+			return CurrentVersion, nil, nil
+		}
+		if n < 1 || n > CurrentVersion {
+			msg := fmt.Sprintf("unsupported version %d (this codely supports 1 to %d)", n, CurrentVersion)
+			if n > CurrentVersion {
+				msg = fmt.Sprintf("version %d is newer than this codely supports (%d); upgrade codely", n, CurrentVersion)
+			}
+			return n, nil, []Diagnostic{{Line: v.Line, Path: "version", Message: msg}}
+		}
+		from = n
+	}
+
+	var assumed []assumedValue
+	for v := from; v < CurrentVersion; v++ {
+		m := migrations[v-1]
+		if m.apply != nil {
+			m.apply(body)
+		}
+		for _, a := range m.assumed {
+			a.Version = v
+			assumed = append(assumed, a)
+		}
+	}
+	if from < CurrentVersion {
+		setVersion(body, CurrentVersion)
+	}
+	return from, assumed, nil
+}
+
+// assume sets a's path to its value in a mapping node unless the path is
+// already set. A null parent is replaced by a mapping; any other parent that
+// is not a mapping is left for the strict decode to report.
+func assume(body *yaml.Node, a assumedValue) {
+	keys := strings.Split(a.Path, ".")
+	node := body
+	for _, key := range keys[:len(keys)-1] {
+		child := mappingValue(node, key)
+		switch {
+		case child == nil || child.Tag == "!!null":
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			setPath(node, []string{key}, child)
+		case child.Kind != yaml.MappingNode:
+			return
+		}
+		node = child
+	}
+	last := keys[len(keys)-1]
+	if v := mappingValue(node, last); v == nil || v.Tag == "!!null" {
+		setPath(node, []string{last}, a.Value)
+	}
+}
+
+// Migrate upgrades config file contents to CurrentVersion, keeping comments.
+// It returns the version the file was written for; if that is already
+// CurrentVersion the data is returned unchanged.
+func Migrate(data []byte) ([]byte, int, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, 0, fmt.Errorf("parsing yaml: %w", err)
+	}
+
+	from, diags := migrate(&root)
+	if len(diags) > 0 {
+		return nil, from, &ValidationError{Diagnostics: diags}
+	}
+	if from == CurrentVersion {
+		return data, from, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, from, fmt.Errorf("encoding config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, from, fmt.Errorf("encoding config: %w", err)
+	}
+	return buf.Bytes(), from, nil
+}
+
+// setVersion sets the version key, adding it as the first key if absent. A
+// comment heading the file stays at the top.
+func setVersion(body *yaml.Node, version int) {
+	value := strconv.Itoa(version)
+	if v := mappingValue(body, "version"); v != nil {
+		v.Tag, v.Value = "!!int", value
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	if len(body.Content) > 0 {
+		key.HeadComment, body.Content[0].HeadComment = body.Content[0].HeadComment, ""
+	}
+	body.Content = append([]*yaml.Node{
+		key,
+		{Kind: yaml.ScalarNode, Tag: "!!int", Value: value},
+	}, body.Content...)
+}
+
+// mappingValue returns the value for key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
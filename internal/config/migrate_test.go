@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_TriStateBooleans(t *testing.T) {
+	// Unset booleans default to true
+	cfg, err := Parse([]byte("version: 2\n"))
+	require.NoError(t, err)
+	assert.True(t, cfg.UI.ShowsDirectory())
+	assert.True(t, cfg.UI.AutoExpands())
+	assert.True(t, cfg.Shed.IsEnabled())
+
+	// Explicit false is kept
+	cfg, err = Parse([]byte(`version: 2
+ui:
+  show_directory: false
+  auto_expand_projects: false
+shed:
+  enabled: false
+`))
+	require.NoError(t, err)
+	assert.False(t, cfg.UI.ShowsDirectory())
+	assert.False(t, cfg.UI.AutoExpands())
+	assert.False(t, cfg.Shed.IsEnabled())
+}
+
+func TestParse_MigratesVersion1(t *testing.T) {
+	// Version 1 files treated absent ui booleans as false
+	cfg, err := Parse([]byte("ui:\n  show_directory: true\n"))
+	require.NoError(t, err)
+	assert.Equal(t, CurrentVersion, cfg.Version)
+	assert.True(t, cfg.UI.ShowsDirectory())
+	assert.False(t, cfg.UI.AutoExpands())
+	assert.True(t, cfg.Shed.IsEnabled())
+
+	cfg, err = Parse([]byte("default_command: bash\n"))
+	require.NoError(t, err)
+	assert.False(t, cfg.UI.ShowsDirectory())
+	assert.False(t, cfg.UI.AutoExpands())
+}
+
+func TestParse_UnsupportedVersion(t *testing.T) {
+	_, err := Parse([]byte("default_command: bash\nversion: 9\n"))
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr))
+	require.Len(t, verr.Diagnostics, 1)
+	assert.Equal(t, 2, verr.Diagnostics[0].Line)
+	assert.Contains(t, verr.Diagnostics[0].Message, "newer than this codely supports")
+
+	_, err = Parse([]byte("version: 0\n"))
+	require.True(t, errors.As(err, &verr))
+	assert.Contains(t, verr.Diagnostics[0].Message, "unsupported version 0")
+}
+
+func TestMigrate(t *testing.T) {
+	data := []byte(`# my config
+commands:
+  claude:
+    exec: claude # pinned
+ui:
+  manager_width: 40
+`)
+
+	out, from, err := Migrate(data)
+	require.NoError(t, err)
+	assert.Equal(t, 1, from)
+	assert.Equal(t, `# my config
+version: 2
+commands:
+  claude:
+    exec: claude # pinned
+ui:
+  manager_width: 40
+  show_directory: false
+  auto_expand_projects: false
+`, string(out))
+
+	// Current files are returned unchanged
+	again, from, err := Migrate(out)
+	require.NoError(t, err)
+	assert.Equal(t, CurrentVersion, from)
+	assert.Equal(t, out, again)
+}
+
+func TestLoadLayered_MigratesEachFile(t *testing.T) {
+	dir := t.TempDir()
+	system := writeLayer(t, dir, "system.yaml", "version: 2\nui:\n  show_directory: true\n")
+	user := writeLayer(t, dir, "user.yaml", "default_command: bash\n")
+
+	cfg, err := LoadLayered(Sources{Files: []string{system, user}})
+	require.NoError(t, err)
+	// A version 1 file without the key does not override another layer
+	assert.True(t, cfg.UI.ShowsDirectory())
+	assert.Equal(t, Origin{Source: system, Line: 3}, cfg.Origins["ui.show_directory"])
+	// Keys no layer sets keep version 1's meaning of absent booleans
+	assert.False(t, cfg.UI.AutoExpands())
+	assert.Equal(t, user+" (version 1 default)", cfg.Origins["ui.auto_expand_projects"].String())
+
+	// An explicit value in the version 1 file still applies
+	user = writeLayer(t, dir, "user.yaml", "ui:\n  show_directory: false\n")
+	cfg, err = LoadLayered(Sources{Files: []string{system, user}})
+	require.NoError(t, err)
+	assert.False(t, cfg.UI.ShowsDirectory())
+	assert.Equal(t, Origin{Source: user, Line: 2}, cfg.Origins["ui.show_directory"])
+
+	// So does one set in the environment
+	user = writeLayer(t, dir, "user.yaml", "default_command: bash\n")
+	cfg, err = LoadLayered(Sources{Files: []string{user}, Environ: []string{"CODELY_UI_AUTO_EXPAND_PROJECTS=true"}})
+	require.NoError(t, err)
+	assert.True(t, cfg.UI.AutoExpands())
+	assert.False(t, cfg.UI.ShowsDirectory())
+
+	cfg, err = LoadLayered(Sources{
+		Files:   []string{system},
+		Environ: []string{"CODELY_SHED_ENABLED=false"},
+	})
+	require.NoError(t, err)
+	assert.True(t, cfg.UI.AutoExpands())
+	assert.False(t, cfg.Shed.IsEnabled())
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charliek/codely/internal/pathutil"
+)
+
+// ProjectConfigFile is the per-project override file read from a local
+// project's directory
+const ProjectConfigFile = ".codely.yaml"
+
+// ProjectConfig holds per-project overrides merged over the global Config
+type ProjectConfig struct {
+	Commands       map[string]Command `yaml:"commands"`
+	DefaultCommand string             `yaml:"default_command"`
+	Env            map[string]string  `yaml:"env"`
+	EnvFile        string             `yaml:"env_file"` // .env file for every command, relative to the project
+
+	// Dir is the project directory the file was read from; set by
+	// LoadProject and checked against trusted_projects
+	Dir string `yaml:"-"`
+}
+
+// LoadProject reads ProjectConfigFile from a project directory. It returns
+// nil without an error if the file does not exist.
+func LoadProject(dir string) (*ProjectConfig, error) {
+	path := filepath.Join(pathutil.ExpandPath(dir), ProjectConfigFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", ProjectConfigFile, err)
+	}
+
+	pc, err := ParseProject(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ProjectConfigFile, err)
+	}
+	pc.Dir = filepath.Dir(path)
+	return pc, nil
+}
+
+// ParseProject strictly parses per-project overrides from YAML bytes
+func ParseProject(data []byte) (*ProjectConfig, error) {
+	var pc ProjectConfig
+	root, diags, err := decodeStrict(data, &pc)
+	if err != nil {
+		return nil, err
+	}
+
+	at := func(path ...string) Diagnostic {
+		return Diagnostic{Line: lineOf(root, path...), Path: strings.Join(path, ".")}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(pc.Commands)) {
+		diags = append(diags, templateDiagnostics(pc.Commands[id], at, "commands", id)...)
+		mode := pc.Commands[id].StatusDetection
+		if mode != "" && !slices.Contains(StatusDetectionModes, mode) {
+			diags = append(diags, Diagnostic{
+				Line: lineOf(root, "commands", id, "status_detection"),
+				Path: "commands." + id + ".status_detection",
+				Message: fmt.Sprintf("unknown mode %q (expected one of %s)",
+					mode, strings.Join(StatusDetectionModes, ", ")),
+			})
+		}
+	}
+
+	diags = append(diags, envTemplateDiagnostics(pc.Env, at, "env")...)
+
+	if len(diags) > 0 {
+		return nil, &ValidationError{Diagnostics: diags}
+	}
+	return &pc, nil
+}
+
+// WithProject returns a copy of c with per-project overrides applied. Fields
+// set on a project command override the global command with the same ID, and
+// environment is layered global command env and env_from < project env <
+// project command env and env_from. Unless the project directory is in
+// trusted_projects, fields that change what runs are ignored. Problems with
+// the merged result are returned in Warnings, replacing those of c. A nil pc
+// returns c unchanged.
+func (c *Config) WithProject(pc *ProjectConfig) *Config {
+	if pc == nil {
+		return c
+	}
+
+	merged := *c
+	merged.Warnings = nil
+	if !c.Trusts(pc.Dir) {
+		pc, merged.Warnings = restrictUntrusted(pc, c.Commands)
+	}
+	merged.Commands = make(map[string]Command, len(c.Commands)+len(pc.Commands))
+	maps.Copy(merged.Commands, c.Commands)
+
+	for id, override := range pc.Commands {
+		cmd := merged.Commands[id]
+		if override.DisplayName != "" {
+			cmd.DisplayName = override.DisplayName
+		}
+		if override.Exec != "" {
+			cmd.Exec = override.Exec
+		}
+		if override.Args != nil {
+			cmd.Args = override.Args
+		}
+		if override.StatusDetection != "" {
+			cmd.StatusDetection = override.StatusDetection
+		}
+		if override.ResumeArgs != nil {
+			cmd.ResumeArgs = override.ResumeArgs
+		}
+		if override.EnvFile != "" {
+			cmd.EnvFile = override.EnvFile
+		}
+		merged.Commands[id] = cmd
+	}
+
+	for id, cmd := range merged.Commands {
+		override := pc.Commands[id]
+		if pc.EnvFile != "" && override.EnvFile == "" {
+			cmd.EnvFile = pc.EnvFile
+			merged.Commands[id] = cmd
+		}
+		if len(pc.Env) == 0 && len(override.Env) == 0 && len(override.EnvFrom) == 0 {
+			continue
+		}
+		env := maps.Clone(cmd.Env)
+		envFrom := maps.Clone(cmd.EnvFrom)
+		for _, layer := range []map[string]string{pc.Env, override.Env} {
+			for key, value := range layer {
+				if env == nil {
+					env = make(map[string]string)
+				}
+				env[key] = value
+				delete(envFrom, key)
+			}
+		}
+		for key, src := range override.EnvFrom {
+			if envFrom == nil {
+				envFrom = make(map[string]EnvSource)
+			}
+			envFrom[key] = src
+			delete(env, key)
+		}
+		cmd.Env = env
+		cmd.EnvFrom = envFrom
+		merged.Commands[id] = cmd
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(merged.Commands)) {
+		if merged.Commands[id].Exec == "" {
+			delete(merged.Commands, id)
+			merged.Warnings = append(merged.Warnings, Diagnostic{
+				Path:    ProjectConfigFile + ": commands." + id,
+				Message: "exec is required for a new command; ignoring it",
+			})
+		}
+	}
+
+	if pc.DefaultCommand != "" {
+		merged.DefaultCommand = pc.DefaultCommand
+		if _, ok := merged.Commands[pc.DefaultCommand]; !ok {
+			merged.Warnings = append(merged.Warnings, Diagnostic{
+				Path:    ProjectConfigFile + ": default_command",
+				Message: fmt.Sprintf("%q is not a configured command", pc.DefaultCommand),
+			})
+		}
+	}
+
+	return &merged
+}
+
+// Trusts reports whether dir is, or is inside, a directory listed in
+// trusted_projects
+func (c *Config) Trusts(dir string) bool {
+	if dir == "" {
+		return false
+	}
+	dir = pathutil.ExpandPath(dir)
+	for _, trusted := range c.TrustedProjects {
+		trusted = pathutil.ExpandPath(trusted)
+		if trusted == "" {
+			continue
+		}
+		rel, err := filepath.Rel(trusted, dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictUntrusted returns a copy of pc limited to the fields an untrusted
+// project file may set: default_command, and a command's display_name and
+// status_detection. Env is ignored too, since variables such as PATH or
+// LD_PRELOAD change what runs. Each ignored field is reported as a warning,
+// and commands not in global are dropped since they cannot set exec.
+func restrictUntrusted(pc *ProjectConfig, global map[string]Command) (*ProjectConfig, []Diagnostic) {
+	var warnings []Diagnostic
+	ignore := func(path string) {
+		warnings = append(warnings, Diagnostic{
+			Path:    ProjectConfigFile + ": " + path,
+			Message: "ignored: the project is not in trusted_projects",
+		})
+	}
+
+	restricted := &ProjectConfig{
+		DefaultCommand: pc.DefaultCommand,
+		Dir:            pc.Dir,
+	}
+	if len(pc.Env) > 0 {
+		ignore("env")
+	}
+	if pc.EnvFile != "" {
+		ignore("env_file")
+	}
+	for _, id := range slices.Sorted(maps.Keys(pc.Commands)) {
+		cmd := pc.Commands[id]
+		if _, ok := global[id]; !ok {
+			ignore("commands." + id)
+			continue
+		}
+		if cmd.Exec != "" {
+			ignore("commands." + id + ".exec")
+		}
+		if cmd.Args != nil {
+			ignore("commands." + id + ".args")
+		}
+		if cmd.ResumeArgs != nil {
+			ignore("commands." + id + ".resume_args")
+		}
+		if len(cmd.Env) > 0 {
+			ignore("commands." + id + ".env")
+		}
+		if len(cmd.EnvFrom) > 0 {
+			ignore("commands." + id + ".env_from")
+		}
+		if cmd.EnvFile != "" {
+			ignore("commands." + id + ".env_file")
+		}
+		if restricted.Commands == nil {
+			restricted.Commands = make(map[string]Command)
+		}
+		restricted.Commands[id] = Command{
+			DisplayName:     cmd.DisplayName,
+			StatusDetection: cmd.StatusDetection,
+		}
+	}
+	return restricted, warnings
+}
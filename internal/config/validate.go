@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charliek/codely/internal/launch"
+	"gopkg.in/yaml.v3"
+)
+
+// StatusDetectionModes lists the accepted status_detection values
+var StatusDetectionModes = []string{"auto", "generic", "claude", "opencode", "codex", "shell"}
+
+// Skins lists the accepted ui.skin values
+var Skins = []string{"tree", "flat"}
+
+// minPollInterval is the shortest status_poll_interval accepted without a warning
+const minPollInterval = 100 * time.Millisecond
+
+// Diagnostic is a config problem tied to a key and, when known, the file
+// (or environment variable) and line it came from
+type Diagnostic struct {
+	Source  string `json:"source,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	msg := d.Message
+	if d.Path != "" {
+		msg = d.Path + ": " + msg
+	}
+	switch {
+	case d.Source != "" && d.Line > 0:
+		msg = fmt.Sprintf("%s:%d: %s", d.Source, d.Line, msg)
+	case d.Source != "":
+		msg = d.Source + ": " + msg
+	case d.Line > 0:
+		msg = fmt.Sprintf("line %d: %s", d.Line, msg)
+	}
+	return msg
+}
+
+// ValidationError reports every error found in a config file
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		lines = append(lines, d.String())
+	}
+	return "invalid config:\n  " + strings.Join(lines, "\n  ")
+}
+
+var (
+	typeErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+	unknownField  = regexp.MustCompile(`^field (\S+) not found in type \S+$`)
+)
+
+// typeErrorDiagnostics converts the messages of a yaml.TypeError into diagnostics
+func typeErrorDiagnostics(err *yaml.TypeError) []Diagnostic {
+	diags := make([]Diagnostic, 0, len(err.Errors))
+	for _, msg := range err.Errors {
+		d := Diagnostic{Message: msg}
+		if m := typeErrorLine.FindStringSubmatch(msg); m != nil {
+			d.Line, _ = strconv.Atoi(m[1])
+			d.Message = m[2]
+		}
+		if m := unknownField.FindStringSubmatch(d.Message); m != nil {
+			d.Message = fmt.Sprintf("unknown key %q", m[1])
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+// validate checks semantic rules on a decoded config, using the YAML tree for
+// line numbers. It returns errors and warnings separately.
+func validate(c *Config, root *yaml.Node) (errs, warnings []Diagnostic) {
+	at := func(path ...string) Diagnostic {
+		return Diagnostic{Line: lineOf(root, path...), Path: strings.Join(path, ".")}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(c.Commands)) {
+		cmd := c.Commands[id]
+		if cmd.Exec == "" {
+			d := at("commands", id)
+			d.Message = "exec is required"
+			errs = append(errs, d)
+		}
+		if cmd.StatusDetection != "" && !slices.Contains(StatusDetectionModes, cmd.StatusDetection) {
+			d := at("commands", id, "status_detection")
+			d.Message = fmt.Sprintf("unknown mode %q (expected one of %s)",
+				cmd.StatusDetection, strings.Join(StatusDetectionModes, ", "))
+			errs = append(errs, d)
+		}
+		errs = append(errs, templateDiagnostics(cmd, at, "commands", id)...)
+	}
+
+	if c.Discovery.MaxDepth < 0 {
+		d := at("discovery", "max_depth")
+		d.Message = "must not be negative"
+		errs = append(errs, d)
+	}
+	for i, glob := range c.Discovery.Ignore {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			d := at("discovery", "ignore")
+			d.Path = fmt.Sprintf("%s[%d]", d.Path, i)
+			d.Message = fmt.Sprintf("invalid glob %q", glob)
+			errs = append(errs, d)
+		}
+	}
+
+	if _, ok := c.Commands[c.DefaultCommand]; !ok {
+		d := at("default_command")
+		d.Message = fmt.Sprintf("%q is not a configured command", c.DefaultCommand)
+		warnings = append(warnings, d)
+	}
+
+	if c.UI.ManagerWidth < 0 {
+		d := at("ui", "manager_width")
+		d.Message = "must not be negative"
+		errs = append(errs, d)
+	}
+
+	if interval, err := time.ParseDuration(c.UI.StatusPollInterval); err != nil || interval <= 0 {
+		d := at("ui", "status_poll_interval")
+		d.Message = fmt.Sprintf("invalid duration %q (e.g. 500ms, 1s)", c.UI.StatusPollInterval)
+		errs = append(errs, d)
+	} else if interval < minPollInterval {
+		d := at("ui", "status_poll_interval")
+		d.Message = fmt.Sprintf("%s is very short and may use noticeable CPU", interval)
+		warnings = append(warnings, d)
+	}
+
+	if !slices.Contains(Skins, c.UI.Skin) {
+		d := at("ui", "skin")
+		d.Message = fmt.Sprintf("unknown skin %q (expected one of %s)", c.UI.Skin, strings.Join(Skins, ", "))
+		errs = append(errs, d)
+	}
+
+	return errs, warnings
+}
+
+// templateDiagnostics reports args, resume_args and env values of cmd that
+// are not valid launch templates, and invalid env_from sources. prefix is the
+// path of the command.
+func templateDiagnostics(cmd Command, at func(path ...string) Diagnostic, prefix ...string) []Diagnostic {
+	var diags []Diagnostic
+	for _, list := range []struct {
+		field string
+		args  []string
+	}{{"args", cmd.Args}, {"resume_args", cmd.ResumeArgs}} {
+		for i, arg := range list.args {
+			if err := launch.Check(arg); err != nil {
+				d := at(append(prefix, list.field)...)
+				d.Path = fmt.Sprintf("%s[%d]", d.Path, i)
+				d.Message = fmt.Sprintf("invalid template: %v", err)
+				diags = append(diags, d)
+			}
+		}
+	}
+	diags = append(diags, envTemplateDiagnostics(cmd.Env, at, append(prefix, "env")...)...)
+	if err := launch.Check(cmd.EnvFile); err != nil {
+		d := at(append(prefix, "env_file")...)
+		d.Message = fmt.Sprintf("invalid template: %v", err)
+		diags = append(diags, d)
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(cmd.EnvFrom)) {
+		src := cmd.EnvFrom[key]
+		path := append(slices.Clone(prefix), "env_from", key)
+		set := 0
+		for _, v := range []string{src.File, src.Env, src.Command} {
+			if v != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			d := at(path...)
+			d.Message = "set exactly one of file, env or command"
+			diags = append(diags, d)
+		}
+		if _, ok := cmd.Env[key]; ok {
+			d := at(path...)
+			d.Message = "also set in env; use one or the other"
+			diags = append(diags, d)
+		}
+		for _, f := range []struct{ field, value string }{{"file", src.File}, {"command", src.Command}} {
+			if err := launch.Check(f.value); err != nil {
+				d := at(append(path, f.field)...)
+				d.Message = fmt.Sprintf("invalid template: %v", err)
+				diags = append(diags, d)
+			}
+		}
+	}
+	return diags
+}
+
+// envTemplateDiagnostics reports env values that are not valid launch
+// templates. prefix is the path of the env mapping.
+func envTemplateDiagnostics(env map[string]string, at func(path ...string) Diagnostic, prefix ...string) []Diagnostic {
+	var diags []Diagnostic
+	for _, key := range slices.Sorted(maps.Keys(env)) {
+		if err := launch.Check(env[key]); err != nil {
+			d := at(append(slices.Clone(prefix), key)...)
+			d.Message = fmt.Sprintf("invalid template: %v", err)
+			diags = append(diags, d)
+		}
+	}
+	return diags
+}
+
+// lineOf returns the line of the value at path in a YAML document, or 0 if
+// the path is not present
+func lineOf(root *yaml.Node, path ...string) int {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return 0
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return 0
+		}
+		node = next
+	}
+	return node.Line
+}
@@ -8,8 +8,51 @@ const (
 	// DefaultConfigPath is the default configuration file path
 	DefaultConfigPath = "~/.config/codely/config.yaml"
 
+	// SystemConfigPath is the system-wide configuration file, read before the
+	// user's file
+	SystemConfigPath = "/etc/codely/config.yaml"
+
 	// DefaultStatePath is the default state file path
 	DefaultStatePath = "~/.local/state/codely/session.json"
+
+	// DefaultSocketPath is the default control socket path
+	DefaultSocketPath = "~/.local/state/codely/control.sock"
+
+	// DefaultFolderCachePath caches the folder picker's last scan
+	DefaultFolderCachePath = "~/.cache/codely/folders.json"
+
+	// DefaultHistoryPath archives closed and exited sessions
+	DefaultHistoryPath = "~/.local/state/codely/history.jsonl"
+
+	// DefaultEventsPath logs session status transitions
+	DefaultEventsPath = "~/.local/state/codely/events.jsonl"
+
+	// DefaultFrecencyPath records how often and recently folders are opened
+	DefaultFrecencyPath = "~/.local/state/codely/frecency.json"
+)
+
+// Workspace defaults
+const (
+	// DefaultWorkspace is the workspace used when none is named. It keeps the
+	// state, socket, history and events paths above.
+	DefaultWorkspace = "default"
+
+	// WorkspacesDir holds a directory per named workspace
+	WorkspacesDir = "~/.local/state/codely/workspaces"
+
+	// DefaultTmuxSession is the tmux session of the default workspace; named
+	// workspaces append "-<name>"
+	DefaultTmuxSession = "codely"
+
+	// WorkspaceEnv names the workspace when --workspace is not given
+	WorkspaceEnv = "CODELY_WORKSPACE"
+)
+
+// Discovery defaults
+const (
+	// DefaultDiscoveryMaxDepth is how many levels below each workspace root
+	// are searched for git repositories
+	DefaultDiscoveryMaxDepth = 3
 )
 
 // UI defaults
@@ -0,0 +1,214 @@
+// Package control provides a local Unix socket for driving a running codely
+// instance from editors, scripts and other tools.
+//
+// The protocol is newline-delimited JSON: each line written by a client is a
+// Request and the server answers each one with a single Response line.
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charliek/codely/internal/debug"
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/pathutil"
+)
+
+// Supported request actions
+const (
+	ActionList   = "list"
+	ActionFocus  = "focus"
+	ActionSpawn  = "spawn"
+	ActionClose  = "close"
+	ActionRename = "rename"
+	ActionSend   = "send"
+)
+
+// dialTimeout bounds how long clients wait to connect and for a response
+const dialTimeout = 10 * time.Second
+
+// Request is a single control request
+type Request struct {
+	Action    string `json:"action"`
+	Project   string `json:"project,omitempty"`    // Project ID or name (spawn)
+	SessionID string `json:"session_id,omitempty"` // Target session (focus, close, rename, send)
+	Command   string `json:"command,omitempty"`    // Command ID (spawn); defaults to default_command
+	Name      string `json:"name,omitempty"`       // New display name (rename); blank resets
+	Text      string `json:"text,omitempty"`       // Text to type into the pane (send)
+	Enter     bool   `json:"enter,omitempty"`      // Press Enter after the text (send)
+}
+
+// Response is the reply to a Request
+type Response struct {
+	OK        bool          `json:"ok"`
+	Error     string        `json:"error,omitempty"`
+	SessionID string        `json:"session_id,omitempty"` // Session created by spawn
+	Sessions  []SessionInfo `json:"sessions,omitempty"`   // Sessions returned by list
+}
+
+// SessionInfo describes a session in a list response
+type SessionInfo struct {
+	ProjectID string        `json:"project_id"`
+	Project   string        `json:"project"`
+	SessionID string        `json:"session_id"`
+	Name      string        `json:"name"`
+	Command   string        `json:"command"`
+	Status    domain.Status `json:"status"`
+	PaneID    int           `json:"pane_id"`
+	Visible   bool          `json:"visible"`
+}
+
+// Errorf builds a failed Response
+func Errorf(format string, a ...any) Response {
+	return Response{Error: fmt.Sprintf(format, a...)}
+}
+
+// Handler processes a request and returns its response
+type Handler func(Request) Response
+
+// Server accepts control connections on a Unix socket
+type Server struct {
+	path     string
+	listener net.Listener
+	handler  Handler
+	wg       sync.WaitGroup
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// Listen creates the control socket at path. A stale socket left behind by a
+// crashed instance is replaced; a socket with a live listener is an error.
+func Listen(path string, handler Handler) (*Server, error) {
+	path = pathutil.ExpandPath(path)
+
+	// Ensure directory exists with restricted permissions (owner only)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("creating socket directory: %w", err)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if conn, dialErr := net.DialTimeout("unix", path, time.Second); dialErr == nil {
+			conn.Close()
+			return nil, fmt.Errorf("control socket %s is in use by another codely instance", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale control socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listening on control socket: %w", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("restricting control socket permissions: %w", err)
+	}
+
+	return &Server{
+		path:     path,
+		listener: listener,
+		handler:  handler,
+		conns:    make(map[net.Conn]struct{}),
+	}, nil
+}
+
+// Path returns the socket path
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Serve accepts connections until Close is called
+func (s *Server) Serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				debug.Log("control: accept error: %v", err)
+			}
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn)
+		}()
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	enc := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		var req Request
+		var resp Response
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp = Errorf("invalid request: %v", err)
+		} else {
+			debug.Log("control: action=%s project=%s session=%s", req.Action, req.Project, req.SessionID)
+			resp = s.handler(req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// Close stops accepting connections, disconnects clients and removes the
+// socket file
+func (s *Server) Close() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	_ = os.Remove(s.path)
+	return err
+}
+
+// Call sends a single request to the socket at path and returns the response
+func Call(path string, req Request) (Response, error) {
+	path = pathutil.ExpandPath(path)
+
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return Response{}, fmt.Errorf("connecting to codely: %w", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, fmt.Errorf("sending request: %w", err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("reading response: %w", err)
+	}
+	return resp, nil
+}
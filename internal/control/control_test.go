@@ -0,0 +1,91 @@
+package control
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerCall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+
+	var got Request
+	srv, err := Listen(path, func(req Request) Response {
+		got = req
+		return Response{OK: true, Sessions: []SessionInfo{{SessionID: "sess-1", Project: "api"}}}
+	})
+	require.NoError(t, err)
+	go srv.Serve()
+	defer srv.Close()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	resp, err := Call(path, Request{Action: ActionList})
+	require.NoError(t, err)
+	assert.True(t, resp.OK)
+	assert.Equal(t, ActionList, got.Action)
+	require.Len(t, resp.Sessions, 1)
+	assert.Equal(t, "sess-1", resp.Sessions[0].SessionID)
+}
+
+func TestServerInvalidRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+
+	srv, err := Listen(path, func(req Request) Response {
+		return Response{OK: true}
+	})
+	require.NoError(t, err)
+	go srv.Serve()
+	defer srv.Close()
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("not json\n"))
+	require.NoError(t, err)
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Contains(t, line, "invalid request")
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+	require.NoError(t, os.WriteFile(path, nil, 0600))
+
+	srv, err := Listen(path, func(req Request) Response { return Response{OK: true} })
+	require.NoError(t, err)
+	defer srv.Close()
+}
+
+func TestListenRejectsLiveSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+
+	srv, err := Listen(path, func(req Request) Response { return Response{OK: true} })
+	require.NoError(t, err)
+	go srv.Serve()
+	defer srv.Close()
+
+	_, err = Listen(path, func(req Request) Response { return Response{OK: true} })
+	assert.ErrorContains(t, err, "in use")
+}
+
+func TestCloseRemovesSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+
+	srv, err := Listen(path, func(req Request) Response { return Response{OK: true} })
+	require.NoError(t, err)
+	go srv.Serve()
+
+	require.NoError(t, srv.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,143 @@
+// Package discovery finds project directories under the workspace roots for
+// the folder picker, and caches the result between runs.
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charliek/codely/internal/pathutil"
+)
+
+// Options controls a scan
+type Options struct {
+	Roots    []string `json:"roots"`
+	MaxDepth int      `json:"max_depth"` // Levels below a root to search; 1 lists direct children only
+	Ignore   []string `json:"ignore"`    // Globs matched against directory names and root-relative paths
+}
+
+// Scan lists candidate project directories. Every visible directory directly
+// under a root is listed; deeper directories are listed only if they are git
+// repositories. Scanning does not descend into repositories, hidden
+// directories or ignored directories. Missing roots are skipped.
+func Scan(opts Options) []string {
+	var folders []string
+	seen := make(map[string]bool)
+
+	for _, root := range opts.Roots {
+		root = filepath.Clean(pathutil.ExpandPath(root))
+		walk(root, root, 1, opts, func(dir string) {
+			if !seen[dir] {
+				seen[dir] = true
+				folders = append(folders, dir)
+			}
+		})
+	}
+
+	return folders
+}
+
+// walk visits the subdirectories of dir, which is depth-1 levels below root
+func walk(root, dir string, depth int, opts Options, found func(string)) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if ignored(root, path, opts.Ignore) {
+			continue
+		}
+
+		repo := isRepo(path)
+		if depth == 1 || repo {
+			found(path)
+		}
+		if !repo && depth < opts.MaxDepth {
+			walk(root, path, depth+1, opts, found)
+		}
+	}
+}
+
+// isRepo reports whether dir is the root of a git repository or worktree
+func isRepo(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// ignored reports whether a directory matches an ignore glob, by name or by
+// its path relative to the root
+func ignored(root, path string, globs []string) bool {
+	name := filepath.Base(path)
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(glob, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheFile is the on-disk form of a cached scan
+type cacheFile struct {
+	Options   Options   `json:"options"`
+	ScannedAt time.Time `json:"scanned_at"`
+	Folders   []string  `json:"folders"`
+}
+
+// LoadCache returns the folders cached by a scan with the same options. It
+// returns false if there is no usable cache.
+func LoadCache(path string, opts Options) ([]string, bool) {
+	data, err := os.ReadFile(pathutil.ExpandPath(path))
+	if err != nil {
+		return nil, false
+	}
+
+	var cache cacheFile
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, false
+	}
+	if !cache.Options.Equal(opts) {
+		return nil, false
+	}
+	return cache.Folders, true
+}
+
+// SaveCache writes the result of a scan
+func SaveCache(path string, opts Options, folders []string) error {
+	path = pathutil.ExpandPath(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(cacheFile{Options: opts, ScannedAt: time.Now(), Folders: folders})
+	if err != nil {
+		return fmt.Errorf("encoding folder cache: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing folder cache: %w", err)
+	}
+	return nil
+}
+
+// Equal reports whether two scans would search the same directories
+func (o Options) Equal(other Options) bool {
+	return o.MaxDepth == other.MaxDepth &&
+		slices.Equal(o.Roots, other.Roots) &&
+		slices.Equal(o.Ignore, other.Ignore)
+}
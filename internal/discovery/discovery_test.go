@@ -0,0 +1,75 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mkdirs(t *testing.T, root string, dirs ...string) {
+	t.Helper()
+	for _, dir := range dirs {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0o755))
+	}
+}
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	mkdirs(t, root,
+		"notes",
+		".hidden/repo/.git",
+		"api/.git",
+		"api/nested/.git", // inside a repo: not listed
+		"github.com/org/web/.git",
+		"github.com/org/cli/.git",
+		"github.com/org/docs", // not a repo below depth 1
+		"github.com/org/deep/er/repo/.git",
+		"node_modules/pkg/.git",
+		"archive/old/.git",
+	)
+
+	folders := Scan(Options{
+		Roots:    []string{root, filepath.Join(root, "missing")},
+		MaxDepth: 3,
+		Ignore:   []string{"node_modules", "archive/*"},
+	})
+
+	assert.Equal(t, []string{
+		filepath.Join(root, "api"),
+		filepath.Join(root, "archive"),
+		filepath.Join(root, "github.com"),
+		filepath.Join(root, "github.com/org/cli"),
+		filepath.Join(root, "github.com/org/web"),
+		filepath.Join(root, "notes"),
+	}, folders)
+
+	// Depth 1 lists direct children only
+	folders = Scan(Options{Roots: []string{root}, MaxDepth: 1})
+	assert.Equal(t, []string{
+		filepath.Join(root, "api"),
+		filepath.Join(root, "archive"),
+		filepath.Join(root, "github.com"),
+		filepath.Join(root, "node_modules"),
+		filepath.Join(root, "notes"),
+	}, folders)
+}
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "folders.json")
+	opts := Options{Roots: []string{"~/src"}, MaxDepth: 3, Ignore: []string{"vendor"}}
+
+	_, ok := LoadCache(path, opts)
+	assert.False(t, ok)
+
+	require.NoError(t, SaveCache(path, opts, []string{"/home/me/src/api"}))
+	folders, ok := LoadCache(path, opts)
+	require.True(t, ok)
+	assert.Equal(t, []string{"/home/me/src/api"}, folders)
+
+	// A cache from different options is not used
+	_, ok = LoadCache(path, Options{Roots: []string{"~/src"}, MaxDepth: 2, Ignore: []string{"vendor"}})
+	assert.False(t, ok)
+}
@@ -0,0 +1,325 @@
+// Package doctor checks the environment codely depends on and reports
+// actionable findings.
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charliek/codely/internal/config"
+	"github.com/charliek/codely/internal/pathutil"
+	"github.com/charliek/codely/internal/shed"
+	"github.com/charliek/codely/internal/store"
+	"github.com/charliek/codely/internal/tmux"
+)
+
+// Severity classifies a finding
+type Severity string
+
+const (
+	SeverityOK   Severity = "ok"
+	SeverityWarn Severity = "warn"
+	SeverityFail Severity = "fail"
+)
+
+// minTmuxVersion is the oldest tmux with pane-scoped options (set-option -p)
+const minTmuxVersion = 3.0
+
+// Finding is the result of a single check
+type Finding struct {
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Hint     string   `json:"hint,omitempty"`
+}
+
+// Env provides the probes used by the checks so tests can replace them
+type Env struct {
+	LookPath       func(file string) (string, error)
+	TmuxVersion    func() (string, error)
+	JoinPaneFormat func() bool
+	InTmux         func() bool
+	Shed           shed.Client
+	ConfigSources  config.Sources
+	StatePath      string
+}
+
+// DefaultEnv returns an Env backed by the real system
+func DefaultEnv(configSources config.Sources, statePath string) Env {
+	return Env{
+		LookPath:       exec.LookPath,
+		TmuxVersion:    tmux.Version,
+		JoinPaneFormat: tmux.ProbeJoinPaneFormat,
+		InTmux:         tmux.NewClient().InTmux,
+		Shed:           shed.NewClient(),
+		ConfigSources:  configSources,
+		StatePath:      statePath,
+	}
+}
+
+// Run executes all checks in order
+func Run(env Env) []Finding {
+	var findings []Finding
+	findings = append(findings, checkTmux(env)...)
+	findings = append(findings, checkShed(env)...)
+	findings = append(findings, checkConfig(env)...)
+	findings = append(findings, checkState(env)...)
+	return findings
+}
+
+// Failed returns the number of failed findings
+func Failed(findings []Finding) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity == SeverityFail {
+			n++
+		}
+	}
+	return n
+}
+
+func checkTmux(env Env) []Finding {
+	if _, err := env.LookPath("tmux"); err != nil {
+		return []Finding{{
+			Check:    "tmux",
+			Severity: SeverityFail,
+			Message:  "tmux not found on PATH",
+			Hint:     "install tmux 3.0 or newer (e.g. brew install tmux, apt install tmux)",
+		}}
+	}
+
+	var findings []Finding
+
+	version, err := env.TmuxVersion()
+	switch {
+	case err != nil:
+		findings = append(findings, Finding{
+			Check:    "tmux",
+			Severity: SeverityFail,
+			Message:  fmt.Sprintf("cannot determine tmux version: %v", err),
+		})
+	case parseVersion(version) < minTmuxVersion:
+		findings = append(findings, Finding{
+			Check:    "tmux",
+			Severity: SeverityWarn,
+			Message:  fmt.Sprintf("tmux %s is older than %.1f", version, minTmuxVersion),
+			Hint:     "pane tagging and session reattachment need tmux 3.0 or newer",
+		})
+	default:
+		findings = append(findings, Finding{
+			Check:    "tmux",
+			Severity: SeverityOK,
+			Message:  fmt.Sprintf("tmux %s", version),
+		})
+	}
+
+	if env.JoinPaneFormat() {
+		findings = append(findings, Finding{
+			Check:    "tmux join-pane",
+			Severity: SeverityOK,
+			Message:  "join-pane supports -P",
+		})
+	} else {
+		findings = append(findings, Finding{
+			Check:    "tmux join-pane",
+			Severity: SeverityWarn,
+			Message:  "join-pane does not support -P; pane IDs are assumed unchanged after join",
+			Hint:     "upgrade tmux for exact pane tracking when switching sessions",
+		})
+	}
+
+	if env.InTmux() {
+		findings = append(findings, Finding{
+			Check:    "tmux session",
+			Severity: SeverityOK,
+			Message:  "running inside tmux",
+		})
+	} else {
+		findings = append(findings, Finding{
+			Check:    "tmux session",
+			Severity: SeverityOK,
+			Message:  "not inside tmux; codely will create or attach its tmux session on start",
+		})
+	}
+
+	return findings
+}
+
+func checkShed(env Env) []Finding {
+	if _, err := env.LookPath("shed"); err != nil || env.Shed == nil {
+		return []Finding{{
+			Check:    "shed",
+			Severity: SeverityWarn,
+			Message:  "shed CLI not found on PATH; remote shed projects are disabled",
+			Hint:     "install shed to use remote development containers",
+		}}
+	}
+
+	servers, err := env.Shed.ListServers()
+	if err != nil {
+		return []Finding{{
+			Check:    "shed",
+			Severity: SeverityFail,
+			Message:  fmt.Sprintf("shed server list failed: %v", err),
+			Hint:     "run `shed server list` to debug server configuration",
+		}}
+	}
+	if len(servers) == 0 {
+		return []Finding{{
+			Check:    "shed",
+			Severity: SeverityWarn,
+			Message:  "no shed servers configured",
+			Hint:     "add a server with `shed server add`",
+		}}
+	}
+
+	names := make([]string, 0, len(servers))
+	for _, s := range servers {
+		names = append(names, s.Name)
+	}
+	return []Finding{{
+		Check:    "shed",
+		Severity: SeverityOK,
+		Message:  fmt.Sprintf("%d server(s): %s", len(servers), strings.Join(names, ", ")),
+	}}
+}
+
+// configSourceList names the config files that exist, or the built-in
+// defaults if there are none
+func configSourceList(src config.Sources) string {
+	var files []string
+	for _, file := range src.Files {
+		path := pathutil.ExpandPath(file)
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		return "built-in defaults"
+	}
+	return strings.Join(files, ", ")
+}
+
+func checkConfig(env Env) []Finding {
+	cfg, err := config.LoadLayered(env.ConfigSources)
+	if err != nil {
+		var verr *config.ValidationError
+		switch {
+		case errors.As(err, &verr):
+			findings := make([]Finding, 0, len(verr.Diagnostics))
+			for _, d := range verr.Diagnostics {
+				findings = append(findings, Finding{
+					Check:    "config",
+					Severity: SeverityFail,
+					Message:  d.String(),
+				})
+			}
+			findings[0].Hint = "run `codely config validate` after fixing the listed values"
+			return findings
+		default:
+			return []Finding{{
+				Check:    "config",
+				Severity: SeverityFail,
+				Message:  err.Error(),
+				Hint:     "fix the YAML in the named config file",
+			}}
+		}
+	}
+
+	findings := []Finding{{
+		Check:    "config",
+		Severity: SeverityOK,
+		Message:  fmt.Sprintf("%d command(s) configured from %s", len(cfg.Commands), configSourceList(env.ConfigSources)),
+	}}
+
+	for _, w := range cfg.Warnings {
+		findings = append(findings, Finding{
+			Check:    "config",
+			Severity: SeverityWarn,
+			Message:  w.String(),
+		})
+	}
+
+	ids := make([]string, 0, len(cfg.Commands))
+	for id := range cfg.Commands {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		cmd := cfg.Commands[id]
+		check := "command " + id
+		path, err := env.LookPath(cmd.Exec)
+		if err != nil {
+			findings = append(findings, Finding{
+				Check:    check,
+				Severity: SeverityWarn,
+				Message:  fmt.Sprintf("%q not found on PATH", cmd.Exec),
+				Hint:     "install it or remove the command from your config (it may still exist inside sheds)",
+			})
+			continue
+		}
+		findings = append(findings, Finding{
+			Check:    check,
+			Severity: SeverityOK,
+			Message:  path,
+		})
+	}
+
+	return findings
+}
+
+func checkState(env Env) []Finding {
+	st := store.New(env.StatePath)
+	if err := st.Load(); err != nil {
+		hint := fmt.Sprintf("move %s aside to start with an empty state", env.StatePath)
+		if errors.Is(err, store.ErrNewerVersion) {
+			hint = "upgrade codely to the release that wrote the state file"
+		}
+		return []Finding{{
+			Check:    "state",
+			Severity: SeverityFail,
+			Message:  err.Error(),
+			Hint:     hint,
+		}}
+	}
+
+	sessions := 0
+	for _, p := range st.Projects() {
+		sessions += len(p.Sessions)
+	}
+	summary := fmt.Sprintf("%d project(s), %d session(s)", len(st.Projects()), sessions)
+	if err := st.Recovered(); err != nil {
+		return []Finding{{
+			Check:    "state",
+			Severity: SeverityWarn,
+			Message:  fmt.Sprintf("%v; using backup with %s", err, summary),
+			Hint:     "codely replaces the state file with the backup on its next save and keeps the unreadable file with a .corrupt suffix",
+		}}
+	}
+	return []Finding{{
+		Check:    "state",
+		Severity: SeverityOK,
+		Message:  summary,
+	}}
+}
+
+// parseVersion extracts major.minor from a tmux version string such as
+// "3.3a", "3.4" or "next-3.5". Unparseable versions return 0.
+func parseVersion(version string) float64 {
+	version = strings.TrimPrefix(version, "next-")
+	end := 0
+	for end < len(version) && (version[end] == '.' || (version[end] >= '0' && version[end] <= '9')) {
+		end++
+	}
+	v, err := strconv.ParseFloat(version[:end], 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,144 @@
+package doctor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charliek/codely/internal/config"
+	"github.com/charliek/codely/internal/shed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEnv(t *testing.T, onPath ...string) Env {
+	t.Helper()
+	dir := t.TempDir()
+	found := make(map[string]bool)
+	for _, name := range onPath {
+		found[name] = true
+	}
+	return Env{
+		LookPath: func(file string) (string, error) {
+			if found[file] {
+				return "/usr/bin/" + file, nil
+			}
+			return "", errors.New("not found")
+		},
+		TmuxVersion:    func() (string, error) { return "3.4", nil },
+		JoinPaneFormat: func() bool { return true },
+		InTmux:         func() bool { return true },
+		Shed:           shed.NewMockClient(),
+		ConfigSources:  config.Sources{Files: []string{filepath.Join(dir, "config.yaml")}},
+		StatePath:      filepath.Join(dir, "session.json"),
+	}
+}
+
+func findingsFor(findings []Finding, check string) []Finding {
+	var out []Finding
+	for _, f := range findings {
+		if f.Check == check {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func TestRunMissingTmux(t *testing.T) {
+	env := testEnv(t)
+	findings := Run(env)
+
+	tmuxFindings := findingsFor(findings, "tmux")
+	require.Len(t, tmuxFindings, 1)
+	assert.Equal(t, SeverityFail, tmuxFindings[0].Severity)
+	assert.NotEmpty(t, tmuxFindings[0].Hint)
+	assert.Empty(t, findingsFor(findings, "tmux join-pane"))
+	assert.Equal(t, 1, Failed(findings))
+}
+
+func TestRunHealthyEnvironment(t *testing.T) {
+	env := testEnv(t, "tmux", "shed", "claude", "codex", "opencode", "bash", "lazygit")
+	mock := env.Shed.(*shed.MockClient)
+	mock.ListServersResult = []shed.Server{{Name: "local"}}
+
+	findings := Run(env)
+	for _, f := range findings {
+		assert.Equal(t, SeverityOK, f.Severity, "%s: %s", f.Check, f.Message)
+	}
+}
+
+func TestCheckTmuxOldVersionAndNoJoinPaneFormat(t *testing.T) {
+	env := testEnv(t, "tmux")
+	env.TmuxVersion = func() (string, error) { return "2.9a", nil }
+	env.JoinPaneFormat = func() bool { return false }
+
+	findings := checkTmux(env)
+	assert.Equal(t, SeverityWarn, findingsFor(findings, "tmux")[0].Severity)
+	assert.Equal(t, SeverityWarn, findingsFor(findings, "tmux join-pane")[0].Severity)
+}
+
+func TestCheckShed(t *testing.T) {
+	env := testEnv(t)
+	findings := checkShed(env)
+	require.Len(t, findings, 1)
+	assert.Equal(t, SeverityWarn, findings[0].Severity)
+
+	env = testEnv(t, "shed")
+	env.Shed.(*shed.MockClient).ListServersErr = errors.New("connection refused")
+	findings = checkShed(env)
+	require.Len(t, findings, 1)
+	assert.Equal(t, SeverityFail, findings[0].Severity)
+	assert.Contains(t, findings[0].Message, "connection refused")
+}
+
+func TestCheckConfigCommandsAndParseErrors(t *testing.T) {
+	env := testEnv(t, "claude")
+	require.NoError(t, os.WriteFile(env.ConfigSources.Files[0], []byte(`
+default_command: missing
+commands:
+  claude:
+    exec: claude
+  aider:
+    exec: aider
+`), 0o644))
+
+	findings := checkConfig(env)
+	assert.Equal(t, SeverityOK, findings[0].Severity)
+	assert.Equal(t, SeverityWarn, findings[1].Severity)
+	assert.Contains(t, findings[1].Message, "default_command")
+	assert.Equal(t, SeverityWarn, findingsFor(findings, "command aider")[0].Severity)
+	assert.Equal(t, SeverityOK, findingsFor(findings, "command claude")[0].Severity)
+
+	require.NoError(t, os.WriteFile(env.ConfigSources.Files[0], []byte("commands: [\n"), 0o644))
+	findings = checkConfig(env)
+	require.Len(t, findings, 1)
+	assert.Equal(t, SeverityFail, findings[0].Severity)
+}
+
+func TestCheckStateCorrupt(t *testing.T) {
+	env := testEnv(t)
+	require.NoError(t, os.WriteFile(env.StatePath, []byte("{not json"), 0o644))
+
+	findings := checkState(env)
+	require.Len(t, findings, 1)
+	assert.Equal(t, SeverityFail, findings[0].Severity)
+}
+
+func TestCheckStateRecovered(t *testing.T) {
+	env := testEnv(t)
+	require.NoError(t, os.WriteFile(env.StatePath+".bak", []byte(`{"projects": []}`), 0o600))
+	require.NoError(t, os.WriteFile(env.StatePath, []byte("{not json"), 0o600))
+
+	findings := checkState(env)
+	require.Len(t, findings, 1)
+	assert.Equal(t, SeverityWarn, findings[0].Severity)
+	assert.Contains(t, findings[0].Message, "using backup")
+}
+
+func TestParseVersion(t *testing.T) {
+	assert.Equal(t, 3.3, parseVersion("3.3a"))
+	assert.Equal(t, 3.4, parseVersion("3.4"))
+	assert.Equal(t, 3.5, parseVersion("next-3.5"))
+	assert.Equal(t, 0.0, parseVersion("master"))
+}
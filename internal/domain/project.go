@@ -49,11 +49,17 @@ type Session struct {
 	Command   Command `json:"command"`    // What's running
 
 	// Runtime state (not persisted)
-	PaneID    int       `json:"-"` // tmux pane ID (can change after break/join)
-	Status    Status    `json:"-"` // Current status
-	StartedAt time.Time `json:"-"`
-	IsVisible bool      `json:"-"` // Currently visible in main window?
-	ExitCode  *int      `json:"-"` // Exit code if process exited
+	PaneID    int    `json:"-"` // tmux pane ID (can change after break/join)
+	Status    Status `json:"-"` // Current status
+	IsVisible bool   `json:"-"` // Currently visible in main window?
+	ExitCode  *int   `json:"-"` // Exit code if process exited
+
+	// Persisted so the history archive knows how long a session ran
+	StartedAt time.Time `json:"started_at,omitzero"`
+
+	// Last working directory seen in the pane of a local session, so a
+	// session lost with the tmux server restarts where it was
+	WorkDir string `json:"work_dir,omitempty"`
 }
 
 // Command defines what runs in a session
@@ -62,7 +68,24 @@ type Command struct {
 	DisplayName string            `json:"display_name"` // Human-readable name
 	Exec        string            `json:"exec"`         // Binary to run
 	Args        []string          `json:"args"`         // Arguments
-	Env         map[string]string `json:"env"`          // Environment variables
+	Env         map[string]string `json:"-"`            // Environment variables (not persisted: may hold secrets)
+
+	// EnvFrom lists environment variables resolved at launch; only the
+	// sources are persisted, never the values
+	EnvFrom map[string]EnvSource `json:"env_from,omitempty"`
+	EnvFile string               `json:"env_file,omitempty"` // .env file loaded at launch
+
+	// StatusDetection is the status_detection mode in effect at launch,
+	// including per-project overrides ("" uses the global config)
+	StatusDetection string `json:"status_detection,omitempty"`
+}
+
+// EnvSource says where an environment value is read from at launch. Exactly
+// one field is set.
+type EnvSource struct {
+	File    string `json:"file,omitempty"`    // Contents of a file
+	Env     string `json:"env,omitempty"`     // Another environment variable
+	Command string `json:"command,omitempty"` // Output of a shell command
 }
 
 // Name returns DisplayName if set, otherwise ID.
@@ -0,0 +1,147 @@
+// Package frecency tracks how often and how recently directories are opened
+// as projects, so the folder picker can rank them.
+package frecency
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/charliek/codely/internal/pathutil"
+)
+
+// maxEntries bounds the store; the lowest scoring entries are dropped on save
+const maxEntries = 500
+
+// Entry records the visits to one directory
+type Entry struct {
+	Count    int       `json:"count"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// Store holds visit records keyed by directory
+type Store struct {
+	path    string
+	entries map[string]Entry
+	now     func() time.Time
+}
+
+// New creates a store persisted at path ("" keeps it in memory only)
+func New(path string) *Store {
+	return &Store{
+		path:    path,
+		entries: make(map[string]Entry),
+		now:     time.Now,
+	}
+}
+
+// Load reads the store from disk. A missing file leaves it empty.
+func (s *Store) Load() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(pathutil.ExpandPath(s.path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("reading frecency file: %w", err)
+	}
+
+	entries := make(map[string]Entry)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("parsing frecency file: %w", err)
+	}
+	s.entries = entries
+	return nil
+}
+
+// Save writes the store to disk
+func (s *Store) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	s.prune()
+
+	path := pathutil.ExpandPath(s.path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating frecency directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding frecency file: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing frecency file: %w", err)
+	}
+	return nil
+}
+
+// Visit records that dir was opened now
+func (s *Store) Visit(dir string) {
+	e := s.entries[dir]
+	e.Count++
+	e.LastUsed = s.now()
+	s.entries[dir] = e
+}
+
+// Score ranks dir by visit count weighted by how recently it was last
+// opened. Unknown directories score 0.
+func (s *Store) Score(dir string) float64 {
+	e, ok := s.entries[dir]
+	if !ok {
+		return 0
+	}
+
+	age := s.now().Sub(e.LastUsed)
+	weight := 0.25
+	switch {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 0.5
+	}
+	return float64(e.Count) * weight
+}
+
+// Top returns up to n directories with the highest scores, best first
+func (s *Store) Top(n int) []string {
+	dirs := s.ranked()
+	if len(dirs) > n {
+		dirs = dirs[:n]
+	}
+	return dirs
+}
+
+// ranked returns every directory ordered by score, then most recent use
+func (s *Store) ranked() []string {
+	dirs := slices.Collect(maps.Keys(s.entries))
+	slices.SortFunc(dirs, func(a, b string) int {
+		if c := cmp.Compare(s.Score(b), s.Score(a)); c != 0 {
+			return c
+		}
+		if c := s.entries[b].LastUsed.Compare(s.entries[a].LastUsed); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	return dirs
+}
+
+// prune drops the lowest ranked entries beyond maxEntries
+func (s *Store) prune() {
+	if len(s.entries) <= maxEntries {
+		return
+	}
+	for _, dir := range s.ranked()[maxEntries:] {
+		delete(s.entries, dir)
+	}
+}
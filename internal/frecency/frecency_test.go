@@ -0,0 +1,58 @@
+package frecency
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(path string, now *time.Time) *Store {
+	s := New(path)
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestScoreWeightsRecency(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	s := newTestStore("", &now)
+
+	// Opened often, but last week
+	old := now.Add(-3 * 24 * time.Hour)
+	s.now = func() time.Time { return old }
+	for range 4 {
+		s.Visit("/src/old")
+	}
+	// Opened once, just now
+	s.now = func() time.Time { return now }
+	s.Visit("/src/new")
+
+	assert.Equal(t, 2.0, s.Score("/src/old"))
+	assert.Equal(t, 4.0, s.Score("/src/new"))
+	assert.Equal(t, 0.0, s.Score("/src/unknown"))
+	assert.Equal(t, []string{"/src/new", "/src/old"}, s.Top(5))
+	assert.Equal(t, []string{"/src/new"}, s.Top(1))
+
+	// Frequency wins once both are recent
+	s.Visit("/src/old")
+	assert.Equal(t, []string{"/src/old", "/src/new"}, s.Top(5))
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "frecency.json")
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	s := newTestStore(path, &now)
+	require.NoError(t, s.Load()) // Missing file is empty
+	s.Visit("/src/a")
+	s.Visit("/src/a")
+	s.Visit("/src/b")
+	require.NoError(t, s.Save())
+
+	loaded := newTestStore(path, &now)
+	require.NoError(t, loaded.Load())
+	assert.Equal(t, 8.0, loaded.Score("/src/a"))
+	assert.Equal(t, []string{"/src/a", "/src/b"}, loaded.Top(5))
+}
@@ -0,0 +1,184 @@
+// Package git provides a client for cloning repositories with the git CLI.
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DefaultHost is prefixed to "org/repo" shorthand to form a clone URL
+const DefaultHost = "https://github.com/"
+
+// shorthand matches the "org/repo" form of a repository
+var shorthand = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// Client defines the interface for git operations
+type Client interface {
+	// Available returns true if the git CLI is installed
+	Available() bool
+
+	// CloneStreaming clones url into dir, streaming progress as it runs.
+	// Canceling ctx stops the clone and removes dir.
+	CloneStreaming(ctx context.Context, url, dir string) (cmdLine string, outputCh <-chan string, doneCh <-chan error)
+}
+
+// DefaultClient implements the Client interface using the git CLI
+type DefaultClient struct{}
+
+// NewClient creates a new default git client
+func NewClient() *DefaultClient {
+	return &DefaultClient{}
+}
+
+// Available checks if the git CLI is installed and accessible
+func (c *DefaultClient) Available() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// CloneStreaming clones url into dir, streaming git's progress output as it
+// runs. It returns the formatted command line, a channel of progress lines,
+// and a done channel that delivers the final error (nil on success).
+// git never prompts for credentials, so a private repository fails instead
+// of waiting for input codely cannot provide. If ctx is canceled the clone
+// is killed, dir is removed unless it existed before, and the error wraps
+// ctx.Err().
+func (c *DefaultClient) CloneStreaming(ctx context.Context, url, dir string) (string, <-chan string, <-chan error) {
+	args := []string{"clone", "--progress", "--", url, dir}
+	cmdLine := "git " + strings.Join(args, " ")
+
+	outputCh := make(chan string, 64)
+	doneCh := make(chan error, 1)
+
+	_, statErr := os.Stat(dir)
+	created := os.IsNotExist(statErr)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = cloneEnv(configValue("core.sshCommand"))
+	stderrPipe, pipeErr := cmd.StderrPipe()
+	if pipeErr != nil {
+		close(outputCh)
+		doneCh <- fmt.Errorf("git clone: stderr pipe: %w", pipeErr)
+		close(doneCh)
+		return cmdLine, outputCh, doneCh
+	}
+
+	if err := cmd.Start(); err != nil {
+		close(outputCh)
+		doneCh <- fmt.Errorf("git clone: start: %w", err)
+		close(doneCh)
+		return cmdLine, outputCh, doneCh
+	}
+
+	go func() {
+		var last string
+		scanner := bufio.NewScanner(stderrPipe)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		scanner.Split(scanProgressLines)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			last = line
+			outputCh <- line
+		}
+		if scanErr := scanner.Err(); scanErr != nil {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+			close(outputCh)
+			doneCh <- fmt.Errorf("git clone: reading output: %w", scanErr)
+			close(doneCh)
+			return
+		}
+		close(outputCh)
+
+		err := cmd.Wait()
+		if ctx.Err() != nil {
+			if created {
+				_ = os.RemoveAll(dir)
+			}
+			doneCh <- fmt.Errorf("git clone: %w", ctx.Err())
+			close(doneCh)
+			return
+		}
+		if err != nil {
+			if last != "" {
+				doneCh <- fmt.Errorf("git clone failed: %s: %w", last, err)
+			} else {
+				doneCh <- fmt.Errorf("git clone failed: %w", err)
+			}
+			close(doneCh)
+			return
+		}
+
+		doneCh <- nil
+		close(doneCh)
+	}()
+
+	return cmdLine, outputCh, doneCh
+}
+
+// cloneEnv returns codely's environment with git's terminal prompts and
+// ssh's password and host key prompts turned off. sshCommand is the
+// core.sshCommand git config; when it, GIT_SSH_COMMAND or GIT_SSH is set,
+// the user's ssh command is kept rather than replaced.
+func cloneEnv(sshCommand string) []string {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if sshCommand == "" && os.Getenv("GIT_SSH_COMMAND") == "" && os.Getenv("GIT_SSH") == "" {
+		env = append(env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+	return env
+}
+
+// configValue returns the value of a git config key, or "" if it is unset
+func configValue(key string) string {
+	out, err := exec.Command("git", "config", "--get", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// scanProgressLines splits output on newlines and on the carriage returns
+// git uses to redraw progress counters
+func scanProgressLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// ResolveURL turns "org/repo" shorthand into a clone URL on DefaultHost.
+// URLs and scp-style addresses such as git@host:org/repo are returned as is.
+func ResolveURL(repo string) (string, error) {
+	repo = strings.TrimSpace(repo)
+	switch {
+	case repo == "":
+		return "", fmt.Errorf("repository is empty")
+	case strings.Contains(repo, "://"), strings.Contains(repo, "@") && strings.Contains(repo, ":"):
+		return repo, nil
+	case shorthand.MatchString(repo):
+		return DefaultHost + strings.TrimSuffix(repo, ".git") + ".git", nil
+	}
+	return "", fmt.Errorf("unrecognized repository %q (expected a URL or org/repo)", repo)
+}
+
+// RepoName returns the directory name git would clone url into
+func RepoName(url string) string {
+	url = strings.TrimRight(url, "/")
+	if i := strings.LastIndexAny(url, "/:"); i >= 0 {
+		url = url[i+1:]
+	}
+	return strings.TrimSuffix(path.Base(url), ".git")
+}
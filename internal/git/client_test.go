@@ -0,0 +1,145 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveURL(t *testing.T) {
+	tests := []struct {
+		repo string
+		want string
+	}{
+		{"charliek/codely", "https://github.com/charliek/codely.git"},
+		{"charliek/codely.git", "https://github.com/charliek/codely.git"},
+		{"https://gitlab.com/org/repo.git", "https://gitlab.com/org/repo.git"},
+		{"git@github.com:org/repo.git", "git@github.com:org/repo.git"},
+		{"ssh://git@host/org/repo", "ssh://git@host/org/repo"},
+	}
+	for _, tt := range tests {
+		got, err := ResolveURL(tt.repo)
+		require.NoError(t, err, tt.repo)
+		assert.Equal(t, tt.want, got)
+	}
+
+	for _, bad := range []string{"", "codely", "a/b/c", "org/re po"} {
+		_, err := ResolveURL(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestRepoName(t *testing.T) {
+	assert.Equal(t, "codely", RepoName("https://github.com/charliek/codely.git"))
+	assert.Equal(t, "repo", RepoName("git@github.com:org/repo.git"))
+	assert.Equal(t, "repo", RepoName("git@host:repo"))
+	assert.Equal(t, "repo", RepoName("https://host/org/repo/"))
+}
+
+func TestScanProgressLines(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("Cloning into 'x'...\nReceiving objects:  50%\rReceiving objects: 100%, done.\n"))
+	scanner.Split(scanProgressLines)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Equal(t, []string{"Cloning into 'x'...", "Receiving objects:  50%", "Receiving objects: 100%, done."}, lines)
+}
+
+func TestCloneStreaming(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	src := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = src
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	run("init", "-q")
+	require.NoError(t, os.WriteFile(filepath.Join(src, "README"), []byte("hi\n"), 0o644))
+	run("add", "README")
+	run("commit", "-q", "-m", "init")
+
+	dest := filepath.Join(t.TempDir(), "clone")
+	c := NewClient()
+	cmdLine, outputCh, doneCh := c.CloneStreaming(context.Background(), src, dest)
+	assert.Equal(t, "git clone --progress -- "+src+" "+dest, cmdLine)
+	for range outputCh {
+	}
+	require.NoError(t, <-doneCh)
+	assert.FileExists(t, filepath.Join(dest, "README"))
+
+	// Cloning into a non-empty directory fails with git's message
+	_, outputCh, doneCh = c.CloneStreaming(context.Background(), src, dest)
+	for range outputCh {
+	}
+	err := <-doneCh
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+
+	// A canceled clone removes the directory it was cloning into
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	canceled := filepath.Join(t.TempDir(), "canceled")
+	_, outputCh, doneCh = c.CloneStreaming(ctx, src, canceled)
+	for range outputCh {
+	}
+	assert.ErrorIs(t, <-doneCh, context.Canceled)
+	assert.NoDirExists(t, canceled)
+	assert.DirExists(t, dest)
+}
+
+func TestCloneEnv(t *testing.T) {
+	t.Setenv("GIT_SSH_COMMAND", "")
+	t.Setenv("GIT_SSH", "")
+	env := cloneEnv("")
+	assert.Contains(t, env, "GIT_TERMINAL_PROMPT=0")
+	assert.Contains(t, env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+
+	// A configured ssh command is kept, from git config or the environment
+	env = cloneEnv("ssh -i ~/.ssh/work")
+	assert.Contains(t, env, "GIT_TERMINAL_PROMPT=0")
+	assert.NotContains(t, env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	t.Setenv("GIT_SSH_COMMAND", "ssh -i key")
+	assert.NotContains(t, cloneEnv(""), "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+}
+
+func TestConfigValue(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	config := filepath.Join(t.TempDir(), "gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", config)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	assert.Empty(t, configValue("core.sshCommand"))
+
+	require.NoError(t, os.WriteFile(config, []byte("[core]\n\tsshCommand = ssh -i ~/.ssh/work\n"), 0o600))
+	assert.Equal(t, "ssh -i ~/.ssh/work", configValue("core.sshCommand"))
+}
+
+func TestMockClientCloneStreaming(t *testing.T) {
+	m := NewMockClient()
+	m.CloneOutput = []string{"Cloning into 'repo'..."}
+
+	_, outputCh, doneCh := m.CloneStreaming(context.Background(), "https://host/org/repo.git", "/src/repo")
+	var lines []string
+	for line := range outputCh {
+		lines = append(lines, line)
+	}
+	assert.Equal(t, m.CloneOutput, lines)
+	assert.NoError(t, <-doneCh)
+	require.Len(t, m.Calls, 1)
+	assert.Equal(t, []interface{}{"https://host/org/repo.git", "/src/repo"}, m.Calls[0].Args)
+}
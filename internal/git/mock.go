@@ -0,0 +1,50 @@
+package git
+
+import "context"
+
+// MockClient is a mock implementation of Client for testing
+type MockClient struct {
+	AvailableResult bool
+	CloneOutput     []string
+	CloneErr        error
+
+	// Track calls for verification
+	Calls []MockCall
+}
+
+// MockCall records a method call for testing verification
+type MockCall struct {
+	Method string
+	Args   []interface{}
+}
+
+// NewMockClient creates a new mock client with sensible defaults
+func NewMockClient() *MockClient {
+	return &MockClient{AvailableResult: true}
+}
+
+func (m *MockClient) recordCall(method string, args ...interface{}) {
+	m.Calls = append(m.Calls, MockCall{Method: method, Args: args})
+}
+
+func (m *MockClient) Available() bool {
+	m.recordCall("Available")
+	return m.AvailableResult
+}
+
+func (m *MockClient) CloneStreaming(ctx context.Context, url, dir string) (string, <-chan string, <-chan error) {
+	m.recordCall("CloneStreaming", url, dir)
+
+	cmdLine := "git clone --progress -- " + url + " " + dir
+	outputCh := make(chan string, len(m.CloneOutput))
+	doneCh := make(chan error, 1)
+
+	for _, line := range m.CloneOutput {
+		outputCh <- line
+	}
+	close(outputCh)
+	doneCh <- m.CloneErr
+	close(doneCh)
+
+	return cmdLine, outputCh, doneCh
+}
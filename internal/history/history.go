@@ -0,0 +1,240 @@
+// Package history archives closed and exited sessions to a JSON Lines file
+// so they can be browsed, filtered and relaunched later.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/pathutil"
+)
+
+// maxFileSize bounds the archive; when an append grows it past this size,
+// the oldest half of the entries is dropped
+const maxFileSize = 4 << 20
+
+// Reason says how a session ended
+type Reason string
+
+const (
+	// ReasonClosed means the session was closed from codely
+	ReasonClosed Reason = "closed"
+	// ReasonExited means the session's process exited
+	ReasonExited Reason = "exited"
+	// ReasonLost means the pane was gone when codely started
+	ReasonLost Reason = "lost"
+)
+
+// Entry is one archived session
+type Entry struct {
+	SessionID   string             `json:"session_id"`
+	ProjectID   string             `json:"project_id"`
+	ProjectName string             `json:"project_name"`
+	ProjectType domain.ProjectType `json:"project_type"`
+	Directory   string             `json:"directory,omitempty"`
+	ShedName    string             `json:"shed_name,omitempty"`
+	ShedServer  string             `json:"shed_server,omitempty"`
+	Command     domain.Command     `json:"command"`
+	StartedAt   time.Time          `json:"started_at,omitzero"` // Zero if unknown
+	EndedAt     time.Time          `json:"ended_at"`
+	ExitCode    *int               `json:"exit_code"`
+	Reason      Reason             `json:"reason"`
+	Output      string             `json:"output,omitempty"` // Last lines of the pane
+}
+
+// NewEntry archives sess as it ends at endedAt. The reason is exited if the
+// session has an exit code and closed otherwise.
+func NewEntry(proj *domain.Project, sess *domain.Session, endedAt time.Time) Entry {
+	e := Entry{
+		SessionID:   sess.ID,
+		ProjectID:   proj.ID,
+		ProjectName: proj.Name,
+		ProjectType: proj.Type,
+		Directory:   proj.Directory,
+		ShedName:    proj.ShedName,
+		ShedServer:  proj.ShedServer,
+		Command:     sess.Command,
+		StartedAt:   sess.StartedAt,
+		EndedAt:     endedAt,
+		ExitCode:    sess.ExitCode,
+		Reason:      ReasonClosed,
+	}
+	if sess.ExitCode != nil {
+		e.Reason = ReasonExited
+	}
+	return e
+}
+
+// Duration returns how long the session ran, or 0 if its start is unknown
+func (e Entry) Duration() time.Duration {
+	if e.StartedAt.IsZero() || e.EndedAt.Before(e.StartedAt) {
+		return 0
+	}
+	return e.EndedAt.Sub(e.StartedAt)
+}
+
+// FormatDuration formats d compactly, e.g. "45s", "12m" or "3h05m".
+// Zero durations are shown as "-".
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "-"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// Result describes how the session ended, e.g. "exit 1" or "closed"
+func (e Entry) Result() string {
+	if e.ExitCode != nil {
+		return fmt.Sprintf("exit %d", *e.ExitCode)
+	}
+	return string(e.Reason)
+}
+
+// Append adds entries to the archive at path
+func Append(path string, entries ...Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	path = pathutil.ExpandPath(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating history directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("encoding history entry: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("opening history file: %w", err)
+	}
+	_, err = f.Write(buf.Bytes())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing history file: %w", err)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Size() > maxFileSize {
+		return trim(path)
+	}
+	return nil
+}
+
+// trim rewrites the archive keeping the newest half of its entries
+func trim(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading history file: %w", err)
+	}
+	lines := bytes.SplitAfter(bytes.TrimRight(data, "\n"), []byte("\n"))
+	kept := bytes.Join(lines[len(lines)/2:], nil)
+	if !bytes.HasSuffix(kept, []byte("\n")) {
+		kept = append(kept, '\n')
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, kept, 0600); err != nil {
+		return fmt.Errorf("trimming history file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("trimming history file: %w", err)
+	}
+	return nil
+}
+
+// Load reads the archive at path, newest first. A missing file is empty and
+// malformed lines are skipped.
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(pathutil.ExpandPath(path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening history file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading history file: %w", err)
+	}
+
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// Filter selects archive entries. Empty fields match everything.
+type Filter struct {
+	Project string    // Project name, ID or directory, case-insensitive substring
+	Command string    // Command ID
+	Since   time.Time // Ended at or after
+	Text    string    // Case-insensitive substring of the project name, command name or ID, or output
+}
+
+// Match reports whether e passes the filter
+func (f Filter) Match(e Entry) bool {
+	if f.Project != "" && !containsFold(f.Project, e.ProjectName, e.ProjectID, e.Directory, e.ShedName) {
+		return false
+	}
+	if f.Command != "" && e.Command.ID != f.Command {
+		return false
+	}
+	if !f.Since.IsZero() && e.EndedAt.Before(f.Since) {
+		return false
+	}
+	if f.Text != "" && !containsFold(f.Text, e.ProjectName, e.Command.Name(), e.Command.ID, e.Output) {
+		return false
+	}
+	return true
+}
+
+// Apply returns the entries that pass the filter, keeping their order
+func (f Filter) Apply(entries []Entry) []Entry {
+	var matched []Entry
+	for _, e := range entries {
+		if f.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// containsFold reports whether any of fields contains substr, ignoring case
+func containsFold(substr string, fields ...string) bool {
+	substr = strings.ToLower(substr)
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), substr) {
+			return true
+		}
+	}
+	return false
+}
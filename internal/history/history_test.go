@@ -0,0 +1,120 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEntry(project, cmdID string, ended time.Time) Entry {
+	return Entry{
+		SessionID:   project + "-" + cmdID,
+		ProjectID:   project + "-id",
+		ProjectName: project,
+		ProjectType: domain.ProjectTypeLocal,
+		Directory:   "/src/" + project,
+		Command:     domain.Command{ID: cmdID, Exec: cmdID},
+		EndedAt:     ended,
+		Reason:      ReasonClosed,
+	}
+}
+
+func TestNewEntry(t *testing.T) {
+	started := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	ended := started.Add(90 * time.Minute)
+	code := 2
+	proj := &domain.Project{ID: "p1", Name: "api", Type: domain.ProjectTypeLocal, Directory: "/src/api"}
+	sess := &domain.Session{
+		ID:        "s1",
+		Command:   domain.Command{ID: "claude", Exec: "claude", Env: map[string]string{"TOKEN": "secret"}},
+		StartedAt: started,
+		ExitCode:  &code,
+	}
+
+	e := NewEntry(proj, sess, ended)
+	assert.Equal(t, ReasonExited, e.Reason)
+	assert.Equal(t, "exit 2", e.Result())
+	assert.Equal(t, 90*time.Minute, e.Duration())
+	assert.Equal(t, "/src/api", e.Directory)
+
+	sess.ExitCode = nil
+	sess.StartedAt = time.Time{}
+	e = NewEntry(proj, sess, ended)
+	assert.Equal(t, "closed", e.Result())
+	assert.Zero(t, e.Duration())
+}
+
+func TestAppendLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "history.jsonl")
+
+	// A missing archive is empty
+	entries, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	first := testEntry("api", "claude", now.Add(-time.Hour))
+	first.Command.Env = map[string]string{"TOKEN": "secret"}
+	require.NoError(t, Append(path, first))
+	require.NoError(t, Append(path, testEntry("web", "bash", now)))
+
+	// Malformed lines are skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("{truncated\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	entries, err = Load(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "web", entries[0].ProjectName, "newest first")
+	assert.Equal(t, "api", entries[1].ProjectName)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret", "env values are not archived")
+}
+
+func TestAppendTrims(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	big := testEntry("api", "claude", time.Now())
+	big.Output = strings.Repeat("x", maxFileSize/8)
+
+	for range 9 {
+		require.NoError(t, Append(path, big))
+	}
+	// The eighth append passes the limit and keeps the newest four
+	entries, err := Load(path)
+	require.NoError(t, err)
+	assert.Len(t, entries, 5)
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	api := testEntry("api", "claude", now.Add(-48*time.Hour))
+	api.Output = "All tests passed"
+	web := testEntry("web", "bash", now)
+	entries := []Entry{web, api}
+
+	assert.Equal(t, []Entry{api}, Filter{Project: "API"}.Apply(entries))
+	assert.Equal(t, []Entry{api}, Filter{Project: "/src/api"}.Apply(entries))
+	assert.Equal(t, []Entry{web}, Filter{Command: "bash"}.Apply(entries))
+	assert.Equal(t, []Entry{web}, Filter{Since: now.Add(-24 * time.Hour)}.Apply(entries))
+	assert.Equal(t, []Entry{api}, Filter{Text: "tests passed"}.Apply(entries))
+	assert.Equal(t, entries, Filter{}.Apply(entries))
+	assert.Empty(t, Filter{Project: "api", Command: "bash"}.Apply(entries))
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "-", FormatDuration(0))
+	assert.Equal(t, "45s", FormatDuration(45*time.Second))
+	assert.Equal(t, "12m", FormatDuration(12*time.Minute+30*time.Second))
+	assert.Equal(t, "3h05m", FormatDuration(3*time.Hour+5*time.Minute))
+}
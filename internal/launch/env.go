@@ -0,0 +1,122 @@
+package launch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/pathutil"
+)
+
+// envCommandTimeout bounds how long an env_from helper command may run
+const envCommandTimeout = 10 * time.Second
+
+// redacted replaces secret values in debug output
+const redacted = "<redacted>"
+
+// resolveEnvSource reads one env_from value. Template placeholders in the
+// source are expanded first, shell-quoted in commands. Errors never include
+// the value.
+func resolveEnvSource(src domain.EnvSource, vars Vars) (string, error) {
+	switch {
+	case src.File != "":
+		path, err := Expand(src.File, vars)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(pathutil.ExpandPath(path))
+		if err != nil {
+			return "", fmt.Errorf("reading file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+
+	case src.Env != "":
+		value, ok := os.LookupEnv(src.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", src.Env)
+		}
+		return value, nil
+
+	case src.Command != "":
+		// Quoted so a project or session name cannot inject shell syntax
+		line, err := Expand(src.Command, vars.shellQuoted())
+		if err != nil {
+			return "", err
+		}
+		return runEnvCommand(line)
+	}
+
+	return "", errors.New("no source set (expected file, env or command)")
+}
+
+// runEnvCommand runs a helper command with sh and returns its output
+// without trailing newlines
+func runEnvCommand(line string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), envCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", line)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("command timed out after %s", envCommandTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("command failed: %w", err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// shellQuoted returns vars with every value quoted for sh
+func (v Vars) shellQuoted() Vars {
+	q := shellQuote
+	return Vars{
+		Project: ProjectVars{ID: q(v.Project.ID), Name: q(v.Project.Name), Directory: q(v.Project.Directory)},
+		Session: SessionVars{ID: q(v.Session.ID), Name: q(v.Session.Name), Command: q(v.Session.Command)},
+		Shed:    ShedVars{Name: q(v.Shed.Name), Server: q(v.Shed.Server)},
+	}
+}
+
+// shellQuote returns s as a single sh word. Strings of only safe characters
+// are returned as is; others are single-quoted.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:@=+,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// EnvList returns the environment as sorted KEY=value pairs
+func (c Command) EnvList() []string {
+	env := make([]string, 0, len(c.Env))
+	for key, value := range c.Env {
+		env = append(env, key+"="+value)
+	}
+	slices.Sort(env)
+	return env
+}
+
+// Redacted returns the environment as sorted KEY=value pairs for logging,
+// with values read from the env file or env_from replaced
+func (c Command) Redacted() []string {
+	env := make([]string, 0, len(c.Env))
+	for key, value := range c.Env {
+		if slices.Contains(c.Secrets, key) {
+			value = redacted
+		}
+		env = append(env, key+"="+value)
+	}
+	slices.Sort(env)
+	return env
+}
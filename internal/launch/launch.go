@@ -0,0 +1,147 @@
+// Package launch resolves what a session runs: it expands template
+// placeholders in command args and env for a project and session, and reads
+// env_from values.
+package launch
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/charliek/codely/internal/domain"
+)
+
+// Vars are the values available to command templates, e.g.
+// {{.Project.Name}} or {{.Shed.Server}}
+type Vars struct {
+	Project ProjectVars
+	Session SessionVars
+	Shed    ShedVars
+}
+
+// ProjectVars describes the project a session is launched in
+type ProjectVars struct {
+	ID        string
+	Name      string
+	Directory string // Empty for shed projects
+}
+
+// SessionVars describes the session being launched
+type SessionVars struct {
+	ID      string
+	Name    string // Display name of the session
+	Command string // Command ID
+}
+
+// ShedVars describes the shed of a shed project; empty for local projects
+type ShedVars struct {
+	Name   string
+	Server string
+}
+
+// NewVars returns the template values for a session in a project
+func NewVars(proj *domain.Project, sess *domain.Session) Vars {
+	vars := Vars{
+		Project: ProjectVars{ID: proj.ID, Name: proj.Name},
+		Session: SessionVars{ID: sess.ID, Name: sess.Command.Name(), Command: sess.Command.ID},
+	}
+	if proj.Type == domain.ProjectTypeShed {
+		vars.Shed = ShedVars{Name: proj.ShedName, Server: proj.ShedServer}
+	} else {
+		vars.Project.Directory = proj.Directory
+	}
+	return vars
+}
+
+// Command is a command with its args and env expanded, its env file loaded
+// and env_from resolved
+type Command struct {
+	Exec    string
+	Args    []string
+	Env     map[string]string
+	Secrets []string // Env keys read from the env file or env_from, sorted
+}
+
+// Resolve expands the args and env of cmd, loads its env file and resolves
+// its env_from sources. Environment is layered env file < env < env_from.
+// The exec binary is used as is.
+func Resolve(cmd domain.Command, vars Vars) (Command, error) {
+	resolved := Command{Exec: cmd.Exec}
+
+	if cmd.Args != nil {
+		resolved.Args = make([]string, len(cmd.Args))
+		for i, arg := range cmd.Args {
+			value, err := Expand(arg, vars)
+			if err != nil {
+				return Command{}, fmt.Errorf("expanding args[%d]: %w", i, err)
+			}
+			resolved.Args[i] = value
+		}
+	}
+
+	env := make(map[string]string)
+	secret := make(map[string]bool)
+
+	if cmd.EnvFile != "" {
+		fileEnv, err := loadEnvFile(cmd.EnvFile, vars)
+		if err != nil {
+			return Command{}, fmt.Errorf("env_file: %w", err)
+		}
+		for key, value := range fileEnv {
+			env[key] = value
+			secret[key] = true
+		}
+	}
+
+	for key, raw := range cmd.Env {
+		value, err := Expand(raw, vars)
+		if err != nil {
+			return Command{}, fmt.Errorf("expanding env %s: %w", key, err)
+		}
+		env[key] = value
+		delete(secret, key)
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(cmd.EnvFrom)) {
+		value, err := resolveEnvSource(cmd.EnvFrom[key], vars)
+		if err != nil {
+			return Command{}, fmt.Errorf("env_from %s: %w", key, err)
+		}
+		env[key] = value
+		secret[key] = true
+	}
+
+	if len(env) > 0 {
+		resolved.Env = env
+	}
+	if len(secret) > 0 {
+		resolved.Secrets = slices.Sorted(maps.Keys(secret))
+	}
+	return resolved, nil
+}
+
+// Expand executes s as a template with vars. Strings without "{{" are
+// returned unchanged.
+func Expand(s string, vars Vars) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	tmpl, err := template.New("").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Check reports whether s is a valid template, including that every
+// placeholder names a known variable
+func Check(s string) error {
+	_, err := Expand(s, Vars{})
+	return err
+}
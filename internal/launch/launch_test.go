@@ -0,0 +1,168 @@
+package launch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewVars(t *testing.T) {
+	sess := &domain.Session{ID: "sess-1", Command: domain.Command{ID: "claude", DisplayName: "Claude Code"}}
+
+	local := NewVars(&domain.Project{ID: "p1", Name: "api", Type: domain.ProjectTypeLocal, Directory: "/src/api"}, sess)
+	assert.Equal(t, ProjectVars{ID: "p1", Name: "api", Directory: "/src/api"}, local.Project)
+	assert.Equal(t, SessionVars{ID: "sess-1", Name: "Claude Code", Command: "claude"}, local.Session)
+	assert.Empty(t, local.Shed)
+
+	remote := NewVars(&domain.Project{ID: "p2", Name: "web", Type: domain.ProjectTypeShed, ShedName: "web", ShedServer: "lab"}, sess)
+	assert.Empty(t, remote.Project.Directory)
+	assert.Equal(t, ShedVars{Name: "web", Server: "lab"}, remote.Shed)
+}
+
+func TestResolve(t *testing.T) {
+	vars := Vars{
+		Project: ProjectVars{Name: "api", Directory: "/src/api"},
+		Session: SessionVars{ID: "sess-1"},
+	}
+	cmd := domain.Command{
+		Exec: "claude",
+		Args: []string{"--log", "{{.Project.Directory}}/.logs/{{.Session.ID}}.log", "--plain"},
+		Env:  map[string]string{"AGENT_NAME": "{{.Project.Name}}-agent", "MODE": "fast"},
+	}
+
+	resolved, err := Resolve(cmd, vars)
+	require.NoError(t, err)
+	assert.Equal(t, "claude", resolved.Exec)
+	assert.Equal(t, []string{"--log", "/src/api/.logs/sess-1.log", "--plain"}, resolved.Args)
+	assert.Equal(t, map[string]string{"AGENT_NAME": "api-agent", "MODE": "fast"}, resolved.Env)
+	// The original command is not modified
+	assert.Equal(t, "{{.Project.Name}}-agent", cmd.Env["AGENT_NAME"])
+
+	_, err = Resolve(domain.Command{Env: map[string]string{"X": "{{.Shed.Host}}"}}, vars)
+	assert.ErrorContains(t, err, "expanding env X")
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, Check("plain --flag"))
+	assert.NoError(t, Check("{{.Shed.Server}}"))
+	assert.Error(t, Check("{{.Project.Name"))
+	assert.Error(t, Check("{{.Project.Path}}"))
+}
+
+func TestResolveEnvFrom(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "api.key"), []byte("sk-file\n"), 0o600))
+	t.Setenv("CODELY_TEST_TOKEN", "from-env")
+
+	cmd := domain.Command{
+		Exec: "claude",
+		Env:  map[string]string{"MODE": "fast"},
+		EnvFrom: map[string]domain.EnvSource{
+			"API_KEY": {File: "{{.Project.Directory}}/api.key"},
+			"TOKEN":   {Env: "CODELY_TEST_TOKEN"},
+			"HELPER":  {Command: "printf 'from-%s\\n' {{.Project.Name}}"},
+		},
+	}
+	vars := Vars{Project: ProjectVars{Name: "api", Directory: dir}}
+
+	resolved, err := Resolve(cmd, vars)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"MODE":    "fast",
+		"API_KEY": "sk-file",
+		"TOKEN":   "from-env",
+		"HELPER":  "from-api",
+	}, resolved.Env)
+	assert.Equal(t, []string{"API_KEY", "HELPER", "TOKEN"}, resolved.Secrets)
+	assert.Equal(t, []string{
+		"API_KEY=<redacted>",
+		"HELPER=<redacted>",
+		"MODE=fast",
+		"TOKEN=<redacted>",
+	}, resolved.Redacted())
+}
+
+func TestResolveEnvFromQuotesCommandValues(t *testing.T) {
+	cmd := domain.Command{EnvFrom: map[string]domain.EnvSource{
+		"NAME": {Command: "printf '%s' {{.Project.Name}}"},
+	}}
+	vars := Vars{Project: ProjectVars{Name: "it's $(echo pwned); exit 1"}}
+
+	resolved, err := Resolve(cmd, vars)
+	require.NoError(t, err)
+	assert.Equal(t, "it's $(echo pwned); exit 1", resolved.Env["NAME"])
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "api", shellQuote("api"))
+	assert.Equal(t, "/src/my-api_v2.1", shellQuote("/src/my-api_v2.1"))
+	assert.Equal(t, "''", shellQuote(""))
+	assert.Equal(t, "'a b'", shellQuote("a b"))
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+}
+
+func TestResolveEnvFromErrors(t *testing.T) {
+	_, err := Resolve(domain.Command{EnvFrom: map[string]domain.EnvSource{
+		"KEY": {Env: "CODELY_TEST_UNSET_VARIABLE"},
+	}}, Vars{})
+	assert.ErrorContains(t, err, "env_from KEY: environment variable CODELY_TEST_UNSET_VARIABLE is not set")
+
+	_, err = Resolve(domain.Command{EnvFrom: map[string]domain.EnvSource{
+		"KEY": {Command: "echo sk-leaked; echo locked >&2; exit 3"},
+	}}, Vars{})
+	assert.ErrorContains(t, err, "env_from KEY: command failed: exit status 3: locked")
+	assert.NotContains(t, err.Error(), "sk-leaked")
+
+	_, err = Resolve(domain.Command{EnvFrom: map[string]domain.EnvSource{"KEY": {}}}, Vars{})
+	assert.ErrorContains(t, err, "no source set")
+}
+
+func TestResolveEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("MODEL=opus\nPROXY=http://proxy\nTOKEN=from-file\n"), 0o600))
+
+	cmd := domain.Command{
+		EnvFile: ".env",
+		Env:     map[string]string{"MODEL": "sonnet"},
+		EnvFrom: map[string]domain.EnvSource{"TOKEN": {Command: "echo from-helper"}},
+	}
+	resolved, err := Resolve(cmd, Vars{Project: ProjectVars{Directory: dir}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MODEL=sonnet", "PROXY=http://proxy", "TOKEN=from-helper"}, resolved.EnvList())
+	assert.Equal(t, []string{"PROXY", "TOKEN"}, resolved.Secrets)
+
+	// A missing file is ignored
+	resolved, err = Resolve(domain.Command{EnvFile: "missing.env"}, Vars{Project: ProjectVars{Directory: dir}})
+	require.NoError(t, err)
+	assert.Empty(t, resolved.Env)
+
+	// Relative paths need a project directory
+	_, err = Resolve(domain.Command{EnvFile: ".env"}, Vars{Shed: ShedVars{Name: "web"}})
+	assert.ErrorContains(t, err, "needs a local project directory")
+}
+
+func TestParseDotenv(t *testing.T) {
+	env, err := parseDotenv([]byte(`# comment
+
+export A=1
+B = two words # trailing
+C='literal $HOME # not a comment'
+D="line\nbreak"
+E=
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"A": "1",
+		"B": "two words",
+		"C": "literal $HOME # not a comment",
+		"D": "line\nbreak",
+		"E": "",
+	}, env)
+
+	_, err = parseDotenv([]byte("A=1\nnot valid\n"))
+	assert.EqualError(t, err, "line 2: expected KEY=VALUE")
+}
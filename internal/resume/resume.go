@@ -0,0 +1,62 @@
+// Package resume decides how a session lost with the tmux server is started
+// again. Tools with a resume adapter continue their previous conversation
+// instead of starting a new one.
+package resume
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charliek/codely/internal/domain"
+)
+
+// adapters maps a tool to the args appended to its command to continue the
+// most recent conversation in the working directory
+var adapters = map[string][]string{
+	"claude":   {"--continue"},
+	"codex":    {"resume", "--last"},
+	"opencode": {"--continue"},
+}
+
+// Plan is how a lost session is started again
+type Plan struct {
+	Args    []string // Command args to start with, before template expansion
+	Resume  bool     // Args continue the previous conversation
+	Adapter string   // Tool adapter that supplied the args ("" for resume_args)
+}
+
+// For returns how to restart cmd. Non-empty resumeArgs replace the command's
+// args and an empty list restarts the command as configured. When
+// resumeArgs is nil, the tool's adapter, chosen like status detection by
+// the status_detection mode, command ID or exec name, adds its args.
+func For(cmd domain.Command, resumeArgs []string) Plan {
+	if resumeArgs != nil {
+		if len(resumeArgs) == 0 {
+			return Plan{Args: slices.Clone(cmd.Args)}
+		}
+		return Plan{Args: slices.Clone(resumeArgs), Resume: true}
+	}
+
+	tool := Tool(cmd)
+	extra, ok := adapters[tool]
+	if !ok {
+		return Plan{Args: slices.Clone(cmd.Args)}
+	}
+	return Plan{
+		Args:    append(slices.Clone(cmd.Args), extra...),
+		Resume:  true,
+		Adapter: tool,
+	}
+}
+
+// Tool returns the tool a command runs if it has a resume adapter, else ""
+func Tool(cmd domain.Command) string {
+	for _, name := range []string{cmd.StatusDetection, cmd.ID, filepath.Base(cmd.Exec)} {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := adapters[name]; ok {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package resume
+
+import (
+	"testing"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFor_Adapters(t *testing.T) {
+	cmd := domain.Command{ID: "claude", Exec: "claude", Args: []string{"--dangerously-skip-permissions"}}
+	plan := For(cmd, nil)
+	assert.Equal(t, []string{"--dangerously-skip-permissions", "--continue"}, plan.Args)
+	assert.Equal(t, "claude", plan.Adapter)
+	assert.True(t, plan.Resume)
+	// The command's args are not modified
+	assert.Equal(t, []string{"--dangerously-skip-permissions"}, cmd.Args)
+
+	// Matched by exec name when the ID is custom
+	plan = For(domain.Command{ID: "review", Exec: "/usr/local/bin/codex"}, nil)
+	assert.Equal(t, []string{"resume", "--last"}, plan.Args)
+	assert.Equal(t, "codex", plan.Adapter)
+
+	// Matched by status_detection mode for wrapper scripts
+	plan = For(domain.Command{ID: "agent", Exec: "run-agent", StatusDetection: "opencode"}, nil)
+	assert.Equal(t, "opencode", plan.Adapter)
+}
+
+func TestFor_NoAdapter(t *testing.T) {
+	cmd := domain.Command{ID: "bash", Exec: "bash", Args: []string{"-l"}}
+	plan := For(cmd, nil)
+	assert.Equal(t, []string{"-l"}, plan.Args)
+	assert.Empty(t, plan.Adapter)
+	assert.False(t, plan.Resume)
+}
+
+func TestFor_ResumeArgs(t *testing.T) {
+	cmd := domain.Command{ID: "claude", Exec: "claude", Args: []string{"--session-id", "{{.Session.ID}}"}}
+	plan := For(cmd, []string{"--resume", "{{.Session.ID}}"})
+	assert.Equal(t, []string{"--resume", "{{.Session.ID}}"}, plan.Args)
+	assert.Empty(t, plan.Adapter)
+	assert.True(t, plan.Resume)
+
+	// An empty list restarts the command as configured, without the adapter
+	plan = For(cmd, []string{})
+	assert.Equal(t, cmd.Args, plan.Args)
+	assert.False(t, plan.Resume)
+}
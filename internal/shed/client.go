@@ -59,7 +59,8 @@ type Client interface {
 	CreateShedStreaming(name string, opts CreateOpts) (cmdLine string, outputCh <-chan string, doneCh <-chan error)
 
 	// Execution - returns *exec.Cmd so caller can set up terminal
-	ExecCommand(shedName, command string, args ...string) *exec.Cmd
+	ExecCommand(shedName string, envFile string, command string, args ...string) *exec.Cmd
+	WriteEnvFile(shedName string, env []string) (path string, err error)
 	Console(shedName string) *exec.Cmd
 }
 
@@ -218,14 +219,52 @@ func (c *DefaultClient) DeleteShed(name string, force bool) error {
 	return nil
 }
 
-// ExecCommand returns a command that will run in the shed
+// sourceEnvScript loads and deletes the env file named by $1 inside the
+// shed, then runs the remaining arguments
+const sourceEnvScript = `. "$1" && rm -f "$1" && shift && exec "$@"`
+
+// ExecCommand returns a command that will run in the shed. When envFile is
+// set (a path returned by WriteEnvFile), the command runs with its
+// variables, and the file is deleted once read.
 // The caller should set up stdin/stdout/stderr and Run() the command
-func (c *DefaultClient) ExecCommand(shedName, command string, args ...string) *exec.Cmd {
-	cmdArgs := []string{"exec", shedName, command}
+func (c *DefaultClient) ExecCommand(shedName string, envFile string, command string, args ...string) *exec.Cmd {
+	cmdArgs := []string{"exec", shedName}
+	if envFile != "" {
+		cmdArgs = append(cmdArgs, "sh", "-c", sourceEnvScript, "sh", envFile)
+	}
+	cmdArgs = append(cmdArgs, command)
 	cmdArgs = append(cmdArgs, args...)
 	return exec.Command("shed", cmdArgs...)
 }
 
+// WriteEnvFile writes env (KEY=VALUE pairs) to a new private file inside
+// the shed and returns its path. The values are sent on stdin, so unlike
+// command arguments they are not visible in the process list.
+func (c *DefaultClient) WriteEnvFile(shedName string, env []string) (string, error) {
+	script := `umask 077 && f=$(mktemp) && cat > "$f" && echo "$f"`
+	cmd := exec.Command("shed", "exec", shedName, "sh", "-c", script)
+	cmd.Stdin = strings.NewReader(envFileContents(env))
+	output, err := cmd.Output()
+	if err != nil {
+		return "", parseExecError("exec", err)
+	}
+	path := strings.TrimSpace(string(output))
+	if path == "" {
+		return "", fmt.Errorf("shed exec: no env file path returned")
+	}
+	return path, nil
+}
+
+// envFileContents formats env as shell export lines
+func envFileContents(env []string) string {
+	var b strings.Builder
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		fmt.Fprintf(&b, "export %s='%s'\n", key, strings.ReplaceAll(value, "'", `'\''`))
+	}
+	return b.String()
+}
+
 // CreateShedStreaming creates a new shed, streaming stderr output as it runs.
 // It returns the formatted command line, a channel of stderr lines, and a done
 // channel that delivers the final error (nil on success).
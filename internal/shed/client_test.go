@@ -77,13 +77,27 @@ func TestMockClientDeleteShed(t *testing.T) {
 func TestMockClientExecCommand(t *testing.T) {
 	m := NewMockClient()
 
-	cmd := m.ExecCommand("test-shed", "claude", "--help")
+	cmd := m.ExecCommand("test-shed", "", "claude", "--help")
 
 	assert.NotNil(t, cmd)
 	assert.Len(t, m.Calls, 1)
 	assert.Equal(t, "ExecCommand", m.Calls[0].Method)
 }
 
+func TestExecCommandEnvFile(t *testing.T) {
+	c := NewClient()
+
+	cmd := c.ExecCommand("web", "", "claude", "--help")
+	assert.Equal(t, []string{"shed", "exec", "web", "claude", "--help"}, cmd.Args)
+
+	cmd = c.ExecCommand("web", "/tmp/tmp.abc", "claude")
+	assert.Equal(t, []string{"shed", "exec", "web", "sh", "-c", sourceEnvScript, "sh", "/tmp/tmp.abc", "claude"}, cmd.Args)
+}
+
+func TestEnvFileContents(t *testing.T) {
+	assert.Equal(t, "export A='1'\nexport B='it'\\''s=two words'\n", envFileContents([]string{"A=1", "B=it's=two words"}))
+}
+
 func TestMockClientConsole(t *testing.T) {
 	m := NewMockClient()
 
@@ -13,6 +13,7 @@ type MockClient struct {
 	StartShedErr      error
 	StopShedErr       error
 	DeleteShedErr     error
+	WriteEnvFileErr   error
 
 	// Track calls for verification
 	Calls []MockCall
@@ -87,12 +88,20 @@ func (m *MockClient) CreateShedStreaming(name string, opts CreateOpts) (string,
 	return cmdLine, outputCh, doneCh
 }
 
-func (m *MockClient) ExecCommand(shedName, command string, args ...string) *exec.Cmd {
-	m.recordCall("ExecCommand", shedName, command, args)
+func (m *MockClient) ExecCommand(shedName string, envFile string, command string, args ...string) *exec.Cmd {
+	m.recordCall("ExecCommand", shedName, envFile, command, args)
 	// Return a dummy command that will work
 	return exec.Command("echo", "mock")
 }
 
+func (m *MockClient) WriteEnvFile(shedName string, env []string) (string, error) {
+	m.recordCall("WriteEnvFile", shedName, env)
+	if m.WriteEnvFileErr != nil {
+		return "", m.WriteEnvFileErr
+	}
+	return "/tmp/codely-env", nil
+}
+
 func (m *MockClient) Console(shedName string) *exec.Cmd {
 	m.recordCall("Console", shedName)
 	return exec.Command("echo", "mock")
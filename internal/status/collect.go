@@ -0,0 +1,90 @@
+package status
+
+import (
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/tmux"
+)
+
+// captureLinesCount is the number of pane lines captured for detection.
+const captureLinesCount = 15
+
+// Snapshot holds the detected status of every session that has a pane.
+type Snapshot struct {
+	Updates   map[string]domain.Status // session ID -> status
+	ExitCodes map[string]*int          // session ID -> exit code (if any)
+	Dirs      map[string]string        // session ID -> pane working directory (live panes)
+
+	// CleanExits lists dead panes whose process exited with code 0.
+	// Callers that own the panes usually kill them.
+	CleanExits []int
+}
+
+// ModeFunc returns the status_detection mode to use for a session.
+type ModeFunc func(sess *domain.Session) string
+
+// Collect lists panes, captures their content and detects the status of each
+// session. Sessions without a pane (PaneID 0) are skipped.
+func Collect(client tmux.Client, projects []*domain.Project, modeFor ModeFunc) Snapshot {
+	snap := Snapshot{
+		Updates:   make(map[string]domain.Status),
+		ExitCodes: make(map[string]*int),
+		Dirs:      make(map[string]string),
+	}
+
+	panes, listErr := client.ListPanes()
+	paneMap := make(map[int]tmux.PaneInfo)
+	if listErr == nil {
+		for _, p := range panes {
+			paneMap[p.ID] = p
+		}
+	}
+
+	for _, proj := range projects {
+		for i := range proj.Sessions {
+			sess := &proj.Sessions[i]
+			if sess.PaneID == 0 {
+				continue
+			}
+
+			if listErr == nil {
+				pane, ok := paneMap[sess.PaneID]
+				if !ok {
+					snap.Updates[sess.ID] = domain.StatusExited
+					snap.ExitCodes[sess.ID] = nil
+					continue
+				}
+
+				if pane.Dead {
+					if pane.DeadCode != nil && *pane.DeadCode != 0 {
+						snap.Updates[sess.ID] = domain.StatusError
+						snap.ExitCodes[sess.ID] = pane.DeadCode
+						continue
+					}
+
+					snap.Updates[sess.ID] = domain.StatusExited
+					snap.ExitCodes[sess.ID] = pane.DeadCode
+					snap.CleanExits = append(snap.CleanExits, sess.PaneID)
+					continue
+				}
+
+				if pane.Path != "" {
+					snap.Dirs[sess.ID] = pane.Path
+				}
+			}
+
+			content, capErr := client.CapturePane(sess.PaneID, captureLinesCount)
+			if capErr != nil {
+				snap.Updates[sess.ID] = domain.StatusError
+				continue
+			}
+
+			mode := ""
+			if modeFor != nil {
+				mode = modeFor(sess)
+			}
+			snap.Updates[sess.ID] = DetectWithMode(content, sess.Command.ID, sess.Command.Exec, mode)
+		}
+	}
+
+	return snap
+}
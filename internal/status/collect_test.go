@@ -0,0 +1,69 @@
+package status
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/tmux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	zero := 0
+	two := 2
+
+	mock := tmux.NewMockClient()
+	mock.CapturePaneResult = "some output\n$ "
+	mock.ListPanesResult = []tmux.PaneInfo{
+		{ID: 1, Command: "bash", Path: "/src/api/cmd"},
+		{ID: 2, Command: "claude", Dead: true, DeadCode: &two, Path: "/src/api"},
+		{ID: 3, Command: "bash", Dead: true, DeadCode: &zero},
+	}
+
+	projects := []*domain.Project{
+		{
+			ID: "proj-1",
+			Sessions: []domain.Session{
+				{ID: "live", PaneID: 1, Command: domain.Command{ID: "bash", Exec: "bash"}},
+				{ID: "crashed", PaneID: 2, Command: domain.Command{ID: "claude", Exec: "claude"}},
+				{ID: "done", PaneID: 3, Command: domain.Command{ID: "bash", Exec: "bash"}},
+				{ID: "gone", PaneID: 4, Command: domain.Command{ID: "bash", Exec: "bash"}},
+				{ID: "unattached", PaneID: 0},
+			},
+		},
+	}
+
+	var modeCalls []string
+	snap := Collect(mock, projects, func(sess *domain.Session) string {
+		modeCalls = append(modeCalls, sess.ID)
+		return "shell"
+	})
+
+	assert.Equal(t, domain.StatusIdle, snap.Updates["live"])
+	assert.Equal(t, domain.StatusError, snap.Updates["crashed"])
+	require.NotNil(t, snap.ExitCodes["crashed"])
+	assert.Equal(t, 2, *snap.ExitCodes["crashed"])
+	assert.Equal(t, domain.StatusExited, snap.Updates["done"])
+	assert.Equal(t, domain.StatusExited, snap.Updates["gone"])
+	assert.NotContains(t, snap.Updates, "unattached")
+	assert.Equal(t, []int{3}, snap.CleanExits)
+	assert.Equal(t, []string{"live"}, modeCalls)
+	assert.Equal(t, map[string]string{"live": "/src/api/cmd"}, snap.Dirs)
+}
+
+func TestCollectCaptureError(t *testing.T) {
+	mock := tmux.NewMockClient()
+	mock.ListPanesResult = []tmux.PaneInfo{{ID: 1}}
+	mock.CapturePaneErr = errors.New("capture failed")
+
+	projects := []*domain.Project{
+		{ID: "proj-1", Sessions: []domain.Session{{ID: "sess-1", PaneID: 1}}},
+	}
+
+	snap := Collect(mock, projects, nil)
+
+	assert.Equal(t, domain.StatusError, snap.Updates["sess-1"])
+	assert.Empty(t, snap.CleanExits)
+}
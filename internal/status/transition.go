@@ -0,0 +1,102 @@
+package status
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/charliek/codely/internal/domain"
+)
+
+// Transition records a session moving from one status to another.
+type Transition struct {
+	Time      time.Time     `json:"time"`
+	ProjectID string        `json:"project_id"`
+	Project   string        `json:"project"`
+	SessionID string        `json:"session_id"`
+	Session   string        `json:"session"`
+	Command   string        `json:"command"`
+	From      domain.Status `json:"from"`
+	To        domain.Status `json:"to"`
+	ExitCode  *int          `json:"exit_code"`
+}
+
+// NewTransition builds a transition for a session at the given time.
+func NewTransition(at time.Time, proj *domain.Project, sess *domain.Session, from, to domain.Status, exitCode *int) Transition {
+	return Transition{
+		Time:      at,
+		ProjectID: proj.ID,
+		Project:   proj.Name,
+		SessionID: sess.ID,
+		Session:   sess.Command.Name(),
+		Command:   sess.Command.ID,
+		From:      from,
+		To:        to,
+		ExitCode:  exitCode,
+	}
+}
+
+// Tracker remembers the last status seen for each session and reports changes
+// between snapshots.
+type Tracker struct {
+	last map[string]observed
+	now  func() time.Time
+}
+
+// observed is the last status seen for a session, with the session and its
+// project as they were then
+type observed struct {
+	status  domain.Status
+	project domain.Project
+	session domain.Session
+}
+
+// NewTracker creates an empty tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		last: make(map[string]observed),
+		now:  time.Now,
+	}
+}
+
+// Observe compares a snapshot with the previously observed statuses and
+// returns a transition for every session whose status changed. A session seen
+// for the first time produces a transition with an empty From. A session no
+// longer present in projects, e.g. because its pane is gone, is reported as
+// exited unless it had already exited or failed, and then forgotten.
+func (t *Tracker) Observe(projects []*domain.Project, snap Snapshot) []Transition {
+	at := t.now()
+	seen := make(map[string]bool)
+
+	var transitions []Transition
+	for _, proj := range projects {
+		for i := range proj.Sessions {
+			sess := &proj.Sessions[i]
+			seen[sess.ID] = true
+			to, ok := snap.Updates[sess.ID]
+			if !ok {
+				continue
+			}
+
+			prev, known := t.last[sess.ID]
+			if known && prev.status == to {
+				continue
+			}
+			t.last[sess.ID] = observed{status: to, project: *proj, session: *sess}
+			transitions = append(transitions, NewTransition(at, proj, sess, prev.status, to, snap.ExitCodes[sess.ID]))
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(t.last)) {
+		if seen[id] {
+			continue
+		}
+		prev := t.last[id]
+		delete(t.last, id)
+		if prev.status != domain.StatusExited && prev.status != domain.StatusError {
+			transitions = append(transitions, NewTransition(at, &prev.project, &prev.session, prev.status, domain.StatusExited, nil))
+		}
+	}
+
+	return transitions
+}
@@ -0,0 +1,102 @@
+package status
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackerObserve(t *testing.T) {
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	tracker := NewTracker()
+	tracker.now = func() time.Time { return at }
+
+	projects := []*domain.Project{
+		{
+			ID:   "proj-1",
+			Name: "api",
+			Sessions: []domain.Session{
+				{ID: "s1", Command: domain.Command{ID: "claude", DisplayName: "Claude Code"}},
+				{ID: "s2", Command: domain.Command{ID: "bash"}},
+			},
+		},
+	}
+
+	// First observation reports every session with an empty From
+	got := tracker.Observe(projects, Snapshot{Updates: map[string]domain.Status{
+		"s1": domain.StatusThinking,
+		"s2": domain.StatusIdle,
+	}})
+	require.Len(t, got, 2)
+	assert.Equal(t, Transition{
+		Time:      at,
+		ProjectID: "proj-1",
+		Project:   "api",
+		SessionID: "s1",
+		Session:   "Claude Code",
+		Command:   "claude",
+		From:      "",
+		To:        domain.StatusThinking,
+	}, got[0])
+
+	// Unchanged statuses produce nothing
+	got = tracker.Observe(projects, Snapshot{Updates: map[string]domain.Status{
+		"s1": domain.StatusThinking,
+		"s2": domain.StatusIdle,
+	}})
+	assert.Empty(t, got)
+
+	// Changes carry the previous status and exit code
+	code := 1
+	got = tracker.Observe(projects, Snapshot{
+		Updates:   map[string]domain.Status{"s1": domain.StatusWaiting, "s2": domain.StatusError},
+		ExitCodes: map[string]*int{"s2": &code},
+	})
+	require.Len(t, got, 2)
+	assert.Equal(t, domain.StatusThinking, got[0].From)
+	assert.Equal(t, domain.StatusWaiting, got[0].To)
+	assert.Equal(t, domain.StatusIdle, got[1].From)
+	assert.Equal(t, &code, got[1].ExitCode)
+}
+
+func TestTrackerForgetsRemovedSessions(t *testing.T) {
+	tracker := NewTracker()
+	proj := &domain.Project{ID: "p", Sessions: []domain.Session{{ID: "s1"}}}
+	snap := Snapshot{Updates: map[string]domain.Status{"s1": domain.StatusIdle}}
+
+	tracker.Observe([]*domain.Project{proj}, snap)
+	tracker.Observe([]*domain.Project{{ID: "p"}}, Snapshot{})
+
+	got := tracker.Observe([]*domain.Project{proj}, snap)
+	require.Len(t, got, 1)
+	assert.Equal(t, domain.Status(""), got[0].From)
+}
+
+func TestTrackerReportsVanishedSessionsAsExited(t *testing.T) {
+	tracker := NewTracker()
+	proj := &domain.Project{ID: "p", Name: "api", Sessions: []domain.Session{
+		{ID: "s1", Command: domain.Command{ID: "claude"}},
+		{ID: "s2", Command: domain.Command{ID: "bash"}},
+	}}
+	tracker.Observe([]*domain.Project{proj}, Snapshot{Updates: map[string]domain.Status{
+		"s1": domain.StatusWaiting,
+		"s2": domain.StatusExited,
+	}})
+
+	// Both panes vanish between ticks, e.g. after the TUI killed a cleanly
+	// exited pane and the session was dropped. Only s1 has not yet been
+	// reported as exited.
+	got := tracker.Observe([]*domain.Project{{ID: "p", Name: "api"}}, Snapshot{})
+	require.Len(t, got, 1)
+	assert.Equal(t, "s1", got[0].SessionID)
+	assert.Equal(t, "api", got[0].Project)
+	assert.Equal(t, "claude", got[0].Command)
+	assert.Equal(t, domain.StatusWaiting, got[0].From)
+	assert.Equal(t, domain.StatusExited, got[0].To)
+
+	// The session is forgotten afterwards
+	assert.Empty(t, tracker.Observe([]*domain.Project{{ID: "p"}}, Snapshot{}))
+}
@@ -0,0 +1,197 @@
+// Package statuslog appends session status transitions to a rotating JSON
+// Lines file, so time spent in each status can be reviewed after the fact.
+package statuslog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/pathutil"
+	"github.com/charliek/codely/internal/status"
+)
+
+const (
+	// maxFileSize is the size past which an append rotates the log
+	maxFileSize = 4 << 20
+	// maxBackups is how many rotated files are kept, as <path>.1 (newest)
+	// to <path>.<maxBackups> (oldest)
+	maxBackups = 3
+)
+
+// Append adds transitions to the log at path, rotating it once it grows past
+// maxFileSize
+func Append(path string, transitions ...status.Transition) error {
+	return appendLog(path, maxFileSize, transitions...)
+}
+
+// appendLog appends transitions, rotating the log when it exceeds limit
+func appendLog(path string, limit int64, transitions ...status.Transition) error {
+	if len(transitions) == 0 {
+		return nil
+	}
+	path = pathutil.ExpandPath(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating status log directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, t := range transitions {
+		if err := enc.Encode(t); err != nil {
+			return fmt.Errorf("encoding status transition: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("opening status log: %w", err)
+	}
+	_, err = f.Write(buf.Bytes())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing status log: %w", err)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Size() > limit {
+		return rotate(path)
+	}
+	return nil
+}
+
+// rotate shifts <path>.N to <path>.N+1, dropping the oldest, and moves the
+// log to <path>.1
+func rotate(path string) error {
+	for n := maxBackups - 1; n >= 1; n-- {
+		err := os.Rename(backupPath(path, n), backupPath(path, n+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("rotating status log: %w", err)
+		}
+	}
+	if err := os.Rename(path, backupPath(path, 1)); err != nil {
+		return fmt.Errorf("rotating status log: %w", err)
+	}
+	return nil
+}
+
+// backupPath returns the name of the nth rotated log
+func backupPath(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
+
+// Load reads the log at path and its rotated files, oldest first. Missing
+// files are empty and malformed lines are skipped.
+func Load(path string) ([]status.Transition, error) {
+	path = pathutil.ExpandPath(path)
+
+	var transitions []status.Transition
+	for n := maxBackups; n >= 0; n-- {
+		file := path
+		if n > 0 {
+			file = backupPath(path, n)
+		}
+		loaded, err := loadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, loaded...)
+	}
+	return transitions, nil
+}
+
+// loadFile reads one log file
+func loadFile(path string) ([]status.Transition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening status log: %w", err)
+	}
+	defer f.Close()
+
+	var transitions []status.Transition
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var t status.Transition
+		if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+			continue
+		}
+		transitions = append(transitions, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading status log: %w", err)
+	}
+	return transitions, nil
+}
+
+// Durations returns, for each transition, how long its session had been in
+// the status it left: the time since the session's previous transition into
+// that status. It is 0 when that transition is not in the log, e.g. for the
+// first transition after codely started.
+func Durations(transitions []status.Transition) []time.Duration {
+	durations := make([]time.Duration, len(transitions))
+	last := make(map[string]status.Transition)
+	for i, t := range transitions {
+		if prev, ok := last[t.SessionID]; ok && prev.To == t.From && t.Time.After(prev.Time) {
+			durations[i] = t.Time.Sub(prev.Time)
+		}
+		last[t.SessionID] = t
+	}
+	return durations
+}
+
+// Active reports whether a session in status s is still running and
+// observed. Sessions that exited or failed, or whose status codely no longer
+// knows, e.g. because it was not running, are not.
+func Active(s domain.Status) bool {
+	switch s {
+	case "", domain.StatusExited, domain.StatusError, domain.StatusUnknown:
+		return false
+	}
+	return true
+}
+
+// Totals sums the time sessions spent in each status between since and now.
+// Time before since is not counted, so a zero since counts the whole log.
+// Each session's last status counts until now if it is Active; closed
+// sessions and codely exiting are logged as moves to exited and unknown. As
+// with Durations, time in a status whose start is not in the log is not
+// counted.
+func Totals(transitions []status.Transition, since, now time.Time) map[domain.Status]time.Duration {
+	totals := make(map[domain.Status]time.Duration)
+	add := func(s domain.Status, start, end time.Time) {
+		if start.Before(since) {
+			start = since
+		}
+		if end.After(now) {
+			end = now
+		}
+		if s != "" && end.After(start) {
+			totals[s] += end.Sub(start)
+		}
+	}
+
+	last := make(map[string]status.Transition)
+	for _, t := range transitions {
+		if prev, ok := last[t.SessionID]; ok && prev.To == t.From {
+			add(t.From, prev.Time, t.Time)
+		}
+		last[t.SessionID] = t
+	}
+	for _, t := range last {
+		if Active(t.To) {
+			add(t.To, t.Time, now)
+		}
+	}
+	return totals
+}
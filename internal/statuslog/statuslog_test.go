@@ -0,0 +1,154 @@
+package statuslog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTransition(sessionID string, from, to domain.Status, at time.Time) status.Transition {
+	return status.Transition{
+		Time:      at,
+		ProjectID: "p1",
+		Project:   "api",
+		SessionID: sessionID,
+		Session:   "Claude",
+		Command:   "claude",
+		From:      from,
+		To:        to,
+	}
+}
+
+func TestAppendLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "events.jsonl")
+
+	// A missing log is empty
+	transitions, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, transitions)
+
+	at := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	code := 1
+	errored := testTransition("s1", domain.StatusThinking, domain.StatusError, at.Add(time.Minute))
+	errored.ExitCode = &code
+	require.NoError(t, Append(path, testTransition("s1", "", domain.StatusThinking, at)))
+	require.NoError(t, Append(path, errored))
+
+	// Malformed lines are skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString("not json\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	transitions, err = Load(path)
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+	assert.Equal(t, domain.StatusThinking, transitions[0].To)
+	assert.Equal(t, domain.StatusError, transitions[1].To)
+	require.NotNil(t, transitions[1].ExitCode)
+	assert.Equal(t, 1, *transitions[1].ExitCode)
+	assert.True(t, at.Equal(transitions[0].Time))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestAppendRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	at := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+
+	// A tiny limit rotates after every append
+	for i := range maxBackups + 2 {
+		tr := testTransition("s1", domain.StatusIdle, domain.StatusWaiting, at.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, appendLog(path, 1, tr))
+	}
+
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "log should have been rotated away")
+	for n := 1; n <= maxBackups; n++ {
+		assert.FileExists(t, backupPath(path, n))
+	}
+	assert.NoFileExists(t, backupPath(path, maxBackups+1))
+
+	// The oldest transition was dropped; the rest load oldest first
+	require.NoError(t, Append(path, testTransition("s1", domain.StatusWaiting, domain.StatusThinking, at.Add(time.Hour))))
+	transitions, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, transitions, maxBackups+1)
+	assert.True(t, at.Add(2*time.Minute).Equal(transitions[0].Time))
+	assert.True(t, at.Add(time.Hour).Equal(transitions[maxBackups].Time))
+}
+
+func TestDurationsAndTotals(t *testing.T) {
+	at := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	transitions := []status.Transition{
+		testTransition("s1", "", domain.StatusThinking, at),
+		testTransition("s2", "", domain.StatusWaiting, at),
+		testTransition("s1", domain.StatusThinking, domain.StatusWaiting, at.Add(5*time.Minute)),
+		testTransition("s2", domain.StatusWaiting, domain.StatusThinking, at.Add(3*time.Minute)),
+		testTransition("s1", domain.StatusWaiting, domain.StatusThinking, at.Add(15*time.Minute)),
+		// After a restart the session's previous status is unknown
+		testTransition("s1", "", domain.StatusWaiting, at.Add(time.Hour)),
+		testTransition("s1", domain.StatusThinking, domain.StatusIdle, at.Add(2*time.Hour)),
+	}
+
+	durations := Durations(transitions)
+	assert.Equal(t, []time.Duration{0, 0, 5 * time.Minute, 3 * time.Minute, 10 * time.Minute, 0, 0}, durations)
+
+	// s2 is still thinking at the end, so that counts until now
+	assert.Equal(t, map[domain.Status]time.Duration{
+		domain.StatusThinking: 5*time.Minute + 117*time.Minute,
+		domain.StatusWaiting:  13 * time.Minute,
+	}, Totals(transitions, time.Time{}, at.Add(2*time.Hour)))
+}
+
+func TestTotalsSince(t *testing.T) {
+	at := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	transitions := []status.Transition{
+		testTransition("s1", "", domain.StatusThinking, at),
+		testTransition("s1", domain.StatusThinking, domain.StatusWaiting, at.Add(time.Hour)),
+		testTransition("s1", domain.StatusWaiting, domain.StatusThinking, at.Add(90*time.Minute)),
+	}
+	since := at.Add(50 * time.Minute)
+	now := at.Add(2 * time.Hour)
+
+	// The thinking interval that started before since is clipped to 10m,
+	// and the open thinking interval since the last change adds 30m
+	assert.Equal(t, map[domain.Status]time.Duration{
+		domain.StatusThinking: 40 * time.Minute,
+		domain.StatusWaiting:  30 * time.Minute,
+	}, Totals(transitions, since, now))
+
+	// A status entered before since and still current counts from since
+	assert.Equal(t, map[domain.Status]time.Duration{
+		domain.StatusThinking: 15 * time.Minute,
+	}, Totals(transitions, at.Add(105*time.Minute), now))
+}
+
+func TestTotalsClosedSessions(t *testing.T) {
+	at := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	transitions := []status.Transition{
+		testTransition("s1", "", domain.StatusWaiting, at.Add(-time.Hour)),
+		// Closed while waiting
+		testTransition("s1", domain.StatusWaiting, domain.StatusExited, at),
+		testTransition("s2", "", domain.StatusThinking, at),
+		// codely exited
+		testTransition("s2", domain.StatusThinking, domain.StatusUnknown, at.Add(time.Hour)),
+		testTransition("s3", "", domain.StatusThinking, at),
+		testTransition("s3", domain.StatusThinking, domain.StatusError, at.Add(30*time.Minute)),
+	}
+
+	// Nine hours later, none of the sessions' last statuses count to now
+	assert.Equal(t, map[domain.Status]time.Duration{
+		domain.StatusWaiting:  time.Hour,
+		domain.StatusThinking: 90 * time.Minute,
+	}, Totals(transitions, at.Add(-24*time.Hour), at.Add(9*time.Hour)))
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"reflect"
+
+	"github.com/charliek/codely/internal/domain"
+)
+
+// EventType identifies what changed in the store
+type EventType string
+
+// Event types emitted by the store's mutating methods
+const (
+	EventProjectAdded   EventType = "project_added"
+	EventProjectRemoved EventType = "project_removed"
+	EventProjectUpdated EventType = "project_updated"
+	EventSessionAdded   EventType = "session_added"
+	EventSessionRemoved EventType = "session_removed"
+	EventSessionUpdated EventType = "session_updated"
+	EventStatusChanged  EventType = "status_changed"
+)
+
+// Event describes a single change to the store
+type Event struct {
+	Type      EventType
+	ProjectID string
+	SessionID string         // Empty for project events
+	Session   domain.Session // Session after the change, or as removed
+	OldStatus domain.Status  // Status before an EventStatusChanged
+}
+
+// subscriber is a registered event handler
+type subscriber struct {
+	id int
+	fn func(Event)
+}
+
+// Subscribe registers fn to be called for every change to the store and
+// returns a function that removes it. Handlers run synchronously on the
+// goroutine that made the change, after the store's lock is released, so they
+// may read or modify the store.
+func (s *Store) Subscribe(fn func(Event)) (unsubscribe func()) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	s.nextSubID++
+	id := s.nextSubID
+	s.subscribers = append(s.subscribers, subscriber{id: id, fn: fn})
+
+	return func() {
+		s.subMu.Lock()
+		defer s.subMu.Unlock()
+		for i, sub := range s.subscribers {
+			if sub.id == id {
+				s.subscribers = append(s.subscribers[:i:i], s.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// publish delivers events to the current subscribers. It must be called
+// without s.mu held.
+func (s *Store) publish(events ...Event) {
+	if len(events) == 0 {
+		return
+	}
+	s.subMu.Lock()
+	subs := s.subscribers
+	s.subMu.Unlock()
+
+	for _, e := range events {
+		for _, sub := range subs {
+			sub.fn(e)
+		}
+	}
+}
+
+// sessionEvent returns the event for a session that changed from before to
+// after: EventStatusChanged if its status changed, EventSessionUpdated
+// otherwise. ok is false when nothing changed.
+func sessionEvent(projectID string, before, after domain.Session) (e Event, ok bool) {
+	if reflect.DeepEqual(before, after) {
+		return Event{}, false
+	}
+	e = Event{
+		Type:      EventSessionUpdated,
+		ProjectID: projectID,
+		SessionID: after.ID,
+		Session:   after,
+	}
+	if before.Status != after.Status {
+		e.Type = EventStatusChanged
+		e.OldStatus = before.Status
+	}
+	return e, true
+}
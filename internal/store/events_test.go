@@ -0,0 +1,113 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/tmux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreSubscribeEmitsEvents(t *testing.T) {
+	s := New(t.TempDir() + "/state.json")
+	var events []Event
+	s.Subscribe(func(e Event) { events = append(events, e) })
+
+	p := &domain.Project{ID: "proj-1", Name: "test"}
+	require.NoError(t, s.AddProject(p))
+	require.NoError(t, s.AddSession("proj-1", &domain.Session{ID: "sess-1", Status: domain.StatusThinking}))
+	require.NoError(t, s.UpdateSession("proj-1", "sess-1", func(sess *domain.Session) {
+		sess.Status = domain.StatusWaiting
+	}))
+	require.NoError(t, s.UpdateSession("proj-1", "sess-1", func(sess *domain.Session) {
+		sess.Command.DisplayName = "renamed"
+	}))
+	// No change, no event
+	require.NoError(t, s.UpdateSession("proj-1", "sess-1", func(sess *domain.Session) {
+		sess.Status = domain.StatusWaiting
+	}))
+	require.NoError(t, s.RemoveSession("proj-1", "sess-1"))
+	require.NoError(t, s.UpdateProject(&domain.Project{ID: "proj-1", Name: "renamed"}))
+	require.NoError(t, s.RemoveProject("proj-1"))
+
+	types := make([]EventType, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+	}
+	assert.Equal(t, []EventType{
+		EventProjectAdded,
+		EventSessionAdded,
+		EventStatusChanged,
+		EventSessionUpdated,
+		EventSessionRemoved,
+		EventProjectUpdated,
+		EventProjectRemoved,
+	}, types)
+
+	assert.Equal(t, "sess-1", events[2].SessionID)
+	assert.Equal(t, domain.StatusThinking, events[2].OldStatus)
+	assert.Equal(t, domain.StatusWaiting, events[2].Session.Status)
+	assert.Equal(t, "renamed", events[4].Session.Command.DisplayName)
+}
+
+func TestStoreSubscribeNoEventOnError(t *testing.T) {
+	s := New(t.TempDir() + "/state.json")
+	var events []Event
+	s.Subscribe(func(e Event) { events = append(events, e) })
+
+	assert.ErrorIs(t, s.AddSession("missing", &domain.Session{ID: "sess-1"}), domain.ErrProjectNotFound)
+	assert.ErrorIs(t, s.RemoveProject("missing"), domain.ErrProjectNotFound)
+	assert.ErrorIs(t, s.UpdateSession("missing", "sess-1", func(*domain.Session) {}), domain.ErrProjectNotFound)
+	assert.Empty(t, events)
+}
+
+func TestStoreUnsubscribe(t *testing.T) {
+	s := New(t.TempDir() + "/state.json")
+	count := 0
+	unsubscribe := s.Subscribe(func(Event) { count++ })
+
+	require.NoError(t, s.AddProject(&domain.Project{ID: "proj-1"}))
+	unsubscribe()
+	require.NoError(t, s.AddProject(&domain.Project{ID: "proj-2"}))
+
+	assert.Equal(t, 1, count)
+}
+
+func TestStoreSubscriberMayUseStore(t *testing.T) {
+	s := New(t.TempDir() + "/state.json")
+	var seen int
+	s.Subscribe(func(Event) { seen = len(s.Projects()) })
+
+	require.NoError(t, s.AddProject(&domain.Project{ID: "proj-1"}))
+	assert.Equal(t, 1, seen)
+}
+
+func TestStoreReconnectSessionsEmitsRemovals(t *testing.T) {
+	s := New(t.TempDir() + "/state.json")
+	require.NoError(t, s.AddProject(&domain.Project{
+		ID: "proj-1",
+		Sessions: []domain.Session{
+			{ID: "live", PaneID: 1},
+			{ID: "moved", PaneID: 2},
+			{ID: "lost", PaneID: 3},
+		},
+	}))
+
+	var events []Event
+	s.Subscribe(func(e Event) { events = append(events, e) })
+
+	client := tmux.NewMockClient()
+	client.ListPanesResult = []tmux.PaneInfo{
+		{ID: 1, SessionID: "live"},
+		{ID: 5, SessionID: "moved"},
+	}
+	s.ReconnectSessions(client, "default")
+
+	require.Len(t, events, 2)
+	assert.Equal(t, EventSessionUpdated, events[0].Type)
+	assert.Equal(t, "moved", events[0].SessionID)
+	assert.Equal(t, 5, events[0].Session.PaneID)
+	assert.Equal(t, EventSessionRemoved, events[1].Type)
+	assert.Equal(t, "lost", events[1].SessionID)
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Suffixes of the files kept next to the state file
+const (
+	lockSuffix    = ".lock"    // Advisory lock held around loads and saves
+	backupSuffix  = ".bak"     // Copy of the last state written
+	corruptSuffix = ".corrupt" // Unreadable state file set aside when replaced
+)
+
+// lockFile takes an advisory lock on path+".lock", shared for readers and
+// exclusive for writers. It blocks until the lock is available.
+func lockFile(path string, exclusive bool) (unlock func(), err error) {
+	f, err := os.OpenFile(path+lockSuffix, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("locking state file: %w", err)
+	}
+
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over path, so readers never see a partial file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// Clean up the temporary file if anything below fails
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// keepOriginal copies the state file as written by an older release to
+// path+".v<version>" before it is first overwritten, without the command
+// env values older files stored. An existing copy is left alone.
+func keepOriginal(path string, version int) error {
+	dest := fmt.Sprintf("%s.v%d", path, version)
+	if _, err := os.Stat(dest); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading state file: %w", err)
+	}
+
+	// Env values may hold secrets, which the migration dropped
+	var state map[string]any
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("parsing state file: %w", err)
+	}
+	dropCommandEnv(state)
+	data, err = json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding version %d state file: %w", version, err)
+	}
+	if err := writeFileAtomic(dest, data, 0600); err != nil {
+		return fmt.Errorf("keeping version %d state file: %w", version, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// CurrentVersion is the state file schema version written by this release.
+// Files without a version key are version 1.
+const CurrentVersion = 2
+
+// ErrNewerVersion is returned when the state file was written by a newer
+// codely. Such files are neither loaded nor overwritten.
+var ErrNewerVersion = errors.New("state file is newer than this codely supports")
+
+// migrations upgrade a decoded state file one version at a time;
+// migrations[i] upgrades version i+1 to i+2
+var migrations = []func(state map[string]any){
+	migrateV1ToV2,
+}
+
+// migrateV1ToV2 drops the command env values version 1 files stored, which
+// may hold secrets. Env is now resolved from config at launch.
+func migrateV1ToV2(state map[string]any) {
+	dropCommandEnv(state)
+}
+
+// dropCommandEnv deletes the env of every session command
+func dropCommandEnv(state map[string]any) {
+	for _, p := range objects(state["projects"]) {
+		for _, sess := range objects(p["sessions"]) {
+			if cmd, ok := sess["command"].(map[string]any); ok {
+				delete(cmd, "env")
+			}
+		}
+	}
+}
+
+// objects returns the JSON objects in an array value, skipping anything else
+func objects(v any) []map[string]any {
+	items, _ := v.([]any)
+	var objs []map[string]any
+	for _, item := range items {
+		if obj, ok := item.(map[string]any); ok {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// migrate upgrades state file contents to CurrentVersion. It returns the
+// upgraded contents and the version the file was written for.
+func migrate(data []byte) ([]byte, int, error) {
+	var header struct {
+		Version *int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, 0, fmt.Errorf("parsing state file: %w", err)
+	}
+
+	from := 1
+	if header.Version != nil {
+		from = *header.Version
+	}
+	switch {
+	case from > CurrentVersion:
+		return nil, from, fmt.Errorf("%w: version %d, supported up to %d; upgrade codely", ErrNewerVersion, from, CurrentVersion)
+	case from < 1:
+		return nil, from, fmt.Errorf("parsing state file: unsupported version %d", from)
+	case from == CurrentVersion:
+		return data, from, nil
+	}
+
+	var state map[string]any
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, from, fmt.Errorf("parsing state file: %w", err)
+	}
+	for v := from; v < CurrentVersion; v++ {
+		migrations[v-1](state)
+	}
+	state["version"] = CurrentVersion
+
+	migrated, err := json.Marshal(state)
+	if err != nil {
+		return nil, from, fmt.Errorf("encoding migrated state: %w", err)
+	}
+	return migrated, from, nil
+}
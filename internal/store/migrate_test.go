@@ -0,0 +1,100 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// v1State is a state file as written before versioning
+const v1State = `{
+  "projects": [
+    {
+      "id": "proj-1",
+      "name": "api",
+      "type": "local",
+      "directory": "/src/api",
+      "sessions": [
+        {
+          "id": "sess-1",
+          "project_id": "proj-1",
+          "command": {"id": "claude", "exec": "claude", "args": null, "env": {"TOKEN": "secret"}}
+        }
+      ]
+    }
+  ],
+  "tmux_session": "codely"
+}`
+
+func TestMigrate(t *testing.T) {
+	out, from, err := migrate([]byte(v1State))
+	require.NoError(t, err)
+	assert.Equal(t, 1, from)
+	assert.NotContains(t, string(out), "secret")
+
+	var state State
+	require.NoError(t, json.Unmarshal(out, &state))
+	assert.Equal(t, CurrentVersion, state.Version)
+	require.Len(t, state.Projects, 1)
+	assert.Equal(t, "claude", state.Projects[0].Sessions[0].Command.Exec)
+
+	// Current files are returned unchanged
+	again, from, err := migrate(out)
+	require.NoError(t, err)
+	assert.Equal(t, CurrentVersion, from)
+	assert.Equal(t, out, again)
+}
+
+func TestMigrateUnsupportedVersions(t *testing.T) {
+	_, _, err := migrate([]byte(`{"version": 99, "projects": []}`))
+	assert.True(t, errors.Is(err, ErrNewerVersion))
+
+	_, _, err = migrate([]byte(`{"version": 0}`))
+	assert.ErrorContains(t, err, "unsupported version 0")
+}
+
+func TestStoreLoadMigratesAndKeepsOriginal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte(v1State), 0600))
+
+	s := New(path)
+	require.NoError(t, s.Load())
+	require.Len(t, s.Projects(), 1)
+	assert.Equal(t, "proj-1", s.Projects()[0].ID)
+	assert.Equal(t, "sess-1", s.Projects()[0].Sessions[0].ID)
+
+	// The first save writes the current version and keeps the original,
+	// minus the command env
+	require.NoError(t, s.Save())
+	original, err := os.ReadFile(path + ".v1")
+	require.NoError(t, err)
+	assert.NotContains(t, string(original), "secret")
+	var kept map[string]any
+	require.NoError(t, json.Unmarshal(original, &kept))
+	assert.NotContains(t, kept, "version")
+	sess := objects(objects(kept["projects"])[0]["sessions"])[0]
+	assert.Equal(t, "sess-1", sess["id"])
+	assert.NotContains(t, sess["command"], "env")
+	assert.Equal(t, "claude", sess["command"].(map[string]any)["exec"])
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"version": 2`)
+	assert.NotContains(t, string(data), "secret")
+}
+
+func TestStoreLoadRefusesNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	newer := `{"version": 99, "projects": []}`
+	require.NoError(t, os.WriteFile(path, []byte(newer), 0600))
+	require.NoError(t, os.WriteFile(path+".bak", []byte(`{"projects": []}`), 0600))
+
+	// The backup is not used in place of a newer file
+	err := New(path).Load()
+	assert.True(t, errors.Is(err, ErrNewerVersion))
+}
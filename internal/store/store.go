@@ -3,11 +3,15 @@ package store
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/charliek/codely/internal/constants"
 	"github.com/charliek/codely/internal/debug"
 	"github.com/charliek/codely/internal/domain"
 	"github.com/charliek/codely/internal/pathutil"
@@ -16,6 +20,7 @@ import (
 
 // State represents the persisted session state
 type State struct {
+	Version     int               `json:"version"`
 	Projects    []*domain.Project `json:"projects"`
 	TmuxSession string            `json:"tmux_session"`
 }
@@ -25,6 +30,13 @@ type Store struct {
 	path  string
 	state State
 	mu    sync.RWMutex
+
+	recovered    error // Why Load fell back to the backup, if it did
+	migratedFrom int   // Version of the loaded file if it was migrated, else 0
+
+	subMu       sync.Mutex
+	subscribers []subscriber
+	nextSubID   int
 }
 
 // New creates a new store with the given path
@@ -32,13 +44,17 @@ func New(path string) *Store {
 	return &Store{
 		path: pathutil.ExpandPath(path),
 		state: State{
+			Version:     CurrentVersion,
 			Projects:    []*domain.Project{},
-			TmuxSession: "codely",
+			TmuxSession: constants.DefaultTmuxSession,
 		},
 	}
 }
 
-// Load reads the state from disk
+// Load reads the state from disk, migrating files written by older releases.
+// If the state file cannot be parsed, the backup written by the last
+// successful Save is loaded instead and Recovered reports why. A file from a
+// newer release is an error wrapping ErrNewerVersion.
 func (s *Store) Load() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -49,31 +65,76 @@ func (s *Store) Load() error {
 		return fmt.Errorf("creating state directory: %w", err)
 	}
 
-	// Check if file exists
-	if _, err := os.Stat(s.path); os.IsNotExist(err) {
-		// No existing state, use defaults
-		return nil
+	unlock, err := lockFile(s.path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, from, err := readState(s.path)
+	if err != nil {
+		if errors.Is(err, ErrNewerVersion) {
+			return err
+		}
+		backup, backupFrom, backupErr := readState(s.path + backupSuffix)
+		if backupErr != nil || backup == nil {
+			return err
+		}
+		debug.Log("store: %v; loaded backup", err)
+		s.recovered = err
+		state, from = backup, backupFrom
+	}
+
+	if state != nil {
+		s.state = *state
+		if from < CurrentVersion {
+			debug.Log("store: migrated state from version %d to %d", from, CurrentVersion)
+			s.migratedFrom = from
+		}
 	}
+	return nil
+}
 
-	data, err := os.ReadFile(s.path)
+// readState reads, migrates and parses a state file, returning the version
+// it was written for. A missing or empty file yields nil state.
+func readState(path string) (*State, int, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("reading state file: %w", err)
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("reading state file: %w", err)
 	}
 
 	if len(data) == 0 {
-		return nil
+		return nil, 0, nil
+	}
+
+	data, from, err := migrate(data)
+	if err != nil {
+		return nil, from, err
 	}
 
 	var state State
 	if err := json.Unmarshal(data, &state); err != nil {
-		return fmt.Errorf("parsing state file: %w", err)
+		return nil, from, fmt.Errorf("parsing state file: %w", err)
 	}
+	return &state, from, nil
+}
 
-	s.state = state
-	return nil
+// Recovered returns the error that made Load fall back to the backup, or
+// nil if the state file was read normally
+func (s *Store) Recovered() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.recovered
 }
 
-// Save writes the state to disk
+// Save writes the state to disk. The file is replaced atomically under an
+// exclusive lock, and a backup copy is kept for Load to fall back to. After
+// a recovered Load, the unreadable file is set aside with a .corrupt suffix
+// rather than overwritten; after a migration, the original file is kept
+// with a .v<N> suffix.
 func (s *Store) Save() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -84,15 +145,37 @@ func (s *Store) Save() error {
 		return fmt.Errorf("creating state directory: %w", err)
 	}
 
+	s.state.Version = CurrentVersion
 	data, err := json.MarshalIndent(s.state, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshaling state: %w", err)
 	}
 
+	unlock, err := lockFile(s.path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if s.recovered != nil {
+		if _, _, err := readState(s.path); err != nil {
+			_ = os.Rename(s.path, s.path+corruptSuffix)
+		}
+	}
+	if s.migratedFrom > 0 {
+		if err := keepOriginal(s.path, s.migratedFrom); err != nil {
+			return err
+		}
+		s.migratedFrom = 0
+	}
+
 	// Write with restricted permissions (owner read/write only)
-	if err := os.WriteFile(s.path, data, 0600); err != nil {
+	if err := writeFileAtomic(s.path, data, 0600); err != nil {
 		return fmt.Errorf("writing state file: %w", err)
 	}
+	if err := writeFileAtomic(s.path+backupSuffix, data, 0600); err != nil {
+		return fmt.Errorf("writing state backup: %w", err)
+	}
 
 	return nil
 }
@@ -107,30 +190,33 @@ func (s *Store) Projects() []*domain.Project {
 // AddProject adds a new project to the store
 func (s *Store) AddProject(p *domain.Project) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	// Check for duplicate ID
 	for _, existing := range s.state.Projects {
 		if existing.ID == p.ID {
+			s.mu.Unlock()
 			return fmt.Errorf("project with ID %s already exists", p.ID)
 		}
 	}
-
 	s.state.Projects = append(s.state.Projects, p)
+	s.mu.Unlock()
+
+	s.publish(Event{Type: EventProjectAdded, ProjectID: p.ID})
 	return nil
 }
 
 // RemoveProject removes a project by ID
 func (s *Store) RemoveProject(id string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	for i, p := range s.state.Projects {
 		if p.ID == id {
 			s.state.Projects = append(s.state.Projects[:i], s.state.Projects[i+1:]...)
+			s.mu.Unlock()
+
+			s.publish(Event{Type: EventProjectRemoved, ProjectID: id})
 			return nil
 		}
 	}
+	s.mu.Unlock()
 
 	return domain.ErrProjectNotFound
 }
@@ -152,14 +238,16 @@ func (s *Store) GetProject(id string) (*domain.Project, error) {
 // UpdateProject updates a project in the store
 func (s *Store) UpdateProject(p *domain.Project) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	for i, existing := range s.state.Projects {
 		if existing.ID == p.ID {
 			s.state.Projects[i] = p
+			s.mu.Unlock()
+
+			s.publish(Event{Type: EventProjectUpdated, ProjectID: p.ID})
 			return nil
 		}
 	}
+	s.mu.Unlock()
 
 	return domain.ErrProjectNotFound
 }
@@ -167,14 +255,48 @@ func (s *Store) UpdateProject(p *domain.Project) error {
 // AddSession adds a session to a project
 func (s *Store) AddSession(projectID string, session *domain.Session) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	for _, p := range s.state.Projects {
 		if p.ID == projectID {
 			p.Sessions = append(p.Sessions, *session)
+			s.mu.Unlock()
+
+			s.publish(Event{Type: EventSessionAdded, ProjectID: projectID, SessionID: session.ID, Session: *session})
+			return nil
+		}
+	}
+	s.mu.Unlock()
+
+	return domain.ErrProjectNotFound
+}
+
+// UpdateSession applies fn to a stored session. Callers should change
+// sessions through UpdateSession rather than through pointers returned by
+// the store so subscribers are told: EventStatusChanged if the status
+// changed, EventSessionUpdated for any other change, nothing otherwise.
+func (s *Store) UpdateSession(projectID, sessionID string, fn func(*domain.Session)) error {
+	s.mu.Lock()
+	for _, p := range s.state.Projects {
+		if p.ID != projectID {
+			continue
+		}
+		for i := range p.Sessions {
+			if p.Sessions[i].ID != sessionID {
+				continue
+			}
+			before := p.Sessions[i]
+			fn(&p.Sessions[i])
+			e, changed := sessionEvent(projectID, before, p.Sessions[i])
+			s.mu.Unlock()
+
+			if changed {
+				s.publish(e)
+			}
 			return nil
 		}
+		s.mu.Unlock()
+		return domain.ErrSessionNotFound
 	}
+	s.mu.Unlock()
 
 	return domain.ErrProjectNotFound
 }
@@ -182,19 +304,22 @@ func (s *Store) AddSession(projectID string, session *domain.Session) error {
 // RemoveSession removes a session from a project
 func (s *Store) RemoveSession(projectID, sessionID string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	for _, p := range s.state.Projects {
 		if p.ID == projectID {
 			for i, sess := range p.Sessions {
 				if sess.ID == sessionID {
 					p.Sessions = append(p.Sessions[:i], p.Sessions[i+1:]...)
+					s.mu.Unlock()
+
+					s.publish(Event{Type: EventSessionRemoved, ProjectID: projectID, SessionID: sessionID, Session: sess})
 					return nil
 				}
 			}
+			s.mu.Unlock()
 			return domain.ErrSessionNotFound
 		}
 	}
+	s.mu.Unlock()
 
 	return domain.ErrProjectNotFound
 }
@@ -218,50 +343,187 @@ func (s *Store) GetSession(projectID, sessionID string) (*domain.Session, error)
 	return nil, domain.ErrProjectNotFound
 }
 
+// FindProject returns a project by ID or, failing that, by name
+func (s *Store) FindProject(ref string) (*domain.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if p := s.findProject(ref); p != nil {
+		return p, nil
+	}
+	return nil, domain.ErrProjectNotFound
+}
+
+// findProject matches a project by ID, then name. Caller must hold the lock.
+func (s *Store) findProject(ref string) *domain.Project {
+	if ref == "" {
+		return nil
+	}
+	for _, p := range s.state.Projects {
+		if p.ID == ref {
+			return p
+		}
+	}
+	for _, p := range s.state.Projects {
+		if p.Name == ref {
+			return p
+		}
+	}
+	return nil
+}
+
+// ResolveSession finds a session from a user-supplied reference of the form
+// "<project>/<session>" or "<project>". The project is matched by ID or name
+// and the session by ID, ID prefix, 1-based index, display name or command ID.
+// A bare project reference resolves if the project has exactly one session,
+// and a bare session ID is also accepted.
+func (s *Store) ResolveSession(ref string) (*domain.Project, *domain.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	projRef, sessRef, hasSession := strings.Cut(ref, "/")
+
+	if !hasSession {
+		for _, p := range s.state.Projects {
+			for i := range p.Sessions {
+				if p.Sessions[i].ID == ref {
+					return p, &p.Sessions[i], nil
+				}
+			}
+		}
+	}
+
+	proj := s.findProject(projRef)
+	if proj == nil {
+		return nil, nil, fmt.Errorf("%w: %q", domain.ErrProjectNotFound, projRef)
+	}
+
+	if !hasSession {
+		switch len(proj.Sessions) {
+		case 0:
+			return nil, nil, fmt.Errorf("%w: project %q has no sessions", domain.ErrSessionNotFound, proj.Name)
+		case 1:
+			return proj, &proj.Sessions[0], nil
+		default:
+			return nil, nil, fmt.Errorf("project %q has %d sessions; use %s/<session>", proj.Name, len(proj.Sessions), proj.Name)
+		}
+	}
+
+	if n, err := strconv.Atoi(sessRef); err == nil && n >= 1 && n <= len(proj.Sessions) {
+		return proj, &proj.Sessions[n-1], nil
+	}
+
+	var matches []int
+	for i, sess := range proj.Sessions {
+		if sess.ID == sessRef {
+			return proj, &proj.Sessions[i], nil
+		}
+		if strings.HasPrefix(sess.ID, sessRef) ||
+			strings.EqualFold(sess.Command.Name(), sessRef) ||
+			sess.Command.ID == sessRef {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil, fmt.Errorf("%w: %q in project %q", domain.ErrSessionNotFound, sessRef, proj.Name)
+	case 1:
+		return proj, &proj.Sessions[matches[0]], nil
+	default:
+		return nil, nil, fmt.Errorf("%q matches %d sessions in project %q; use an index or session ID", sessRef, len(matches), proj.Name)
+	}
+}
+
 // CleanupDeadSessions removes sessions whose panes no longer exist
 func (s *Store) CleanupDeadSessions(tmuxClient tmux.Client) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
+	var events []Event
 	for _, p := range s.state.Projects {
 		var liveSessions []domain.Session
 		for _, sess := range p.Sessions {
 			if sess.PaneID > 0 && tmuxClient.PaneExists(sess.PaneID) {
 				liveSessions = append(liveSessions, sess)
+			} else {
+				events = append(events, Event{Type: EventSessionRemoved, ProjectID: p.ID, SessionID: sess.ID, Session: sess})
 			}
 		}
 		p.Sessions = liveSessions
 	}
+	s.mu.Unlock()
+
+	s.publish(events...)
 }
 
-// ReconnectSessions attempts to reconnect sessions to their existing panes
-// by matching pane IDs. Sessions with pane ID 0 or non-existent panes are removed.
-func (s *Store) ReconnectSessions(tmuxClient tmux.Client) {
+// ReconnectSessions reattaches stored sessions to their running panes.
+// Panes are matched by the codely session tag first and by pane ID as a
+// fallback for untagged panes. Sessions without a live pane are removed.
+// Tagged panes of the named workspace that do not belong to any stored
+// session are returned as orphans so the caller can surface them; panes of
+// other workspaces are left alone.
+func (s *Store) ReconnectSessions(tmuxClient tmux.Client, workspace string) []tmux.PaneInfo {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	panes, err := tmuxClient.ListPanes()
 	if err != nil {
-		return
+		s.mu.Unlock()
+		return nil
 	}
 
-	paneMap := make(map[int]bool)
+	paneMap := make(map[int]tmux.PaneInfo)
+	taggedPanes := make(map[string]tmux.PaneInfo)
 	for _, p := range panes {
-		paneMap[p.ID] = true
+		paneMap[p.ID] = p
+		if p.SessionID != "" {
+			taggedPanes[p.SessionID] = p
+		}
 	}
-	debug.Log("reconnectSessions: panes=%d", len(paneMap))
+	debug.Log("reconnectSessions: panes=%d tagged=%d", len(paneMap), len(taggedPanes))
 
+	known := make(map[string]bool)
+	var events []Event
 	for _, p := range s.state.Projects {
 		before := len(p.Sessions)
 		var liveSessions []domain.Session
 		for _, sess := range p.Sessions {
-			if sess.PaneID > 0 && paneMap[sess.PaneID] {
+			known[sess.ID] = true
+			if pane, ok := taggedPanes[sess.ID]; ok {
+				if sess.PaneID != pane.ID {
+					sess.PaneID = pane.ID
+					events = append(events, Event{Type: EventSessionUpdated, ProjectID: p.ID, SessionID: sess.ID, Session: sess})
+				}
+				liveSessions = append(liveSessions, sess)
+				continue
+			}
+			if pane, ok := paneMap[sess.PaneID]; ok && sess.PaneID > 0 && pane.SessionID == "" {
 				liveSessions = append(liveSessions, sess)
+				continue
 			}
+			events = append(events, Event{Type: EventSessionRemoved, ProjectID: p.ID, SessionID: sess.ID, Session: sess})
 		}
 		p.Sessions = liveSessions
 		debug.Log("reconnectSessions: project=%s before=%d after=%d", p.Name, before, len(liveSessions))
 	}
+
+	var orphans []tmux.PaneInfo
+	for _, p := range panes {
+		if p.SessionID != "" && !known[p.SessionID] && paneWorkspace(p) == workspace {
+			orphans = append(orphans, p)
+		}
+	}
+	debug.Log("reconnectSessions: orphans=%d", len(orphans))
+	s.mu.Unlock()
+
+	s.publish(events...)
+	return orphans
+}
+
+// paneWorkspace returns the workspace a pane was tagged with. Panes tagged
+// before workspaces existed belong to the default workspace.
+func paneWorkspace(p tmux.PaneInfo) string {
+	if p.Workspace == "" {
+		return constants.DefaultWorkspace
+	}
+	return p.Workspace
 }
 
 // TmuxSession returns the tmux session name
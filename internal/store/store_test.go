@@ -58,6 +58,36 @@ func TestStoreLoadSave(t *testing.T) {
 	assert.Len(t, projects[0].Sessions, 1)
 }
 
+func TestStoreSaveOmitsEnvValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := New(path)
+	require.NoError(t, s.AddProject(&domain.Project{
+		ID:   "proj-1",
+		Name: "api",
+		Sessions: []domain.Session{{
+			ID: "sess-1",
+			Command: domain.Command{
+				ID:      "claude",
+				Exec:    "claude",
+				Env:     map[string]string{"ANTHROPIC_API_KEY": "sk-secret"},
+				EnvFrom: map[string]domain.EnvSource{"GITHUB_TOKEN": {Command: "gh auth token"}},
+			},
+		}},
+	}))
+	require.NoError(t, s.Save())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-secret")
+	assert.NotContains(t, string(data), "ANTHROPIC_API_KEY")
+
+	s2 := New(path)
+	require.NoError(t, s2.Load())
+	sess := s2.Projects()[0].Sessions[0]
+	assert.Nil(t, sess.Command.Env)
+	assert.Equal(t, domain.EnvSource{Command: "gh auth token"}, sess.Command.EnvFrom["GITHUB_TOKEN"])
+}
+
 func TestStoreAddRemoveProject(t *testing.T) {
 	s := New("/tmp/nonexistent.json")
 
@@ -238,3 +268,167 @@ func TestStoreUpdateProject(t *testing.T) {
 	err = s.UpdateProject(&domain.Project{ID: "proj-999"})
 	assert.ErrorIs(t, err, domain.ErrProjectNotFound)
 }
+
+func TestStoreReconnectSessionsByTag(t *testing.T) {
+	s := New("/tmp/nonexistent.json")
+
+	// Pane IDs are not persisted, so sessions loaded from disk have PaneID 0
+	p := &domain.Project{
+		ID:   "proj-1",
+		Name: "test",
+		Sessions: []domain.Session{
+			{ID: "sess-1", ProjectID: "proj-1"},
+			{ID: "sess-2", ProjectID: "proj-1"},
+			{ID: "sess-3", ProjectID: "proj-1", PaneID: 9},
+		},
+	}
+	_ = s.AddProject(p)
+
+	mock := tmux.NewMockClient()
+	mock.ListPanesResult = []tmux.PaneInfo{
+		{ID: 0, Command: "codely"},
+		{ID: 4, Command: "claude", SessionID: "sess-1", ProjectID: "proj-1"},
+		{ID: 7, Command: "bash", SessionID: "sess-old", ProjectID: "proj-1"},
+		{ID: 9, Command: "bash"},
+		{ID: 11, Command: "bash", SessionID: "sess-other", ProjectID: "proj-2", Workspace: "client-a"},
+	}
+
+	orphans := s.ReconnectSessions(mock, "default")
+
+	got, _ := s.GetProject("proj-1")
+	require.Len(t, got.Sessions, 2)
+	assert.Equal(t, "sess-1", got.Sessions[0].ID)
+	assert.Equal(t, 4, got.Sessions[0].PaneID)
+	assert.Equal(t, "sess-3", got.Sessions[1].ID)
+	assert.Equal(t, 9, got.Sessions[1].PaneID)
+
+	require.Len(t, orphans, 1)
+	assert.Equal(t, 7, orphans[0].ID)
+	assert.Equal(t, "sess-old", orphans[0].SessionID)
+
+	// Only panes tagged with the same workspace are orphans
+	orphans = s.ReconnectSessions(mock, "client-a")
+	require.Len(t, orphans, 1)
+	assert.Equal(t, "sess-other", orphans[0].SessionID)
+}
+
+func TestStoreResolveSession(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(filepath.Join(tmpDir, "session.json"))
+
+	require.NoError(t, s.AddProject(&domain.Project{
+		ID:   "proj-api",
+		Name: "api",
+		Sessions: []domain.Session{
+			{ID: "aaaa-1111", Command: domain.Command{ID: "claude", DisplayName: "Claude Code"}},
+			{ID: "bbbb-2222", Command: domain.Command{ID: "bash", DisplayName: "Bash Shell"}},
+			{ID: "cccc-3333", Command: domain.Command{ID: "bash", DisplayName: "Tests"}},
+		},
+	}))
+	require.NoError(t, s.AddProject(&domain.Project{
+		ID:       "proj-web",
+		Name:     "web",
+		Sessions: []domain.Session{{ID: "dddd-4444", Command: domain.Command{ID: "codex"}}},
+	}))
+	require.NoError(t, s.AddProject(&domain.Project{ID: "proj-empty", Name: "empty"}))
+
+	resolved := []struct {
+		ref       string
+		projectID string
+		sessionID string
+	}{
+		{"api/claude", "proj-api", "aaaa-1111"},
+		{"api/claude code", "proj-api", "aaaa-1111"},
+		{"api/tests", "proj-api", "cccc-3333"},
+		{"api/2", "proj-api", "bbbb-2222"},
+		{"api/bbbb", "proj-api", "bbbb-2222"},
+		{"proj-api/aaaa-1111", "proj-api", "aaaa-1111"},
+		{"web", "proj-web", "dddd-4444"},
+		{"dddd-4444", "proj-web", "dddd-4444"},
+	}
+	for _, tc := range resolved {
+		proj, sess, err := s.ResolveSession(tc.ref)
+		require.NoError(t, err, tc.ref)
+		assert.Equal(t, tc.projectID, proj.ID, tc.ref)
+		assert.Equal(t, tc.sessionID, sess.ID, tc.ref)
+	}
+
+	_, _, err := s.ResolveSession("api/bash")
+	assert.ErrorContains(t, err, "matches 2 sessions")
+
+	_, _, err = s.ResolveSession("api")
+	assert.ErrorContains(t, err, "has 3 sessions")
+
+	_, _, err = s.ResolveSession("empty")
+	assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+
+	_, _, err = s.ResolveSession("api/opencode")
+	assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+
+	_, _, err = s.ResolveSession("missing/claude")
+	assert.ErrorIs(t, err, domain.ErrProjectNotFound)
+}
+
+func TestStoreSaveIsAtomicWithBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	s := New(path)
+	require.NoError(t, s.AddProject(&domain.Project{ID: "proj-1", Name: "one"}))
+	require.NoError(t, s.Save())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	backup, err := os.ReadFile(path + ".bak")
+	require.NoError(t, err)
+	assert.Equal(t, data, backup)
+
+	// Only the state, backup and lock files remain; no temporary files
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"state.json", "state.json.bak", "state.json.lock"}, names)
+}
+
+func TestStoreLoadFallsBackToBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	s := New(path)
+	require.NoError(t, s.AddProject(&domain.Project{ID: "proj-1", Name: "one"}))
+	require.NoError(t, s.Save())
+
+	// Simulate a truncated write by an older codely
+	require.NoError(t, os.WriteFile(path, []byte(`{"projects": [`), 0600))
+
+	s2 := New(path)
+	require.NoError(t, s2.Load())
+	require.ErrorContains(t, s2.Recovered(), "parsing state file")
+	require.Len(t, s2.Projects(), 1)
+	assert.Equal(t, "proj-1", s2.Projects()[0].ID)
+
+	// Saving sets the unreadable file aside
+	require.NoError(t, s2.Save())
+	corrupt, err := os.ReadFile(path + ".corrupt")
+	require.NoError(t, err)
+	assert.Equal(t, `{"projects": [`, string(corrupt))
+
+	s3 := New(path)
+	require.NoError(t, s3.Load())
+	assert.NoError(t, s3.Recovered())
+	assert.Len(t, s3.Projects(), 1)
+}
+
+func TestStoreLoadCorruptWithoutBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0600))
+
+	err := New(path).Load()
+	assert.ErrorContains(t, err, "parsing state file")
+}
@@ -0,0 +1,51 @@
+package tmux
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// probePaneTarget is a pane that never exists, used to probe flag support
+// without touching real panes.
+const probePaneTarget = "%2147483647"
+
+// Version returns the installed tmux version (e.g. "3.4")
+func Version() (string, error) {
+	output, err := exec.Command("tmux", "-V").Output()
+	if err != nil {
+		return "", fmt.Errorf("tmux -V failed: %w", err)
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(output)), "tmux "), nil
+}
+
+// ProbeJoinPaneFormat reports whether join-pane accepts -P/-F to print the
+// joined pane's ID. tmux rejects unknown flags while parsing the command, so
+// probing against a non-existent pane is safe and works without a server.
+func ProbeJoinPaneFormat() bool {
+	output, _ := exec.Command("tmux", "join-pane",
+		"-P", "-F", "#{pane_id}",
+		"-s", probePaneTarget,
+		"-t", probePaneTarget,
+	).CombinedOutput()
+	return !isUnknownFlag(string(output))
+}
+
+// isUnknownFlag reports whether tmux output is a flag parsing error
+func isUnknownFlag(output string) bool {
+	return strings.Contains(output, "unknown flag")
+}
+
+var (
+	joinPaneFormatOnce      sync.Once
+	joinPaneFormatSupported bool
+)
+
+// joinPaneFormat returns the cached result of ProbeJoinPaneFormat
+func joinPaneFormat() bool {
+	joinPaneFormatOnce.Do(func() {
+		joinPaneFormatSupported = ProbeJoinPaneFormat()
+	})
+	return joinPaneFormatSupported
+}
@@ -9,6 +9,15 @@ import (
 	"strings"
 )
 
+// Pane user options used to tag panes created by codely. They survive
+// break-pane/join-pane and codely restarts, so sessions can be matched back
+// to their panes even though pane IDs are not persisted.
+const (
+	SessionIDOption = "@codely_session_id"
+	ProjectIDOption = "@codely_project_id"
+	WorkspaceOption = "@codely_workspace"
+)
+
 // PaneInfo contains information about a tmux pane
 type PaneInfo struct {
 	ID       int
@@ -17,23 +26,36 @@ type PaneInfo struct {
 	WindowID string
 	Dead     bool
 	DeadCode *int
+
+	// Codely tags (empty for panes not created by codely)
+	SessionID string
+	ProjectID string
+	Workspace string // Empty for panes tagged before workspaces existed
+
+	Path string // Current working directory of the pane's process
 }
 
 // Client defines the interface for tmux operations
 type Client interface {
 	// Session management
 	InTmux() bool
-	CreateSession(name string) error
+	HasSession(name string) bool
+	CreateSession(name, command string, args ...string) error
 	AttachSession(name string) error
+	SwitchClient(name string) error
 
 	// Pane management
-	SplitWindow(dir, command string, args ...string) (paneID int, err error)
-	SplitPane(targetPaneID int, vertical bool, dir, command string, args ...string) (paneID int, err error)
+	SplitWindow(dir string, env []string, command string, args ...string) (paneID int, err error)
+	SplitPane(targetPaneID int, vertical bool, dir string, env []string, command string, args ...string) (paneID int, err error)
 	FocusPane(paneID int) error
 	KillPane(paneID int) error
 	ResizePane(paneID int, width int) error
 	ToggleZoom(paneID int) error
 	SetRemainOnExit(paneID int, enabled bool) error
+	SetPaneOption(paneID int, name, value string) error
+
+	// Input
+	SendKeys(paneID int, text string, enter bool) error
 
 	// Pane visibility management (for single visible pane mode)
 	BreakPane(paneID int) (newPaneID int, err error)                  // Move pane to background window
@@ -67,10 +89,27 @@ func (c *DefaultClient) InTmux() bool {
 	return os.Getenv("TMUX") != ""
 }
 
-// CreateSession creates a new tmux session with the given name
-func (c *DefaultClient) CreateSession(name string) error {
-	cmd := exec.Command("tmux", "new-session", "-d", "-s", name)
-	return cmd.Run()
+// HasSession returns true if a tmux session with exactly the given name exists
+func (c *DefaultClient) HasSession(name string) bool {
+	cmd := exec.Command("tmux", "has-session", "-t", "="+name)
+	return cmd.Run() == nil
+}
+
+// CreateSession creates a new detached tmux session with the given name.
+// If command is not empty it runs in the session's first pane instead of
+// the default shell.
+func (c *DefaultClient) CreateSession(name, command string, args ...string) error {
+	tmuxArgs := []string{"new-session", "-d", "-s", name}
+	if command != "" {
+		tmuxArgs = append(tmuxArgs, shellQuoteCommand(command, args...))
+	}
+
+	cmd := exec.Command("tmux", tmuxArgs...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("new-session failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
 }
 
 // AttachSession attaches to an existing tmux session
@@ -82,10 +121,21 @@ func (c *DefaultClient) AttachSession(name string) error {
 	return cmd.Run()
 }
 
+// SwitchClient moves the current tmux client to another session
+func (c *DefaultClient) SwitchClient(name string) error {
+	cmd := exec.Command("tmux", "switch-client", "-t", "="+name)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("switch-client failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
 // SplitWindow creates a new pane by splitting the current window horizontally
-// It runs the specified command with args in the given directory
+// It runs the specified command with args in the given directory, with env
+// (KEY=VALUE pairs) added to its environment
 // Returns the pane ID of the newly created pane
-func (c *DefaultClient) SplitWindow(dir, command string, args ...string) (int, error) {
+func (c *DefaultClient) SplitWindow(dir string, env []string, command string, args ...string) (int, error) {
 	if strings.ContainsAny(command, " \t") {
 		fmt.Fprintf(os.Stderr, "codely: command exec contains whitespace; use exec + args instead: %q\n", command)
 	}
@@ -103,6 +153,7 @@ func (c *DefaultClient) SplitWindow(dir, command string, args ...string) (int, e
 	if dir != "" {
 		tmuxArgs = append(tmuxArgs, "-c", dir)
 	}
+	tmuxArgs = appendEnvArgs(tmuxArgs, env)
 
 	tmuxArgs = append(tmuxArgs, fullCmd)
 
@@ -126,10 +177,19 @@ func (c *DefaultClient) SplitWindow(dir, command string, args ...string) (int, e
 	return paneID, nil
 }
 
+// appendEnvArgs adds a split-window -e flag for each KEY=VALUE pair
+func appendEnvArgs(tmuxArgs, env []string) []string {
+	for _, kv := range env {
+		tmuxArgs = append(tmuxArgs, "-e", kv)
+	}
+	return tmuxArgs
+}
+
 // SplitPane creates a new pane by splitting a specific target pane
 // If vertical is true, splits vertically (new pane below); otherwise horizontally (new pane to right)
+// The command gets env (KEY=VALUE pairs) added to its environment
 // Returns the pane ID of the newly created pane
-func (c *DefaultClient) SplitPane(targetPaneID int, vertical bool, dir, command string, args ...string) (int, error) {
+func (c *DefaultClient) SplitPane(targetPaneID int, vertical bool, dir string, env []string, command string, args ...string) (int, error) {
 	if strings.ContainsAny(command, " \t") {
 		fmt.Fprintf(os.Stderr, "codely: command exec contains whitespace; use exec + args instead: %q\n", command)
 	}
@@ -153,6 +213,7 @@ func (c *DefaultClient) SplitPane(targetPaneID int, vertical bool, dir, command
 	if dir != "" {
 		tmuxArgs = append(tmuxArgs, "-c", dir)
 	}
+	tmuxArgs = appendEnvArgs(tmuxArgs, env)
 
 	tmuxArgs = append(tmuxArgs, fullCmd)
 
@@ -210,6 +271,31 @@ func (c *DefaultClient) SetRemainOnExit(paneID int, enabled bool) error {
 	return cmd.Run()
 }
 
+// SetPaneOption sets a pane-scoped option (e.g. a @user option) on a pane.
+func (c *DefaultClient) SetPaneOption(paneID int, name, value string) error {
+	cmd := exec.Command("tmux", "set-option", "-p", "-t", fmt.Sprintf("%%%d", paneID), name, value)
+	return cmd.Run()
+}
+
+// SendKeys types text literally into the specified pane, optionally
+// followed by Enter
+func (c *DefaultClient) SendKeys(paneID int, text string, enter bool) error {
+	target := fmt.Sprintf("%%%d", paneID)
+	if text != "" {
+		cmd := exec.Command("tmux", "send-keys", "-t", target, "-l", text)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("send-keys: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+	}
+	if enter {
+		cmd := exec.Command("tmux", "send-keys", "-t", target, "Enter")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("send-keys: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
 // CapturePane captures the last N lines of content from the specified pane
 func (c *DefaultClient) CapturePane(paneID int, lines int) (string, error) {
 	cmd := exec.Command("tmux", "capture-pane",
@@ -228,23 +314,32 @@ func (c *DefaultClient) CapturePane(paneID int, lines int) (string, error) {
 func (c *DefaultClient) ListPanes() ([]PaneInfo, error) {
 	cmd := exec.Command("tmux", "list-panes",
 		"-a", // all panes across all sessions
-		"-F", "#{pane_id}:#{pane_current_command}:#{pane_active}:#{window_id}:#{pane_dead}:#{pane_dead_status}",
+		"-F", "#{pane_id}:#{pane_current_command}:#{pane_active}:#{window_id}:#{pane_dead}:#{pane_dead_status}:#{"+SessionIDOption+"}:#{"+ProjectIDOption+"}:#{"+WorkspaceOption+"}:#{pane_current_path}",
 	)
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("list-panes failed: %w", err)
 	}
 
+	return parsePaneList(string(output)), nil
+}
+
+// parsePaneList parses list-panes output produced by the ListPanes format.
+func parsePaneList(output string) []PaneInfo {
 	var panes []PaneInfo
-	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
 		if line == "" {
 			continue
 		}
 
-		parts := strings.SplitN(line, ":", 6)
+		// The path comes last since it may itself contain colons
+		parts := strings.SplitN(line, ":", 10)
 		if len(parts) < 6 {
 			continue
 		}
+		for len(parts) < 10 {
+			parts = append(parts, "")
+		}
 
 		// Parse pane ID (format: %N)
 		idStr := parts[0]
@@ -265,16 +360,20 @@ func (c *DefaultClient) ListPanes() ([]PaneInfo, error) {
 		}
 
 		panes = append(panes, PaneInfo{
-			ID:       id,
-			Command:  parts[1],
-			Active:   parts[2] == "1",
-			WindowID: parts[3],
-			Dead:     dead,
-			DeadCode: deadCode,
+			ID:        id,
+			Command:   parts[1],
+			Active:    parts[2] == "1",
+			WindowID:  parts[3],
+			Dead:      dead,
+			DeadCode:  deadCode,
+			SessionID: parts[6],
+			ProjectID: parts[7],
+			Workspace: parts[8],
+			Path:      parts[9],
 		})
 	}
 
-	return panes, nil
+	return panes
 }
 
 // GetStatusRight returns the current status-right value.
@@ -377,6 +476,20 @@ func (c *DefaultClient) BreakPane(paneID int) (int, error) {
 // The pane is joined as a horizontal split next to targetPaneID
 // Returns the new pane ID (pane ID may change after join-pane)
 func (c *DefaultClient) JoinPane(paneID int, targetPaneID int) (int, error) {
+	// Older tmux (e.g. 3.4 and earlier) doesn't support -P on join-pane
+	if !joinPaneFormat() {
+		cmd := exec.Command("tmux", "join-pane",
+			"-s", fmt.Sprintf("%%%d", paneID),
+			"-t", fmt.Sprintf("%%%d", targetPaneID),
+			"-h",
+		)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return 0, fmt.Errorf("join-pane failed: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+		// Assume pane ID stays the same; caller can verify if needed.
+		return paneID, nil
+	}
+
 	cmd := exec.Command("tmux", "join-pane",
 		"-s", fmt.Sprintf("%%%d", paneID), // source pane
 		"-t", fmt.Sprintf("%%%d", targetPaneID), // target pane
@@ -386,21 +499,7 @@ func (c *DefaultClient) JoinPane(paneID int, targetPaneID int) (int, error) {
 	)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		msg := strings.TrimSpace(string(output))
-		// tmux 3.4 doesn't support -P on join-pane
-		if strings.Contains(msg, "unknown flag -P") {
-			cmd = exec.Command("tmux", "join-pane",
-				"-s", fmt.Sprintf("%%%d", paneID),
-				"-t", fmt.Sprintf("%%%d", targetPaneID),
-				"-h",
-			)
-			if err := cmd.Run(); err != nil {
-				return 0, fmt.Errorf("join-pane failed: %w", err)
-			}
-			// Assume pane ID stays the same; caller can verify if needed.
-			return paneID, nil
-		}
-		return 0, fmt.Errorf("join-pane failed: %w: %s", err, msg)
+		return 0, fmt.Errorf("join-pane failed: %w: %s", err, strings.TrimSpace(string(output)))
 	}
 
 	// Parse new pane ID from output (format: %N)
@@ -18,7 +18,7 @@ func TestMockClientSplitWindow(t *testing.T) {
 	m := NewMockClient()
 	m.SplitWindowPaneID = 42
 
-	paneID, err := m.SplitWindow("/tmp", "bash", "-c", "echo hello")
+	paneID, err := m.SplitWindow("/tmp", nil, "bash", "-c", "echo hello")
 
 	assert.NoError(t, err)
 	assert.Equal(t, 42, paneID)
@@ -71,3 +71,44 @@ func TestMockClientPaneExists(t *testing.T) {
 	m.PaneExistsResult = false
 	assert.False(t, m.PaneExists(5))
 }
+
+func TestParsePaneList(t *testing.T) {
+	output := "%0:codely:1:@0:0:::\n" +
+		"%3:claude:0:@1:0::sess-1:proj-1:client-a:/home/me/src/odd:name\n" +
+		"%4:bash:0:@2:1:2::\n" +
+		"%5:zsh:0:@3:0:\n"
+
+	panes := parsePaneList(output)
+
+	assert.Len(t, panes, 4)
+	assert.Equal(t, 0, panes[0].ID)
+	assert.Empty(t, panes[0].SessionID)
+
+	assert.Equal(t, 3, panes[1].ID)
+	assert.Equal(t, "sess-1", panes[1].SessionID)
+	assert.Equal(t, "proj-1", panes[1].ProjectID)
+	assert.Equal(t, "client-a", panes[1].Workspace)
+	assert.Equal(t, "/home/me/src/odd:name", panes[1].Path)
+
+	assert.True(t, panes[2].Dead)
+	if assert.NotNil(t, panes[2].DeadCode) {
+		assert.Equal(t, 2, *panes[2].DeadCode)
+	}
+
+	// Output from older formats without tag fields still parses
+	assert.Equal(t, 5, panes[3].ID)
+	assert.Empty(t, panes[3].ProjectID)
+}
+
+func TestIsUnknownFlag(t *testing.T) {
+	assert.True(t, isUnknownFlag("command join-pane: unknown flag -P\n"))
+	assert.False(t, isUnknownFlag("can't find pane: %2147483647\n"))
+	assert.False(t, isUnknownFlag("no server running on /tmp/tmux-1000/default\n"))
+}
+
+func TestAppendEnvArgs(t *testing.T) {
+	assert.Equal(t, []string{"split-window"}, appendEnvArgs([]string{"split-window"}, nil))
+	assert.Equal(t,
+		[]string{"split-window", "-e", "A=1", "-e", "B=two words"},
+		appendEnvArgs([]string{"split-window"}, []string{"A=1", "B=two words"}))
+}
@@ -3,8 +3,10 @@ package tmux
 // MockClient is a mock implementation of Client for testing
 type MockClient struct {
 	InTmuxResult       bool
+	HasSessionResult   bool
 	CreateSessionErr   error
 	AttachSessionErr   error
+	SwitchClientErr    error
 	SplitWindowPaneID  int
 	SplitWindowErr     error
 	SplitPanePaneID    int
@@ -14,6 +16,8 @@ type MockClient struct {
 	ResizePaneErr      error
 	ToggleZoomErr      error
 	SetRemainOnExitErr error
+	SetPaneOptionErr   error
+	SendKeysErr        error
 	BreakPanePaneID    int
 	BreakPaneErr       error
 	JoinPanePaneID     int
@@ -64,8 +68,13 @@ func (m *MockClient) InTmux() bool {
 	return m.InTmuxResult
 }
 
-func (m *MockClient) CreateSession(name string) error {
-	m.recordCall("CreateSession", name)
+func (m *MockClient) HasSession(name string) bool {
+	m.recordCall("HasSession", name)
+	return m.HasSessionResult
+}
+
+func (m *MockClient) CreateSession(name, command string, args ...string) error {
+	m.recordCall("CreateSession", name, command, args)
 	return m.CreateSessionErr
 }
 
@@ -74,13 +83,18 @@ func (m *MockClient) AttachSession(name string) error {
 	return m.AttachSessionErr
 }
 
-func (m *MockClient) SplitWindow(dir, command string, args ...string) (int, error) {
-	m.recordCall("SplitWindow", dir, command, args)
+func (m *MockClient) SwitchClient(name string) error {
+	m.recordCall("SwitchClient", name)
+	return m.SwitchClientErr
+}
+
+func (m *MockClient) SplitWindow(dir string, env []string, command string, args ...string) (int, error) {
+	m.recordCall("SplitWindow", dir, env, command, args)
 	return m.SplitWindowPaneID, m.SplitWindowErr
 }
 
-func (m *MockClient) SplitPane(targetPaneID int, vertical bool, dir, command string, args ...string) (int, error) {
-	m.recordCall("SplitPane", targetPaneID, vertical, dir, command, args)
+func (m *MockClient) SplitPane(targetPaneID int, vertical bool, dir string, env []string, command string, args ...string) (int, error) {
+	m.recordCall("SplitPane", targetPaneID, vertical, dir, env, command, args)
 	return m.SplitPanePaneID, m.SplitPaneErr
 }
 
@@ -109,6 +123,16 @@ func (m *MockClient) SetRemainOnExit(paneID int, enabled bool) error {
 	return m.SetRemainOnExitErr
 }
 
+func (m *MockClient) SetPaneOption(paneID int, name, value string) error {
+	m.recordCall("SetPaneOption", paneID, name, value)
+	return m.SetPaneOptionErr
+}
+
+func (m *MockClient) SendKeys(paneID int, text string, enter bool) error {
+	m.recordCall("SendKeys", paneID, text, enter)
+	return m.SendKeysErr
+}
+
 func (m *MockClient) CapturePane(paneID int, lines int) (string, error) {
 	m.recordCall("CapturePane", paneID, lines)
 	return m.CapturePaneResult, m.CapturePaneErr
@@ -3,48 +3,90 @@ package tui
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/charliek/codely/internal/config"
+	"github.com/charliek/codely/internal/control"
 	"github.com/charliek/codely/internal/debug"
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/frecency"
 	"github.com/charliek/codely/internal/shed"
 	"github.com/charliek/codely/internal/store"
 	"github.com/charliek/codely/internal/tmux"
+	"github.com/charliek/codely/internal/workspace"
 )
 
+// Options configures a TUI run
+type Options struct {
+	ConfigSources config.Sources      // Config layers; files are watched for changes (none disables reload)
+	Workspace     workspace.Workspace // Workspace this codely manages (zero value is the default workspace)
+	StorePath     string              // State file path
+	FolderCache   string              // Folder picker scan cache ("" disables caching)
+	FrecencyPath  string              // Folder open history ("" disables frecency ranking)
+	HistoryPath   string              // Closed session archive ("" disables history)
+	EventsPath    string              // Status transition log ("" disables the log)
+	SocketPath    string              // Control socket path ("" disables the socket)
+	Debug         bool                // Enable debug logging
+	DebugFile     string              // Debug log file path
+	Skin          SkinName            // UI skin override ("" uses ui.skin)
+}
+
 // Run starts the TUI application
-func Run(cfg *config.Config, storePath string, debugMode bool, debugFile string, skinName SkinName) error {
-	if debugMode {
-		if err := debug.Enable(debugFile); err != nil {
+func Run(cfg *config.Config, opts Options) error {
+	if opts.Debug {
+		if err := debug.Enable(opts.DebugFile); err != nil {
 			return fmt.Errorf("enabling debug log: %w", err)
 		}
 		defer debug.Close()
 		debug.Log("codely starting")
 	}
 
+	ws := opts.Workspace
+	if ws.Name == "" {
+		ws = workspace.Default()
+	}
+
 	// Create tmux client
 	tmuxClient := tmux.NewClient()
 
-	// Check if in tmux
-	if !tmuxClient.InTmux() {
-		return fmt.Errorf("codely must be run inside tmux. Please start tmux first with: tmux new-session -s codely")
-	}
-
 	// Create store and load state
-	st := store.New(storePath)
+	st := store.New(opts.StorePath)
 	if err := st.Load(); err != nil {
 		return fmt.Errorf("loading state: %w", err)
 	}
+	st.SetTmuxSession(ws.TmuxSession)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating codely executable: %w", err)
+	}
 
-	// Cleanup dead sessions
-	st.ReconnectSessions(tmuxClient)
+	// Outside tmux: start (or join) the workspace's tmux session, which
+	// re-runs codely in its first pane
+	if !tmuxClient.InTmux() {
+		args := os.Args[1:]
+		if !ws.IsDefault() {
+			// The workspace may have come from the environment, which the
+			// tmux server does not pass on
+			args = append(slices.Clone(args), "--workspace", ws.Name)
+		}
+		return bootstrapTmux(tmuxClient, st.TmuxSession(), exe, args)
+	}
+
+	// Reattach sessions to their tagged panes and drop dead ones
+	before := make(map[string][]domain.Session)
+	for _, p := range st.Projects() {
+		before[p.ID] = slices.Clone(p.Sessions)
+	}
+	orphans := st.ReconnectSessions(tmuxClient, ws.Name)
 	if err := st.Save(); err != nil {
 		return fmt.Errorf("saving state: %w", err)
 	}
-	debug.Log("store loaded: projects=%d", len(st.Projects()))
+	debug.Log("store loaded: workspace=%s projects=%d", ws.Name, len(st.Projects()))
 	debug.Log("sessions reconnected: projects=%d", len(st.Projects()))
 
 	// Create shed client (optional - may not be available)
@@ -87,7 +129,30 @@ func Run(cfg *config.Config, storePath string, debugMode bool, debugFile string,
 	debug.Log("startup: TMUX_PANE=%s codelyPaneID=%d codelyWindowID=%s", os.Getenv("TMUX_PANE"), codelyPaneID, codelyWindowID)
 
 	// Create model
-	model := NewModel(cfg, st, tmuxClient, shedClient, codelyPaneID, codelyWindowID, skinName)
+	model := NewModel(cfg, st, tmuxClient, shedClient, codelyPaneID, codelyWindowID, resolveSkin(opts.Skin, cfg))
+	model.skinOverride = opts.Skin
+	model.configSources = opts.ConfigSources
+	model.configModTime = configModTime(opts.ConfigSources.Files)
+	model.folderCachePath = opts.FolderCache
+	model.frecency = loadFrecency(opts.FrecencyPath)
+	model.historyPath = opts.HistoryPath
+	if opts.EventsPath != "" {
+		st.Subscribe(logStatusChanges(st, opts.EventsPath))
+	}
+	model.workspace = ws
+	model.codelyExe = exe
+	model.codelyArgs = os.Args[1:]
+	model.archiveLost(before)
+	model.offerResurrect(before)
+	model.addNotice(orphanNotice(orphans, st))
+	if err := st.Recovered(); err != nil {
+		model.addNotice(fmt.Sprintf("state: %v; restored the last saved state from backup", err))
+	}
+	for _, w := range cfg.Warnings {
+		model.addNotice("config: " + w.String())
+	}
+	model.syncStore()
+	model.updateTmuxNotifications()
 
 	// Resize the manager pane if possible
 	if cfg.UI.ManagerWidth > 0 && codelyPaneID >= 0 {
@@ -95,9 +160,35 @@ func Run(cfg *config.Config, storePath string, debugMode bool, debugFile string,
 		debug.Log("initial resize: paneID=%d width=%d", codelyPaneID, cfg.UI.ManagerWidth)
 	}
 
+	// Expose the control socket; failure is not fatal, the TUI still works.
+	// It listens before the program is created, which copies the model, so
+	// a failure notice is shown. Requests are served once p is set.
+	var p *tea.Program
+	var srv *control.Server
+	if opts.SocketPath != "" {
+		var err error
+		srv, err = control.Listen(opts.SocketPath, func(req control.Request) control.Response {
+			return controlHandler(p)(req)
+		})
+		if err != nil {
+			debug.Log("control socket disabled: %v", err)
+			model.addNotice(fmt.Sprintf("control socket disabled: %v", err))
+		} else {
+			debug.Log("control socket: %s", srv.Path())
+			defer srv.Close()
+		}
+	}
+
 	// Run Bubble Tea program
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	p = tea.NewProgram(model, tea.WithAltScreen())
+	if srv != nil {
+		go srv.Serve()
+	}
+
 	finalModel, err := p.Run()
+	if opts.EventsPath != "" {
+		logShutdown(st, opts.EventsPath)
+	}
 	if err != nil {
 		return fmt.Errorf("running TUI: %w", err)
 	}
@@ -111,3 +202,65 @@ func Run(cfg *config.Config, storePath string, debugMode bool, debugFile string,
 
 	return nil
 }
+
+// resolveSkin picks the skin: an explicit override wins over ui.skin, and
+// the tree skin is the default.
+func resolveSkin(override SkinName, cfg *config.Config) SkinName {
+	if override != "" {
+		return override
+	}
+	if cfg.UI.Skin != "" {
+		return SkinName(cfg.UI.Skin)
+	}
+	return SkinTree
+}
+
+// bootstrapTmux creates the named tmux session running exe with args if it
+// does not exist yet, then attaches the terminal to it.
+func bootstrapTmux(tmuxClient tmux.Client, sessionName, exe string, args []string) error {
+	if !tmuxClient.HasSession(sessionName) {
+		debug.Log("bootstrap: creating tmux session %q", sessionName)
+		if err := tmuxClient.CreateSession(sessionName, exe, args...); err != nil {
+			return fmt.Errorf("creating tmux session %q: %w", sessionName, err)
+		}
+	}
+
+	debug.Log("bootstrap: attaching to tmux session %q", sessionName)
+	if err := tmuxClient.AttachSession(sessionName); err != nil {
+		return fmt.Errorf("attaching to tmux session %q: %w", sessionName, err)
+	}
+	return nil
+}
+
+// orphanNotice describes codely-tagged panes that no stored session claims,
+// e.g. panes left behind by a state file that was reset or edited.
+func orphanNotice(orphans []tmux.PaneInfo, st *store.Store) string {
+	if len(orphans) == 0 {
+		return ""
+	}
+
+	labels := make([]string, 0, len(orphans))
+	for _, p := range orphans {
+		label := fmt.Sprintf("%%%d", p.ID)
+		if proj, err := st.GetProject(p.ProjectID); err == nil {
+			label += " (" + proj.Name + ")"
+		}
+		labels = append(labels, label)
+	}
+
+	return fmt.Sprintf("%d orphaned codely pane(s) not in saved state: %s", len(orphans), strings.Join(labels, ", "))
+}
+
+// loadFrecency loads the folder open history. A history that cannot be read
+// starts empty.
+func loadFrecency(path string) *frecency.Store {
+	if path == "" {
+		return nil
+	}
+	fs := frecency.New(path)
+	if err := fs.Load(); err != nil {
+		debug.Log("loading frecency: %v", err)
+		return frecency.New(path)
+	}
+	return fs
+}
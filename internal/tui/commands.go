@@ -1,19 +1,22 @@
 package tui
 
 import (
-	"os"
+	"context"
+	"fmt"
 	"path/filepath"
-	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/google/uuid"
 
 	"github.com/charliek/codely/internal/debug"
+	"github.com/charliek/codely/internal/discovery"
 	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/launch"
 	"github.com/charliek/codely/internal/shed"
 	"github.com/charliek/codely/internal/status"
 	"github.com/charliek/codely/internal/tmux"
+	"github.com/charliek/codely/internal/workspace"
 )
 
 // statusPollCmd returns a command that polls status after the configured interval
@@ -26,94 +29,72 @@ func (m *Model) statusPollCmd() tea.Cmd {
 // pollStatusCmd captures pane content and detects status for all sessions
 func (m *Model) pollStatusCmd() tea.Cmd {
 	return func() tea.Msg {
-		updates := make(map[string]domain.Status)
-		exitCodes := make(map[string]*int)
-
-		panes, listErr := m.tmux.ListPanes()
-		paneMap := make(map[int]tmux.PaneInfo)
-		if listErr == nil {
-			for _, p := range panes {
-				paneMap[p.ID] = p
-			}
+		snap := status.Collect(m.tmux, m.store.Projects(), m.detectionMode)
+		for _, paneID := range snap.CleanExits {
+			_ = m.tmux.KillPane(paneID)
 		}
 
-		for _, proj := range m.store.Projects() {
-			for _, sess := range proj.Sessions {
-				if sess.PaneID == 0 {
-					continue
-				}
-
-				if listErr == nil {
-					pane, ok := paneMap[sess.PaneID]
-					if !ok {
-						updates[sess.ID] = domain.StatusExited
-						exitCodes[sess.ID] = nil
-						continue
-					}
+		debug.Log("pollStatus: sessions=%d cleanExits=%d", len(snap.Updates), len(snap.CleanExits))
+		return StatusUpdateMsg{Updates: snap.Updates, ExitCodes: snap.ExitCodes, Dirs: snap.Dirs}
+	}
+}
 
-					if pane.Dead {
-						if pane.DeadCode != nil && *pane.DeadCode != 0 {
-							updates[sess.ID] = domain.StatusError
-							exitCodes[sess.ID] = pane.DeadCode
-							continue
-						}
-
-						updates[sess.ID] = domain.StatusExited
-						exitCodes[sess.ID] = pane.DeadCode
-						_ = m.tmux.KillPane(sess.PaneID)
-						continue
-					}
-				}
+// detectionMode returns the configured status_detection mode for a session.
+func (m *Model) detectionMode(sess *domain.Session) string {
+	return m.config.DetectionMode(sess.Command)
+}
 
-				content, capErr := m.tmux.CapturePane(sess.PaneID, 15)
-				if capErr != nil {
-					updates[sess.ID] = domain.StatusError
-					continue
-				}
+// discoveryOptions returns the folder scan options for the current config
+func (m *Model) discoveryOptions() discovery.Options {
+	return discovery.Options{
+		Roots:    m.config.WorkspaceRoots,
+		MaxDepth: m.config.Discovery.MaxDepth,
+		Ignore:   m.config.Discovery.Ignore,
+	}
+}
 
-				detectionMode := ""
-				if cmdCfg, ok := m.config.Commands[sess.Command.ID]; ok {
-					detectionMode = cmdCfg.StatusDetection
-				}
-				updates[sess.ID] = status.DetectWithMode(content, sess.Command.ID, sess.Command.Exec, detectionMode)
-			}
+// loadCachedFoldersCmd loads the folders found by the last scan, so the
+// picker is populated while a fresh scan runs
+func (m *Model) loadCachedFoldersCmd() tea.Cmd {
+	if m.folderCachePath == "" {
+		return nil
+	}
+	path := m.folderCachePath
+	opts := m.discoveryOptions()
+	return func() tea.Msg {
+		folders, ok := discovery.LoadCache(path, opts)
+		if !ok {
+			return nil
 		}
-
-		debug.Log("pollStatus: panes=%d sessions=%d", len(paneMap), len(updates))
-		return StatusUpdateMsg{Updates: updates, ExitCodes: exitCodes}
+		return FoldersLoadedMsg{Folders: folders, Options: opts, Cached: true}
 	}
 }
 
-// loadFoldersCmd loads available folders from workspace roots
-func (m *Model) loadFoldersCmd() tea.Cmd {
+// scanFoldersCmd scans the workspace roots in the background and updates
+// the cache
+func (m *Model) scanFoldersCmd() tea.Cmd {
+	path := m.folderCachePath
+	opts := m.discoveryOptions()
 	return func() tea.Msg {
-		var folders []string
-
-		for _, root := range m.config.WorkspaceRoots {
-			// Expand ~
-			if strings.HasPrefix(root, "~") {
-				home, err := os.UserHomeDir()
-				if err != nil {
-					continue
-				}
-				root = home + root[1:]
-			}
-
-			// Read directory entries
-			entries, err := os.ReadDir(root)
-			if err != nil {
-				continue
-			}
-
-			for _, entry := range entries {
-				if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
-					folders = append(folders, filepath.Join(root, entry.Name()))
-				}
+		start := time.Now()
+		folders := discovery.Scan(opts)
+		debug.Log("scanFolders: folders=%d took=%s", len(folders), time.Since(start))
+		if path != "" {
+			if err := discovery.SaveCache(path, opts, folders); err != nil {
+				debug.Log("scanFolders: %v", err)
 			}
 		}
+		return FoldersLoadedMsg{Folders: folders, Options: opts}
+	}
+}
 
-		return FoldersLoadedMsg{Folders: folders}
+// refreshFoldersCmd starts a background scan unless one is running
+func (m *Model) refreshFoldersCmd() tea.Cmd {
+	if m.foldersScanning {
+		return nil
 	}
+	m.foldersScanning = true
+	return m.scanFoldersCmd()
 }
 
 // loadShedsCmd loads available sheds
@@ -149,14 +130,17 @@ func (m *Model) createPaneCmd(project *domain.Project, session *domain.Session)
 	sessionID := session.ID
 	projectType := project.Type
 	projectDir := project.Directory
+	if session.WorkDir != "" {
+		// A restored session starts where it was last working
+		projectDir = session.WorkDir
+	}
 	shedName := project.ShedName
-	cmdExec := session.Command.Exec
-	cmdArgs := session.Command.Args
+	command := session.Command
+	vars := launch.NewVars(project, session)
 	currentManagerWidth := m.managerWidth
 
 	return func() tea.Msg {
 		var paneID int
-		var err error
 		var hiddenProjectID string
 		var hiddenSessionID string
 		var hiddenPaneID int
@@ -164,15 +148,29 @@ func (m *Model) createPaneCmd(project *domain.Project, session *domain.Session)
 
 		debug.Log("createPane: project=%s session=%s codelyPaneID=%d managerWidth=%d", projectID, sessionID, m.codelyPaneID, currentManagerWidth)
 
-		// Determine the directory and command
+		// Expand template placeholders in the command's args and env
+		resolved, err := launch.Resolve(command, vars)
+		if err != nil {
+			return PaneCreatedMsg{
+				ProjectID: projectID,
+				SessionID: sessionID,
+				Err:       fmt.Errorf("command %s: %w", command.ID, err),
+			}
+		}
+		debug.Log("createPane: exec=%s args=%v env=%v", resolved.Exec, resolved.Args, resolved.Redacted())
+
+		// Determine the directory and command. Local panes get env from
+		// tmux; shed sessions load it from a file written into the shed.
 		var dir string
 		var execCmd string
 		var execArgs []string
+		var paneEnv []string
 
 		if projectType == domain.ProjectTypeLocal {
 			dir = projectDir
-			execCmd = cmdExec
-			execArgs = cmdArgs
+			execCmd = resolved.Exec
+			execArgs = resolved.Args
+			paneEnv = resolved.EnvList()
 		} else {
 			// Shed project: use shed exec
 			if m.shed == nil {
@@ -183,9 +181,23 @@ func (m *Model) createPaneCmd(project *domain.Project, session *domain.Session)
 				}
 			}
 
+			// Env values go through a file so they stay out of the
+			// pane's command line
+			var envFile string
+			if env := resolved.EnvList(); len(env) > 0 {
+				envFile, err = m.shed.WriteEnvFile(shedName, env)
+				if err != nil {
+					return PaneCreatedMsg{
+						ProjectID: projectID,
+						SessionID: sessionID,
+						Err:       fmt.Errorf("command %s: %w", command.ID, err),
+					}
+				}
+			}
+
 			// Build the command for shed exec, keeping args separate so
 			// shellQuoteCommand quotes each arg individually.
-			cmd := m.shed.ExecCommand(shedName, cmdExec, cmdArgs...)
+			cmd := m.shed.ExecCommand(shedName, envFile, resolved.Exec, resolved.Args...)
 			execCmd = cmd.Args[0]
 			execArgs = cmd.Args[1:]
 			dir = ""
@@ -269,13 +281,17 @@ func (m *Model) createPaneCmd(project *domain.Project, session *domain.Session)
 
 		// Split from Codely's pane (horizontal split to the right)
 		if m.codelyPaneID >= 0 {
-			paneID, err = m.tmux.SplitPane(m.codelyPaneID, false, dir, execCmd, execArgs...)
+			paneID, err = m.tmux.SplitPane(m.codelyPaneID, false, dir, paneEnv, execCmd, execArgs...)
 		} else {
-			paneID, err = m.tmux.SplitWindow(dir, execCmd, execArgs...)
+			paneID, err = m.tmux.SplitWindow(dir, paneEnv, execCmd, execArgs...)
 		}
 		debug.Log("createPane: SplitPane(%d) newPaneID=%d err=%v", m.codelyPaneID, paneID, err)
 		if paneID > 0 {
 			_ = m.tmux.SetRemainOnExit(paneID, true)
+			// Tag the pane so the session can be reattached after a restart
+			_ = m.tmux.SetPaneOption(paneID, tmux.SessionIDOption, sessionID)
+			_ = m.tmux.SetPaneOption(paneID, tmux.ProjectIDOption, projectID)
+			_ = m.tmux.SetPaneOption(paneID, tmux.WorkspaceOption, m.workspace.Name)
 		}
 
 		// Restore codely pane to its previous width (tmux defaults to 50/50 on split)
@@ -312,6 +328,18 @@ func (m *Model) killPaneCmd(project *domain.Project, session *domain.Session) te
 	}
 }
 
+// sendKeysCmd types text into a pane, optionally followed by Enter
+func (m *Model) sendKeysCmd(paneID int, text string, enter bool) tea.Cmd {
+	return func() tea.Msg {
+		err := m.tmux.SendKeys(paneID, text, enter)
+		debug.Log("sendKeys: paneID=%d len=%d enter=%v err=%v", paneID, len(text), enter, err)
+		if err != nil {
+			return ErrorMsg{Err: fmt.Errorf("sending input: %w", err)}
+		}
+		return nil
+	}
+}
+
 // focusPaneCmd focuses a tmux pane
 func (m *Model) focusPaneCmd(paneID int) tea.Cmd {
 	return func() tea.Msg {
@@ -545,6 +573,36 @@ func waitForShedOutput(name string, outputCh <-chan string, doneCh <-chan error)
 	}
 }
 
+// cloneRepoCmd starts a streaming clone and returns a cloneStartedMsg.
+func (m *Model) cloneRepoCmd(ctx context.Context, url, dir string) tea.Cmd {
+	return func() tea.Msg {
+		cmdLine, outputCh, doneCh := m.git.CloneStreaming(ctx, url, dir)
+		return cloneStartedMsg{
+			dir:      dir,
+			cmdLine:  cmdLine,
+			outputCh: outputCh,
+			doneCh:   doneCh,
+		}
+	}
+}
+
+// waitForCloneOutput reads one line from outputCh or the final result from doneCh.
+func waitForCloneOutput(dir string, outputCh <-chan string, doneCh <-chan error) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-outputCh
+		if ok {
+			return cloneOutputMsg{
+				line:     line,
+				dir:      dir,
+				outputCh: outputCh,
+				doneCh:   doneCh,
+			}
+		}
+		err := <-doneCh
+		return RepoClonedMsg{Directory: dir, Err: err}
+	}
+}
+
 // deleteShedCmd deletes a shed
 func (m *Model) deleteShedCmd(name string, force bool) tea.Cmd {
 	return func() tea.Msg {
@@ -597,13 +655,37 @@ func newSession(projectID string, cmdID string, cmd domain.Command) *domain.Sess
 		ID:        uuid.New().String(),
 		ProjectID: projectID,
 		Command: domain.Command{
-			ID:          cmdID,
-			DisplayName: cmd.DisplayName,
-			Exec:        cmd.Exec,
-			Args:        cmd.Args,
-			Env:         cmd.Env,
+			ID:              cmdID,
+			DisplayName:     cmd.DisplayName,
+			Exec:            cmd.Exec,
+			Args:            cmd.Args,
+			Env:             cmd.Env,
+			EnvFrom:         cmd.EnvFrom,
+			EnvFile:         cmd.EnvFile,
+			StatusDetection: cmd.StatusDetection,
 		},
 		Status:    domain.StatusUnknown,
 		StartedAt: time.Now(),
 	}
 }
+
+// switchWorkspaceCmd starts codely in the workspace's tmux session if
+// needed and switches the client to it
+func (m *Model) switchWorkspaceCmd(ws workspace.Workspace) tea.Cmd {
+	exe, args := m.codelyExe, m.codelyArgs
+	return func() tea.Msg {
+		if !m.tmux.HasSession(ws.TmuxSession) {
+			args := append(append([]string{}, args...), "--workspace", ws.Name)
+			debug.Log("switchWorkspace: creating tmux session %q", ws.TmuxSession)
+			if err := m.tmux.CreateSession(ws.TmuxSession, exe, args...); err != nil {
+				return WorkspaceSwitchedMsg{Name: ws.Name, Err: fmt.Errorf("starting workspace %s: %w", ws.Name, err)}
+			}
+		}
+		err := m.tmux.SwitchClient(ws.TmuxSession)
+		debug.Log("switchWorkspace: session=%q err=%v", ws.TmuxSession, err)
+		if err != nil {
+			err = fmt.Errorf("switching to workspace %s: %w", ws.Name, err)
+		}
+		return WorkspaceSwitchedMsg{Name: ws.Name, Err: err}
+	}
+}
@@ -0,0 +1,151 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/charliek/codely/internal/control"
+	"github.com/charliek/codely/internal/domain"
+)
+
+// controlReplyTimeout bounds how long a control request waits for the
+// Update loop, e.g. if the program is shutting down.
+const controlReplyTimeout = 5 * time.Second
+
+// controlRequestMsg carries a control socket request into the Update loop.
+// The response is delivered on reply, which must be buffered.
+type controlRequestMsg struct {
+	req   control.Request
+	reply chan<- control.Response
+}
+
+// controlHandler returns a control.Handler that forwards requests to the
+// running program so they go through the same Update paths as key presses.
+func controlHandler(p *tea.Program) control.Handler {
+	return func(req control.Request) control.Response {
+		reply := make(chan control.Response, 1)
+		p.Send(controlRequestMsg{req: req, reply: reply})
+		select {
+		case resp := <-reply:
+			return resp
+		case <-time.After(controlReplyTimeout):
+			return control.Errorf("timed out waiting for codely")
+		}
+	}
+}
+
+// handleControlRequest executes a control request and sends its response.
+func (m Model) handleControlRequest(msg controlRequestMsg) (tea.Model, tea.Cmd) {
+	resp, cmd := m.executeControlRequest(msg.req)
+	msg.reply <- resp
+	return m, cmd
+}
+
+func (m *Model) executeControlRequest(req control.Request) (control.Response, tea.Cmd) {
+	switch req.Action {
+	case control.ActionList:
+		return control.Response{OK: true, Sessions: m.controlSessions()}, nil
+
+	case control.ActionFocus:
+		proj, sess := m.findSession(req.SessionID)
+		if sess == nil {
+			return control.Errorf("session %q not found", req.SessionID), nil
+		}
+		if sess.PaneID == 0 {
+			return control.Errorf("session %q has no pane", req.SessionID), nil
+		}
+		m.skin.SelectBySessionID(proj.ID, sess.ID)
+		return control.Response{OK: true, SessionID: sess.ID}, m.focusSessionCmd(proj, sess)
+
+	case control.ActionSpawn:
+		proj := m.findProject(req.Project)
+		if proj == nil {
+			return control.Errorf("project %q not found", req.Project), nil
+		}
+		cfg := m.projectConfig(proj)
+		cmdID := req.Command
+		if cmdID == "" {
+			cmdID = cfg.DefaultCommand
+		}
+		if _, ok := cfg.Commands[cmdID]; !ok {
+			return control.Errorf("command %q not configured", cmdID), nil
+		}
+		sess, cmd := m.launchSession(proj, cmdID)
+		return control.Response{OK: true, SessionID: sess.ID}, cmd
+
+	case control.ActionClose:
+		proj, sess := m.findSession(req.SessionID)
+		if sess == nil {
+			return control.Errorf("session %q not found", req.SessionID), nil
+		}
+		return control.Response{OK: true, SessionID: req.SessionID}, m.closeSession(proj, sess)
+
+	case control.ActionRename:
+		proj, sess := m.findSession(req.SessionID)
+		if sess == nil {
+			return control.Errorf("session %q not found", req.SessionID), nil
+		}
+		m.renameSession(proj, sess, req.Name)
+		return control.Response{OK: true, SessionID: sess.ID}, nil
+
+	case control.ActionSend:
+		_, sess := m.findSession(req.SessionID)
+		if sess == nil {
+			return control.Errorf("session %q not found", req.SessionID), nil
+		}
+		if sess.PaneID == 0 {
+			return control.Errorf("session %q has no pane", req.SessionID), nil
+		}
+		if req.Text == "" && !req.Enter {
+			return control.Errorf("nothing to send"), nil
+		}
+		return control.Response{OK: true, SessionID: sess.ID}, m.sendKeysCmd(sess.PaneID, req.Text, req.Enter)
+	}
+
+	return control.Errorf("unknown action %q", req.Action), nil
+}
+
+// controlSessions lists all sessions for a control list response.
+func (m *Model) controlSessions() []control.SessionInfo {
+	sessions := []control.SessionInfo{}
+	for _, proj := range m.store.Projects() {
+		for _, sess := range proj.Sessions {
+			sessions = append(sessions, control.SessionInfo{
+				ProjectID: proj.ID,
+				Project:   proj.Name,
+				SessionID: sess.ID,
+				Name:      sess.Command.Name(),
+				Command:   sess.Command.ID,
+				Status:    sess.Status,
+				PaneID:    sess.PaneID,
+				Visible:   sess.IsVisible,
+			})
+		}
+	}
+	return sessions
+}
+
+// findSession returns the session with the given ID and its project.
+func (m *Model) findSession(sessionID string) (*domain.Project, *domain.Session) {
+	if sessionID == "" {
+		return nil, nil
+	}
+	for _, proj := range m.store.Projects() {
+		for i := range proj.Sessions {
+			if proj.Sessions[i].ID == sessionID {
+				return proj, &proj.Sessions[i]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// findProject returns the project matching an ID or, failing that, a name.
+func (m *Model) findProject(ref string) *domain.Project {
+	proj, err := m.store.FindProject(ref)
+	if err != nil {
+		return nil
+	}
+	return proj
+}
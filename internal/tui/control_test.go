@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/codely/internal/control"
+	"github.com/charliek/codely/internal/tmux"
+)
+
+func controlRequest(t *testing.T, m Model, req control.Request) (Model, control.Response) {
+	t.Helper()
+
+	reply := make(chan control.Response, 1)
+	updated, _ := m.Update(controlRequestMsg{req: req, reply: reply})
+	return updated.(Model), <-reply
+}
+
+func TestControlList(t *testing.T) {
+	model, _ := renameTestModel(t, SkinTree, "claude", "Claude Code")
+
+	_, resp := controlRequest(t, model, control.Request{Action: control.ActionList})
+
+	assert.True(t, resp.OK)
+	require.Len(t, resp.Sessions, 1)
+	assert.Equal(t, "sess-1", resp.Sessions[0].SessionID)
+	assert.Equal(t, "project", resp.Sessions[0].Project)
+	assert.Equal(t, "Claude Code", resp.Sessions[0].Name)
+}
+
+func TestControlSpawnByProjectName(t *testing.T) {
+	model, st := renameTestModel(t, SkinTree, "claude", "Claude Code")
+
+	_, resp := controlRequest(t, model, control.Request{Action: control.ActionSpawn, Project: "project", Command: "bash"})
+
+	require.True(t, resp.OK, resp.Error)
+	proj, err := st.GetProject("proj-1")
+	require.NoError(t, err)
+	require.Len(t, proj.Sessions, 2)
+	assert.Equal(t, resp.SessionID, proj.Sessions[1].ID)
+	assert.Equal(t, "bash", proj.Sessions[1].Command.ID)
+}
+
+func TestControlSpawnErrors(t *testing.T) {
+	model, _ := renameTestModel(t, SkinTree, "claude", "Claude Code")
+
+	_, resp := controlRequest(t, model, control.Request{Action: control.ActionSpawn, Project: "missing"})
+	assert.False(t, resp.OK)
+	assert.Contains(t, resp.Error, "project")
+
+	_, resp = controlRequest(t, model, control.Request{Action: control.ActionSpawn, Project: "proj-1", Command: "nope"})
+	assert.False(t, resp.OK)
+	assert.Contains(t, resp.Error, "command")
+}
+
+func TestControlRenameAndClose(t *testing.T) {
+	model, st := renameTestModel(t, SkinTree, "claude", "Claude Code")
+
+	model, resp := controlRequest(t, model, control.Request{Action: control.ActionRename, SessionID: "sess-1", Name: "reviewer"})
+	require.True(t, resp.OK, resp.Error)
+	proj, _ := st.GetProject("proj-1")
+	assert.Equal(t, "reviewer", proj.Sessions[0].Command.DisplayName)
+
+	_, resp = controlRequest(t, model, control.Request{Action: control.ActionClose, SessionID: "sess-1"})
+	require.True(t, resp.OK, resp.Error)
+	proj, _ = st.GetProject("proj-1")
+	assert.Empty(t, proj.Sessions)
+}
+
+func TestControlUnknownAction(t *testing.T) {
+	model, _ := renameTestModel(t, SkinTree, "claude", "Claude Code")
+
+	_, resp := controlRequest(t, model, control.Request{Action: "explode"})
+
+	assert.False(t, resp.OK)
+	assert.Contains(t, resp.Error, "unknown action")
+}
+
+func TestControlSend(t *testing.T) {
+	model, st := renameTestModel(t, SkinTree, "claude", "Claude Code")
+	mock := model.tmux.(*tmux.MockClient)
+
+	_, resp := controlRequest(t, model, control.Request{Action: control.ActionSend, SessionID: "sess-1", Text: "continue"})
+	assert.False(t, resp.OK)
+	assert.Contains(t, resp.Error, "no pane")
+
+	sess, err := st.GetSession("proj-1", "sess-1")
+	require.NoError(t, err)
+	sess.PaneID = 7
+
+	_, resp = controlRequest(t, model, control.Request{Action: control.ActionSend, SessionID: "sess-1"})
+	assert.False(t, resp.OK)
+	assert.Contains(t, resp.Error, "nothing to send")
+
+	reply := make(chan control.Response, 1)
+	_, cmd := model.Update(controlRequestMsg{
+		req:   control.Request{Action: control.ActionSend, SessionID: "sess-1", Text: "continue", Enter: true},
+		reply: reply,
+	})
+	resp = <-reply
+	require.True(t, resp.OK, resp.Error)
+	require.NotNil(t, cmd)
+	assert.Nil(t, cmd())
+
+	require.NotEmpty(t, mock.Calls)
+	last := mock.Calls[len(mock.Calls)-1]
+	assert.Equal(t, "SendKeys", last.Method)
+	assert.Equal(t, []interface{}{7, "continue", true}, last.Args)
+}
@@ -0,0 +1,67 @@
+package tui
+
+import "strings"
+
+// Fuzzy match scoring
+const (
+	fuzzyMatchScore   = 1       // Each matched character
+	fuzzyConsecutive  = 5       // Match directly after the previous match
+	fuzzySegmentStart = 6       // Match at the start of a path segment or word
+	fuzzyBasename     = 2       // Match within the last path segment
+	fuzzyGapPenalty   = 2       // Each run of skipped characters
+	fuzzyBoundaries   = "/-_. " // Characters that end a segment or word
+)
+
+// fuzzyMatch reports whether the characters of query appear in order in
+// text, ignoring case and spaces in the query, and scores the match. Matches
+// that are consecutive, start path segments or fall in the last segment
+// score higher.
+func fuzzyMatch(text, query string) (int, bool) {
+	q := []rune(strings.ToLower(strings.ReplaceAll(query, " ", "")))
+	if len(q) == 0 {
+		return 0, true
+	}
+	t := []rune(strings.ToLower(text))
+	base := strings.LastIndex(strings.TrimRight(text, "/"), "/") + 1
+	base = len([]rune(text[:base]))
+
+	best, found := 0, false
+	for start := range t {
+		if t[start] != q[0] {
+			continue
+		}
+		score, ok := fuzzyScoreFrom(t, q, start, base)
+		if ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	return best, found
+}
+
+// fuzzyScoreFrom greedily matches q in t with q[0] at start
+func fuzzyScoreFrom(t, q []rune, start, base int) (int, bool) {
+	score := 0
+	prev := -1
+	qi := 0
+	for i := start; i < len(t) && qi < len(q); i++ {
+		if t[i] != q[qi] {
+			continue
+		}
+		score += fuzzyMatchScore
+		switch {
+		case prev >= 0 && i == prev+1:
+			score += fuzzyConsecutive
+		case prev >= 0:
+			score -= fuzzyGapPenalty
+		}
+		if i == 0 || strings.ContainsRune(fuzzyBoundaries, t[i-1]) {
+			score += fuzzySegmentStart
+		}
+		if i >= base {
+			score += fuzzyBasename
+		}
+		prev = i
+		qi++
+	}
+	return score, qi == len(q)
+}
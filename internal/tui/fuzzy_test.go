@@ -0,0 +1,32 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	_, ok := fuzzyMatch("~/src/codely", "cdy")
+	assert.True(t, ok)
+	_, ok = fuzzyMatch("~/src/codely", "CODE")
+	assert.True(t, ok, "matching ignores case")
+	_, ok = fuzzyMatch("~/src/codely", "src cod")
+	assert.True(t, ok, "spaces in the query are ignored")
+	_, ok = fuzzyMatch("~/src/codely", "ylc")
+	assert.False(t, ok, "characters must appear in order")
+
+	score, ok := fuzzyMatch("~/src/anything", "")
+	assert.True(t, ok)
+	assert.Zero(t, score)
+
+	// Consecutive matches in the last segment beat scattered ones
+	base, _ := fuzzyMatch("~/src/api", "api")
+	scattered, _ := fuzzyMatch("~/src/a-platform-index", "api")
+	assert.Greater(t, base, scattered)
+
+	// Segment starts beat mid-word matches
+	initials, _ := fuzzyMatch("~/src/web-app", "wa")
+	midWord, _ := fuzzyMatch("~/src/sandwich", "wa")
+	assert.Greater(t, initials, midWord)
+}
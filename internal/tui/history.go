@@ -0,0 +1,288 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+
+	"github.com/charliek/codely/internal/debug"
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/history"
+	"github.com/charliek/codely/internal/pathutil"
+)
+
+// History view limits
+const (
+	historyOutputLines = 40 // Pane lines archived with each session
+	historyRows        = 15 // Entries shown at once
+	historyDetailLines = 12 // Output lines shown for the selected entry
+)
+
+// archiveSessions records sessions that are about to be removed, with the
+// tail of their pane output. Sessions must be copies or still in the store.
+func (m *Model) archiveSessions(proj *domain.Project, sessions ...domain.Session) {
+	if m.historyPath == "" || len(sessions) == 0 {
+		return
+	}
+
+	now := time.Now()
+	entries := make([]history.Entry, 0, len(sessions))
+	for i := range sessions {
+		sess := &sessions[i]
+		e := history.NewEntry(proj, sess, now)
+		if sess.PaneID > 0 {
+			if out, err := m.tmux.CapturePane(sess.PaneID, historyOutputLines); err == nil {
+				e.Output = outputTail(out, historyOutputLines)
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	if err := history.Append(m.historyPath, entries...); err != nil {
+		m.addNotice(fmt.Sprintf("history: %v", err))
+	}
+}
+
+// archiveLost records sessions that were dropped at startup because their
+// panes no longer exist. before holds each project's sessions as loaded.
+func (m *Model) archiveLost(before map[string][]domain.Session) {
+	if m.historyPath == "" {
+		return
+	}
+
+	now := time.Now()
+	var entries []history.Entry
+	for _, l := range m.droppedSessions(before) {
+		e := history.NewEntry(l.Project, &l.Session, now)
+		e.Reason = history.ReasonLost
+		entries = append(entries, e)
+	}
+
+	if err := history.Append(m.historyPath, entries...); err != nil {
+		m.addNotice(fmt.Sprintf("history: %v", err))
+	}
+}
+
+// outputTail returns the last n non-blank-trailing lines of captured output
+func outputTail(out string, n int) string {
+	lines := strings.Split(strings.TrimRight(out, "\n "), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// openHistory loads the archive and shows the history view
+func (m *Model) openHistory() {
+	entries, err := history.Load(m.historyPath)
+	if err != nil {
+		m.addNotice(fmt.Sprintf("history: %v", err))
+	}
+	m.historyEntries = entries
+	m.historyIdx = 0
+	m.historyDetail = false
+	m.historySearching = false
+	m.historySearch.SetValue("")
+	m.historySearch.Blur()
+	m.mode = ModeHistory
+}
+
+// filteredHistory returns the archive entries matching the filter text
+func (m *Model) filteredHistory() []history.Entry {
+	if text := m.historySearch.Value(); text != "" {
+		return history.Filter{Text: text}.Apply(m.historyEntries)
+	}
+	return m.historyEntries
+}
+
+// handleHistoryKey handles keys in the history view
+func (m Model) handleHistoryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	entries := m.filteredHistory()
+
+	if m.historySearching {
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.historySearching = false
+			m.historySearch.SetValue("")
+			m.historySearch.Blur()
+			m.historyIdx = 0
+			return m, nil
+		case tea.KeyEnter, tea.KeyDown, tea.KeyUp:
+			// Keep the filter and return to navigation
+			m.historySearching = false
+			m.historySearch.Blur()
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.historySearch, cmd = m.historySearch.Update(msg)
+		m.historyIdx = 0
+		return m, cmd
+	}
+
+	switch {
+	case msg.Type == tea.KeyEsc, key.Matches(msg, m.keys.Quit), key.Matches(msg, m.keys.History):
+		m.mode = ModeNormal
+		m.historyEntries = nil
+		return m, nil
+
+	case key.Matches(msg, m.keys.Up):
+		if m.historyIdx > 0 {
+			m.historyIdx--
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Down):
+		if m.historyIdx < len(entries)-1 {
+			m.historyIdx++
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Search):
+		m.historySearching = true
+		m.historySearch.Focus()
+		return m, nil
+
+	case key.Matches(msg, m.keys.Space):
+		m.historyDetail = !m.historyDetail
+		return m, nil
+
+	case key.Matches(msg, m.keys.Enter):
+		if m.historyIdx < len(entries) {
+			return m.relaunchHistory(entries[m.historyIdx])
+		}
+	}
+
+	return m, nil
+}
+
+// relaunchHistory starts the archived session's command again in its
+// project. A project that has since been closed is recreated.
+func (m Model) relaunchHistory(e history.Entry) (tea.Model, tea.Cmd) {
+	proj := m.historyProject(e)
+	if proj == nil {
+		proj = &domain.Project{
+			ID:         uuid.New().String(),
+			Name:       e.ProjectName,
+			Type:       e.ProjectType,
+			Directory:  e.Directory,
+			ShedName:   e.ShedName,
+			ShedServer: e.ShedServer,
+			Sessions:   []domain.Session{},
+			Expanded:   true,
+		}
+		_ = m.store.AddProject(proj)
+	}
+
+	if _, ok := m.projectConfig(proj).Commands[e.Command.ID]; !ok {
+		m.err = fmt.Errorf("command %q is no longer configured", e.Command.ID)
+		return m, nil
+	}
+
+	debug.Log("relaunchHistory: session=%s project=%s command=%s", e.SessionID, proj.Name, e.Command.ID)
+	launched, cmd := m.launchSession(proj, e.Command.ID)
+	if sess, err := m.store.GetSession(proj.ID, launched.ID); err == nil && e.Command.DisplayName != "" {
+		// Keep a custom name the session was given
+		m.renameSession(proj, sess, e.Command.DisplayName)
+	}
+	m.mode = ModeNormal
+	m.historyEntries = nil
+	return m, cmd
+}
+
+// historyProject finds the open project an archived session belonged to, by
+// ID or else by directory or shed
+func (m *Model) historyProject(e history.Entry) *domain.Project {
+	if proj, err := m.store.GetProject(e.ProjectID); err == nil {
+		return proj
+	}
+	for _, p := range m.store.Projects() {
+		switch {
+		case p.Type != e.ProjectType:
+		case p.Type == domain.ProjectTypeShed && p.ShedName == e.ShedName && p.ShedServer == e.ShedServer:
+			return p
+		case p.Type == domain.ProjectTypeLocal && p.Directory == e.Directory:
+			return p
+		}
+	}
+	return nil
+}
+
+// historyView renders the history view
+func (m Model) historyView() string {
+	var b strings.Builder
+
+	b.WriteString(styleDialogTitle.Render("Session History"))
+	b.WriteString("\n\n")
+
+	entries := m.filteredHistory()
+	if len(entries) == 0 {
+		if len(m.historyEntries) == 0 {
+			b.WriteString(styleHelp.Render("No closed sessions yet."))
+		} else {
+			b.WriteString(styleHelp.Render("No sessions match the filter."))
+		}
+		b.WriteString("\n\n")
+	}
+
+	// Scroll so the selection stays visible
+	start := max(0, min(m.historyIdx-historyRows/2, len(entries)-historyRows))
+	end := min(len(entries), start+historyRows)
+	for i := start; i < end; i++ {
+		e := entries[i]
+		line := fmt.Sprintf("%s  %-16s %-14s %-8s %s",
+			e.EndedAt.Local().Format("Jan 02 15:04"),
+			truncate(e.ProjectName, 16),
+			truncate(e.Command.Name(), 14),
+			e.Result(),
+			history.FormatDuration(e.Duration()))
+		if i == m.historyIdx {
+			b.WriteString(styleDialogOptionSelected.Render("● " + line))
+		} else {
+			b.WriteString(styleDialogOption.Render("○ " + line))
+		}
+		b.WriteString("\n")
+	}
+
+	if m.historyDetail && m.historyIdx < len(entries) {
+		e := entries[m.historyIdx]
+		b.WriteString("\n")
+		path := pathutil.ContractHome(e.Directory)
+		if e.ProjectType == domain.ProjectTypeShed {
+			path = "shed:" + e.ShedName
+		}
+		b.WriteString(styleProjectPath.Render(path))
+		b.WriteString("\n")
+		output := outputTail(e.Output, historyDetailLines)
+		if output == "" {
+			output = "(no output captured)"
+		}
+		for _, line := range strings.Split(output, "\n") {
+			b.WriteString(styleProjectPath.Render("> " + line))
+			b.WriteString("\n")
+		}
+	}
+
+	if m.historySearching || m.historySearch.Value() != "" {
+		b.WriteString("\n")
+		b.WriteString(m.historySearch.View())
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(styleHelp.Render("[/] filter  [space] output  [enter] relaunch  [esc] back"))
+
+	return styleDialog.Render(b.String())
+}
+
+// truncate shortens s to at most n runes, marking the cut with "…"
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
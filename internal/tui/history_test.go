@@ -0,0 +1,140 @@
+package tui
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/codely/internal/config"
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/history"
+	"github.com/charliek/codely/internal/shed"
+	"github.com/charliek/codely/internal/store"
+	"github.com/charliek/codely/internal/tmux"
+)
+
+func historyTestModel(t *testing.T) (Model, *store.Store, *tmux.MockClient) {
+	t.Helper()
+
+	st := store.New(t.TempDir() + "/state.json")
+	require.NoError(t, st.AddProject(&domain.Project{
+		ID:        "proj-1",
+		Name:      "api",
+		Type:      domain.ProjectTypeLocal,
+		Directory: t.TempDir(),
+		Expanded:  true,
+		Sessions: []domain.Session{{
+			ID:        "sess-1",
+			ProjectID: "proj-1",
+			Command:   domain.Command{ID: "claude", DisplayName: "refactor", Exec: "claude"},
+			PaneID:    7,
+			StartedAt: time.Now().Add(-time.Hour),
+		}},
+	}))
+
+	tmuxClient := tmux.NewMockClient()
+	tmuxClient.CapturePaneResult = "$ claude\nDone: 3 files changed\n\n\n"
+	model := NewModel(config.Default(), st, tmuxClient, shed.NewMockClient(), 0, "", SkinTree)
+	model.historyPath = filepath.Join(t.TempDir(), "history.jsonl")
+	return *model, st, tmuxClient
+}
+
+func TestCloseSessionArchivesHistory(t *testing.T) {
+	model, st, _ := historyTestModel(t)
+	proj, err := st.GetProject("proj-1")
+	require.NoError(t, err)
+
+	model.closeSession(proj, &proj.Sessions[0])
+	assert.Empty(t, proj.Sessions)
+
+	entries, err := history.Load(model.historyPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	e := entries[0]
+	assert.Equal(t, "sess-1", e.SessionID)
+	assert.Equal(t, "api", e.ProjectName)
+	assert.Equal(t, "refactor", e.Command.Name())
+	assert.Equal(t, history.ReasonClosed, e.Reason)
+	assert.Equal(t, "$ claude\nDone: 3 files changed", e.Output)
+	assert.InDelta(t, time.Hour.Seconds(), e.Duration().Seconds(), 5)
+}
+
+func TestArchiveLost(t *testing.T) {
+	model, st, _ := historyTestModel(t)
+	proj, err := st.GetProject("proj-1")
+	require.NoError(t, err)
+
+	before := map[string][]domain.Session{"proj-1": append([]domain.Session{}, proj.Sessions...)}
+	proj.Sessions = nil
+	model.archiveLost(before)
+
+	entries, err := history.Load(model.historyPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, history.ReasonLost, entries[0].Reason)
+	assert.Empty(t, entries[0].Output)
+}
+
+func TestHistoryViewFiltersAndRelaunches(t *testing.T) {
+	model, st, _ := historyTestModel(t)
+	proj, err := st.GetProject("proj-1")
+	require.NoError(t, err)
+	dir := proj.Directory
+	model.closeSession(proj, &proj.Sessions[0])
+	require.NoError(t, history.Append(model.historyPath, history.Entry{
+		SessionID:   "sess-old",
+		ProjectID:   "proj-gone",
+		ProjectName: "web",
+		ProjectType: domain.ProjectTypeLocal,
+		Directory:   "/src/web",
+		Command:     domain.Command{ID: "bash", Exec: "bash"},
+		EndedAt:     time.Now(),
+		Reason:      history.ReasonExited,
+	}))
+
+	updated, _ := model.handleNormalKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'H'}})
+	m := updated.(Model)
+	require.Equal(t, ModeHistory, m.mode)
+	assert.Len(t, m.filteredHistory(), 2)
+	assert.Contains(t, m.historyView(), "refactor")
+
+	// Filter down to the archived claude session
+	updated, _ = m.handleHistoryKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	m = updated.(Model)
+	for _, r := range "files changed" {
+		updated, _ = m.handleHistoryKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = updated.(Model)
+	}
+	updated, _ = m.handleHistoryKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	require.Len(t, m.filteredHistory(), 1)
+	assert.Equal(t, "sess-1", m.filteredHistory()[0].SessionID)
+
+	// Relaunching reuses the open project and keeps the session name
+	updated, cmd := m.handleHistoryKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	assert.NotNil(t, cmd)
+	assert.Equal(t, ModeNormal, m.mode)
+	require.Len(t, st.Projects(), 1)
+	proj, err = st.GetProject("proj-1")
+	require.NoError(t, err)
+	require.Len(t, proj.Sessions, 1)
+	assert.Equal(t, "refactor", proj.Sessions[0].Command.Name())
+	assert.Equal(t, dir, proj.Directory)
+
+	// A closed project is recreated from the archive
+	m.openHistory()
+	require.Equal(t, "sess-old", m.filteredHistory()[m.historyIdx].SessionID)
+	updated, _ = m.handleHistoryKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	require.Len(t, st.Projects(), 2)
+	web := st.Projects()[1]
+	assert.Equal(t, "web", web.Name)
+	assert.Equal(t, "/src/web", web.Directory)
+	require.Len(t, web.Sessions, 1)
+	assert.Equal(t, "bash", web.Sessions[0].Command.ID)
+}
@@ -16,11 +16,14 @@ type KeyMap struct {
 	NewProject  key.Binding
 	AddTerminal key.Binding
 	Rename      key.Binding
+	SendInput   key.Binding
 	Close       key.Binding
 	CloseAll    key.Binding
 	StartShed   key.Binding
 	StopShed    key.Binding
 	Refresh     key.Binding
+	History     key.Binding
+	Workspaces  key.Binding
 
 	// Dialog
 	Confirm key.Binding
@@ -73,6 +76,10 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("r"),
 			key.WithHelp("r", "rename"),
 		),
+		SendInput: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "send input"),
+		),
 		Close: key.NewBinding(
 			key.WithKeys("x"),
 			key.WithHelp("x", "close"),
@@ -93,6 +100,14 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("R", "ctrl+r"),
 			key.WithHelp("R", "refresh"),
 		),
+		History: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", "history"),
+		),
+		Workspaces: key.NewBinding(
+			key.WithKeys("W"),
+			key.WithHelp("W", "workspaces"),
+		),
 		Confirm: key.NewBinding(
 			key.WithKeys("y"),
 			key.WithHelp("y", "yes"),
@@ -126,7 +141,7 @@ func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Left, k.Right},
 		{k.Enter, k.Space, k.NewProject, k.AddTerminal},
-		{k.Rename, k.Close, k.CloseAll, k.Refresh},
-		{k.StartShed, k.StopShed, k.Help, k.Quit},
+		{k.Rename, k.SendInput, k.Close, k.CloseAll, k.Refresh},
+		{k.StartShed, k.StopShed, k.History, k.Workspaces, k.Help, k.Quit},
 	}
 }
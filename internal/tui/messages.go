@@ -1,6 +1,10 @@
 package tui
 
 import (
+	"time"
+
+	"github.com/charliek/codely/internal/config"
+	"github.com/charliek/codely/internal/discovery"
 	"github.com/charliek/codely/internal/domain"
 	"github.com/charliek/codely/internal/shed"
 )
@@ -12,6 +16,7 @@ type TickMsg struct{}
 type StatusUpdateMsg struct {
 	Updates   map[string]domain.Status // session ID -> status
 	ExitCodes map[string]*int          // session ID -> exit code (if any)
+	Dirs      map[string]string        // session ID -> pane working directory
 }
 
 // PaneCreatedMsg is sent when a new tmux pane is created
@@ -86,6 +91,43 @@ type shedCreateOutputMsg struct {
 	doneCh   <-chan error
 }
 
+// RepoClonedMsg is sent when a clone finishes
+type RepoClonedMsg struct {
+	Directory string
+	Err       error
+}
+
+// cloneStartedMsg carries channels from the streaming clone process.
+type cloneStartedMsg struct {
+	dir      string
+	cmdLine  string
+	outputCh <-chan string
+	doneCh   <-chan error
+}
+
+// cloneOutputMsg carries one progress line and channels for chaining.
+type cloneOutputMsg struct {
+	line     string
+	dir      string
+	outputCh <-chan string
+	doneCh   <-chan error
+}
+
+// ConfigReloadedMsg is sent after checking the config file for changes.
+// Config and Err are both nil if the file is unchanged.
+type ConfigReloadedMsg struct {
+	ModTime time.Time
+	Config  *config.Config
+	Err     error
+}
+
+// WorkspaceSwitchedMsg is sent after switching the client to another
+// workspace's tmux session
+type WorkspaceSwitchedMsg struct {
+	Name string
+	Err  error
+}
+
 // ErrorMsg represents an error to display
 type ErrorMsg struct {
 	Err error
@@ -105,9 +147,12 @@ type ProjectCreatedMsg struct {
 	Project *domain.Project
 }
 
-// FoldersLoadedMsg is sent when folders are loaded for picker
+// FoldersLoadedMsg is sent when folders are loaded for picker, from the
+// cache or a fresh scan
 type FoldersLoadedMsg struct {
 	Folders []string
+	Options discovery.Options // Options the folders were found with
+	Cached  bool
 	Err     error
 }
 
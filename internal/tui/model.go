@@ -1,11 +1,20 @@
 package tui
 
 import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
 	"github.com/charliek/codely/internal/config"
 	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/frecency"
+	"github.com/charliek/codely/internal/git"
+	"github.com/charliek/codely/internal/history"
 	"github.com/charliek/codely/internal/shed"
 	"github.com/charliek/codely/internal/store"
 	"github.com/charliek/codely/internal/tmux"
+	"github.com/charliek/codely/internal/workspace"
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/textinput"
 )
@@ -24,7 +33,13 @@ const (
 	ModeShedClose
 	ModeConfirm
 	ModeHelp
-	ModeNewProjectType // Choosing between local/shed
+	ModeNewProjectType // Choosing between local/clone/shed
+	ModeSendInput      // Typing text to send to a session
+	ModeCloneRepo      // Entering a repository to clone
+	ModeCloning        // Waiting for a clone to finish
+	ModeHistory        // Browsing closed sessions
+	ModeWorkspaces     // Choosing a workspace to switch to
+	ModeResurrect      // Choosing lost sessions to restore at startup
 )
 
 // ConfirmAction represents what action is being confirmed
@@ -37,13 +52,25 @@ const (
 	ConfirmDeleteShed
 )
 
+// newProjectType is an option in the new project type selector
+type newProjectType int
+
+const (
+	newProjectLocal newProjectType = iota
+	newProjectClone
+	newProjectAttachShed
+	newProjectCreateShed
+)
+
 // Model is the main application model
 type Model struct {
 	// Dependencies
-	config *config.Config
-	store  *store.Store
-	tmux   tmux.Client
-	shed   shed.Client
+	config  *config.Config
+	store   *store.Store
+	changes *storeChanges // Store events not yet synced
+	tmux    tmux.Client
+	shed    shed.Client
+	git     git.Client
 
 	// UI state
 	mode     Mode
@@ -53,6 +80,7 @@ type Model struct {
 	width    int
 	height   int
 	err      error
+	notice   string // Informational message shown until the next key press
 	showHelp bool
 
 	// Picker state
@@ -60,16 +88,26 @@ type Model struct {
 	folderIdx       int      // Selected folder index
 	folderSearch    textinput.Model
 	folderSearching bool
+	folderCachePath string          // Cache of the last folder scan ("" disables caching)
+	foldersScanning bool            // A background folder scan is running
+	foldersScanned  bool            // folders holds a fresh scan, not the cache
+	frecency        *frecency.Store // Folder open history for ranking (nil disables)
 
-	commands    []config.Command // Available commands
-	commandKeys []string         // Command IDs in order
-	commandIdx  int              // Selected command index
+	commands     []config.Command // Available commands
+	commandKeys  []string         // Command IDs in order
+	commandIdx   int              // Selected command index
+	pickerConfig *config.Config   // Effective config for the picker's project (nil uses config)
 
 	// Rename state
 	renameInput     textinput.Model
 	renameProjectID string
 	renameSessionID string
 
+	// Send input state
+	sendInput     textinput.Model
+	sendProjectID string
+	sendSessionID string
+
 	sheds   []shed.Shed // Available sheds
 	shedIdx int         // Selected shed index
 
@@ -91,7 +129,39 @@ type Model struct {
 	shedCloseOption int // 0=close only, 1=stop, 2=delete
 
 	// New project type state
-	newProjectTypeIdx int // 0=local, 1=attach shed, 2=create shed
+	newProjectTypes   []newProjectType // Options offered by the selector
+	newProjectTypeIdx int              // Index into newProjectTypes
+
+	// History state
+	historyPath      string          // Session archive ("" disables history)
+	historyEntries   []history.Entry // Archive as loaded when the view opened, newest first
+	historyIdx       int             // Selected entry in filteredHistory
+	historySearch    textinput.Model
+	historySearching bool
+	historyDetail    bool // Show the selected entry's output
+
+	// Workspace state
+	workspace       workspace.Workspace // The workspace this codely manages
+	workspaces      []workspaceInfo     // Picker entries
+	workspaceIdx    int                 // Selected picker entry
+	workspaceInput  textinput.Model     // Name of a new workspace
+	workspaceNaming bool                // Typing a new workspace name
+	codelyExe       string              // Executable started in other workspaces' tmux sessions
+	codelyArgs      []string            // Arguments codely was started with
+
+	// Resurrect state
+	lost           []lostSession // Sessions whose panes were gone at startup
+	lostIdx        int           // Selected entry in lost
+	resurrectQueue []lostSession // Selected sessions still to be restored
+
+	// Clone state
+	cloneRepo   textinput.Model
+	cloneRoot   int                // Index into config.WorkspaceRoots
+	cloneFocus  int                // 0=repository, 1=workspace root (if several)
+	cloneDir    string             // Directory being cloned into
+	cloneCmd    string             // The command line string for display
+	cloneOutput []string           // Progress lines for display
+	cloneCancel context.CancelFunc // Stops the clone in progress
 
 	// Confirm state
 	confirmAction  ConfirmAction
@@ -109,6 +179,11 @@ type Model struct {
 	// tmux status bar notifications
 	statusBarLast string
 	statusBarKeys map[string]int
+
+	// Config reload
+	configSources config.Sources // Config layers; files are watched for changes
+	configModTime time.Time      // Latest modification time of the loaded config files
+	skinOverride  SkinName       // Skin from --skin, which wins over ui.skin on reload
 }
 
 // NewModel creates a new application model
@@ -116,7 +191,7 @@ func NewModel(cfg *config.Config, store *store.Store, tmuxClient tmux.Client, sh
 	keys := DefaultKeyMap()
 
 	// Expand all projects by default if configured (before building skin)
-	if cfg.UI.AutoExpandProjects {
+	if cfg.UI.AutoExpands() {
 		for _, p := range store.Projects() {
 			p.Expanded = true
 		}
@@ -140,23 +215,39 @@ func NewModel(cfg *config.Config, store *store.Store, tmuxClient tmux.Client, sh
 	shedCreateRepo.Placeholder = "user/repo (optional)"
 	shedCreateRepo.CharLimit = 100
 
+	cloneRepo := textinput.New()
+	cloneRepo.Placeholder = "org/repo or git URL"
+	cloneRepo.CharLimit = 200
+
+	historySearch := textinput.New()
+	historySearch.Placeholder = "Filter history..."
+	historySearch.CharLimit = 100
+
+	workspaceInput := textinput.New()
+	workspaceInput.Placeholder = "workspace-name"
+	workspaceInput.CharLimit = 50
+
 	renameInput := textinput.New()
 	renameInput.Placeholder = "Session name"
 	renameInput.CharLimit = 80
 
-	// Build commands list
-	var commands []config.Command
-	var commandKeys []string
-	for id, cmd := range cfg.Commands {
-		commandKeys = append(commandKeys, id)
-		commands = append(commands, cmd)
-	}
+	sendInput := textinput.New()
+	sendInput.Placeholder = "Text to send"
+	sendInput.CharLimit = 1000
+
+	commands, commandKeys := commandList(cfg)
+
+	// Save, redraw and notify whenever the store changes
+	changes := &storeChanges{}
+	store.Subscribe(changes.record)
 
 	return &Model{
 		config:         cfg,
 		store:          store,
+		changes:        changes,
 		tmux:           tmuxClient,
 		shed:           shedClient,
+		git:            git.NewClient(),
 		mode:           ModeNormal,
 		skin:           skin,
 		keys:           keys,
@@ -165,13 +256,30 @@ func NewModel(cfg *config.Config, store *store.Store, tmuxClient tmux.Client, sh
 		commandKeys:    commandKeys,
 		folderSearch:   folderSearch,
 		renameInput:    renameInput,
+		sendInput:      sendInput,
 		shedCreateName: shedCreateName,
 		shedCreateRepo: shedCreateRepo,
+		cloneRepo:      cloneRepo,
+		historySearch:  historySearch,
+		workspace:      workspace.Default(),
+		workspaceInput: workspaceInput,
 		codelyPaneID:   codelyPaneID,
 		codelyWindowID: codelyWindowID,
 		managerWidth:   cfg.UI.ManagerWidth,
 		statusBarKeys:  make(map[string]int),
+		// Init starts the first folder scan
+		foldersScanning: true,
+	}
+}
+
+// commandList returns the configured commands and their IDs in matching order
+func commandList(cfg *config.Config) ([]config.Command, []string) {
+	commandKeys := slices.Sorted(maps.Keys(cfg.Commands))
+	commands := make([]config.Command, 0, len(commandKeys))
+	for _, id := range commandKeys {
+		commands = append(commands, cfg.Commands[id])
 	}
+	return commands, commandKeys
 }
 
 // SelectedProject returns the currently selected project (if any)
@@ -188,3 +296,14 @@ func (m *Model) SelectedSession() *domain.Session {
 func (m *Model) IsSessionSelected() bool {
 	return m.skin.IsSessionSelected()
 }
+
+// addNotice appends a message to the notice banner
+func (m *Model) addNotice(msg string) {
+	if msg == "" {
+		return
+	}
+	if m.notice != "" {
+		m.notice += "; "
+	}
+	m.notice += msg
+}
@@ -1,14 +1,25 @@
 package tui
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
+	tea "github.com/charmbracelet/bubbletea"
+
 	"github.com/charliek/codely/internal/config"
 	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/frecency"
+	"github.com/charliek/codely/internal/git"
 	"github.com/charliek/codely/internal/shed"
+	"github.com/charliek/codely/internal/statuslog"
 	"github.com/charliek/codely/internal/store"
 	"github.com/charliek/codely/internal/tmux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewModel(t *testing.T) {
@@ -122,9 +133,424 @@ func TestStatusUpdateMsg(t *testing.T) {
 	updates := map[string]domain.Status{
 		"sess-1": domain.StatusThinking,
 	}
-	model.applyStatusUpdates(updates)
+	model.applyStatusUpdates(updates, nil)
 
 	// Verify status was updated
 	p, _ := st.GetProject("proj-1")
 	assert.Equal(t, domain.StatusThinking, p.Sessions[0].Status)
 }
+
+func TestUpdateSyncsStoreChanges(t *testing.T) {
+	cfg := config.Default()
+	path := filepath.Join(t.TempDir(), "state.json")
+	st := store.New(path)
+	require.NoError(t, st.AddProject(&domain.Project{
+		ID:   "proj-1",
+		Name: "test",
+		Type: domain.ProjectTypeLocal,
+		Sessions: []domain.Session{
+			{ID: "sess-1", ProjectID: "proj-1", PaneID: 5, Command: domain.Command{ID: "claude"}},
+		},
+	}))
+
+	tmuxClient := tmux.NewMockClient()
+	model := NewModel(cfg, st, tmuxClient, shed.NewMockClient(), 0, "", SkinTree)
+
+	// A poll without changes leaves the state and notifications alone
+	next, _ := model.Update(StatusUpdateMsg{})
+	assert.Empty(t, tmuxClient.Calls)
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	// A status change surfaces in the tmux status bar but is not persisted
+	next, _ = next.Update(StatusUpdateMsg{Updates: map[string]domain.Status{"sess-1": domain.StatusWaiting}})
+	assert.Contains(t, findCall(t, tmuxClient, "SetStatusRight").Args[0], "test/")
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	// A new working directory is saved
+	_, _ = next.Update(StatusUpdateMsg{Dirs: map[string]string{"sess-1": "/src/test/sub"}})
+	saved := store.New(path)
+	require.NoError(t, saved.Load())
+	sess, err := saved.GetSession("proj-1", "sess-1")
+	require.NoError(t, err)
+	assert.Equal(t, "/src/test/sub", sess.WorkDir)
+}
+
+func TestLogStatusChanges(t *testing.T) {
+	cfg := config.Default()
+	st := store.New(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, st.AddProject(&domain.Project{
+		ID:   "proj-1",
+		Name: "api",
+		Sessions: []domain.Session{
+			{ID: "sess-1", ProjectID: "proj-1", PaneID: 5, Command: domain.Command{ID: "claude", DisplayName: "Claude"}},
+		},
+	}))
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	st.Subscribe(logStatusChanges(st, path))
+	model := NewModel(cfg, st, tmux.NewMockClient(), shed.NewMockClient(), 0, "", SkinTree)
+
+	code := 2
+	next, _ := model.Update(StatusUpdateMsg{Updates: map[string]domain.Status{"sess-1": domain.StatusThinking}})
+	// An unchanged status is not logged again
+	next, _ = next.Update(StatusUpdateMsg{Updates: map[string]domain.Status{"sess-1": domain.StatusThinking}})
+	_, _ = next.Update(StatusUpdateMsg{
+		Updates:   map[string]domain.Status{"sess-1": domain.StatusError},
+		ExitCodes: map[string]*int{"sess-1": &code},
+	})
+
+	transitions, err := statuslog.Load(path)
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+	assert.Equal(t, "api", transitions[0].Project)
+	assert.Equal(t, "Claude", transitions[0].Session)
+	assert.Equal(t, "claude", transitions[0].Command)
+	assert.Equal(t, domain.Status(""), transitions[0].From)
+	assert.Equal(t, domain.StatusThinking, transitions[0].To)
+	assert.Nil(t, transitions[0].ExitCode)
+	assert.Equal(t, domain.StatusThinking, transitions[1].From)
+	assert.Equal(t, domain.StatusError, transitions[1].To)
+	require.NotNil(t, transitions[1].ExitCode)
+	assert.Equal(t, 2, *transitions[1].ExitCode)
+}
+
+func TestLogStatusChangesEnds(t *testing.T) {
+	st := store.New(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, st.AddProject(&domain.Project{
+		ID:   "proj-1",
+		Name: "api",
+		Sessions: []domain.Session{
+			{ID: "sess-1", ProjectID: "proj-1", PaneID: 5, Status: domain.StatusWaiting, Command: domain.Command{ID: "claude"}},
+			{ID: "sess-2", ProjectID: "proj-1", PaneID: 6, Status: domain.StatusThinking, Command: domain.Command{ID: "codex"}},
+			{ID: "sess-3", ProjectID: "proj-1", PaneID: 7, Status: domain.StatusExited, Command: domain.Command{ID: "bash"}},
+		},
+	}))
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	st.Subscribe(logStatusChanges(st, path))
+
+	// Closing an active session logs it as exited; an exited one is not
+	// logged again
+	require.NoError(t, st.RemoveSession("proj-1", "sess-1"))
+	require.NoError(t, st.RemoveSession("proj-1", "sess-3"))
+	// Shutting down moves the remaining active sessions to unknown
+	logShutdown(st, path)
+
+	transitions, err := statuslog.Load(path)
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+	assert.Equal(t, "sess-1", transitions[0].SessionID)
+	assert.Equal(t, domain.StatusWaiting, transitions[0].From)
+	assert.Equal(t, domain.StatusExited, transitions[0].To)
+	assert.Equal(t, "sess-2", transitions[1].SessionID)
+	assert.Equal(t, domain.StatusThinking, transitions[1].From)
+	assert.Equal(t, domain.StatusUnknown, transitions[1].To)
+}
+
+func TestCreatePaneCmdTagsPane(t *testing.T) {
+	cfg := config.Default()
+	st := store.New(t.TempDir() + "/state.json")
+	proj := &domain.Project{ID: "proj-1", Name: "test", Type: domain.ProjectTypeLocal, Directory: "/tmp/test"}
+	sess := &domain.Session{ID: "sess-1", ProjectID: "proj-1", Command: domain.Command{ID: "bash", Exec: "bash"}}
+	_ = st.AddProject(proj)
+	_ = st.AddSession(proj.ID, sess)
+
+	tmuxClient := tmux.NewMockClient()
+	tmuxClient.SplitPanePaneID = 7
+	model := NewModel(cfg, st, tmuxClient, shed.NewMockClient(), 0, "", SkinTree)
+
+	msg := model.createPaneCmd(proj, sess)()
+	created, ok := msg.(PaneCreatedMsg)
+	assert.True(t, ok)
+	assert.Equal(t, 7, created.PaneID)
+
+	var tags []interface{}
+	for _, call := range tmuxClient.Calls {
+		if call.Method == "SetPaneOption" {
+			tags = append(tags, call.Args...)
+		}
+	}
+	assert.Equal(t, []interface{}{
+		7, tmux.SessionIDOption, "sess-1",
+		7, tmux.ProjectIDOption, "proj-1",
+		7, tmux.WorkspaceOption, "default",
+	}, tags)
+}
+
+func TestCreatePaneCmdExpandsTemplates(t *testing.T) {
+	cfg := config.Default()
+	st := store.New(t.TempDir() + "/state.json")
+	proj := &domain.Project{ID: "proj-1", Name: "api", Type: domain.ProjectTypeLocal, Directory: "/src/api"}
+	sess := &domain.Session{ID: "sess-1", ProjectID: "proj-1", Command: domain.Command{
+		ID:   "claude",
+		Exec: "claude",
+		Args: []string{"--log", "/tmp/{{.Project.Name}}-{{.Session.ID}}.log", "{{.Project.Directory}}"},
+		Env:  map[string]string{"AGENT": "{{.Project.Name}}", "MODE": "fast"},
+	}}
+	_ = st.AddProject(proj)
+	_ = st.AddSession(proj.ID, sess)
+
+	tmuxClient := tmux.NewMockClient()
+	tmuxClient.SplitPanePaneID = 7
+	model := NewModel(cfg, st, tmuxClient, shed.NewMockClient(), 0, "", SkinTree)
+
+	msg := model.createPaneCmd(proj, sess)().(PaneCreatedMsg)
+	require.NoError(t, msg.Err)
+
+	split := findCall(t, tmuxClient, "SplitPane")
+	assert.Equal(t, []string{"AGENT=api", "MODE=fast"}, split.Args[3])
+	assert.Equal(t, []string{"--log", "/tmp/api-sess-1.log", "/src/api"}, split.Args[5])
+
+	// Shed projects expose the shed name and server
+	shedProj := &domain.Project{ID: "proj-2", Name: "web", Type: domain.ProjectTypeShed, ShedName: "web", ShedServer: "lab"}
+	shedSess := &domain.Session{ID: "sess-2", ProjectID: "proj-2", Command: domain.Command{
+		ID: "claude", Exec: "claude", Args: []string{"--name={{.Shed.Name}}@{{.Shed.Server}}"},
+		Env: map[string]string{"SERVER": "{{.Shed.Server}}"},
+	}}
+	shedClient := shed.NewMockClient()
+	model = NewModel(cfg, st, tmux.NewMockClient(), shedClient, 0, "", SkinTree)
+	_ = model.createPaneCmd(shedProj, shedSess)()
+	require.Len(t, shedClient.Calls, 2)
+	assert.Equal(t, []interface{}{"web", []string{"SERVER=lab"}}, shedClient.Calls[0].Args)
+	assert.Equal(t, []interface{}{"web", "/tmp/codely-env", "claude", []string{"--name=web@lab"}}, shedClient.Calls[1].Args)
+
+	// The env file must be written before the pane starts
+	shedClient.WriteEnvFileErr = errors.New("shed unreachable")
+	msg = model.createPaneCmd(shedProj, shedSess)().(PaneCreatedMsg)
+	assert.ErrorContains(t, msg.Err, "shed unreachable")
+
+	// Unknown placeholders fail the launch
+	sess.Command.Args = []string{"{{.Project.Nmae}}"}
+	msg = model.createPaneCmd(proj, sess)().(PaneCreatedMsg)
+	assert.ErrorContains(t, msg.Err, "command claude: expanding args[0]")
+}
+
+func TestOrphanNotice(t *testing.T) {
+	st := store.New("/tmp/test-state.json")
+	_ = st.AddProject(&domain.Project{ID: "proj-1", Name: "api"})
+
+	assert.Empty(t, orphanNotice(nil, st))
+
+	notice := orphanNotice([]tmux.PaneInfo{
+		{ID: 4, SessionID: "sess-9", ProjectID: "proj-1"},
+		{ID: 6, SessionID: "sess-8", ProjectID: "proj-gone"},
+	}, st)
+	assert.Equal(t, "2 orphaned codely pane(s) not in saved state: %4 (api), %6", notice)
+}
+
+func TestBootstrapTmuxCreatesSession(t *testing.T) {
+	tmuxClient := tmux.NewMockClient()
+	tmuxClient.HasSessionResult = false
+
+	err := bootstrapTmux(tmuxClient, "codely", "/usr/local/bin/codely", []string{"--skin", "flat"})
+	assert.NoError(t, err)
+
+	var methods []string
+	for _, call := range tmuxClient.Calls {
+		methods = append(methods, call.Method)
+	}
+	assert.Equal(t, []string{"HasSession", "CreateSession", "AttachSession"}, methods)
+	assert.Equal(t, []interface{}{"codely", "/usr/local/bin/codely", []string{"--skin", "flat"}}, tmuxClient.Calls[1].Args)
+}
+
+func TestBootstrapTmuxAttachesExistingSession(t *testing.T) {
+	tmuxClient := tmux.NewMockClient()
+	tmuxClient.HasSessionResult = true
+
+	err := bootstrapTmux(tmuxClient, "codely", "/usr/local/bin/codely", nil)
+	assert.NoError(t, err)
+
+	var methods []string
+	for _, call := range tmuxClient.Calls {
+		methods = append(methods, call.Method)
+	}
+	assert.Equal(t, []string{"HasSession", "AttachSession"}, methods)
+}
+
+func TestFolderScanAndCache(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "api"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "github.com", "org", "web", ".git"), 0o755))
+
+	cfg := config.Default()
+	cfg.WorkspaceRoots = []string{root}
+	st := store.New(t.TempDir() + "/state.json")
+	model := NewModel(cfg, st, tmux.NewMockClient(), shed.NewMockClient(), 0, "", SkinTree)
+	model.folderCachePath = filepath.Join(t.TempDir(), "folders.json")
+
+	// No cache before the first scan
+	assert.Nil(t, model.loadCachedFoldersCmd()())
+
+	scanned := model.scanFoldersCmd()().(FoldersLoadedMsg)
+	want := []string{
+		filepath.Join(root, "api"),
+		filepath.Join(root, "github.com"),
+		filepath.Join(root, "github.com", "org", "web"),
+	}
+	assert.Equal(t, want, scanned.Folders)
+
+	cached := model.loadCachedFoldersCmd()().(FoldersLoadedMsg)
+	assert.True(t, cached.Cached)
+	assert.Equal(t, want, cached.Folders)
+
+	// The scan result wins over a cache load that arrives later
+	updated, _ := model.Update(scanned)
+	m := updated.(Model)
+	assert.False(t, m.foldersScanning)
+	updated, _ = m.Update(FoldersLoadedMsg{Folders: []string{"/stale"}, Options: m.discoveryOptions(), Cached: true})
+	m = updated.(Model)
+	assert.Equal(t, want, m.folders)
+
+	// Only one scan runs at a time
+	assert.NotNil(t, m.refreshFoldersCmd())
+	assert.Nil(t, m.refreshFoldersCmd())
+}
+
+func TestFolderScanFromReplacedConfigDropped(t *testing.T) {
+	oldRoot, newRoot := t.TempDir(), t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(oldRoot, "old"), 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(newRoot, "new"), 0o755))
+
+	cfg := config.Default()
+	cfg.WorkspaceRoots = []string{oldRoot}
+	model := NewModel(cfg, store.New(t.TempDir()+"/state.json"), tmux.NewMockClient(), shed.NewMockClient(), 0, "", SkinTree)
+	stale := model.scanFoldersCmd()
+
+	// The roots change while the first scan runs
+	newCfg := config.Default()
+	newCfg.WorkspaceRoots = []string{newRoot}
+	cmd := model.handleConfigReloaded(ConfigReloadedMsg{Config: newCfg})
+	require.NotNil(t, cmd)
+	fresh := model.scanFoldersCmd()
+
+	// The new scan's folders are kept, whichever finishes first
+	updated, _ := model.Update(fresh())
+	m := updated.(Model)
+	updated, _ = m.Update(stale())
+	m = updated.(Model)
+	assert.Equal(t, []string{filepath.Join(newRoot, "new")}, m.folders)
+
+	// A stale scan finishing before the new one leaves the scan running
+	model.folders = nil
+	model.foldersScanned = false
+	model.foldersScanning = true
+	updated, _ = model.Update(stale())
+	m = updated.(Model)
+	assert.Empty(t, m.folders)
+	assert.True(t, m.foldersScanning)
+}
+
+func TestFilteredFoldersFrecency(t *testing.T) {
+	st := store.New(t.TempDir() + "/state.json")
+	model := NewModel(config.Default(), st, tmux.NewMockClient(), shed.NewMockClient(), 0, "", SkinTree)
+	model.folders = []string{"/src/api", "/src/nested/tool", "/src/web", "/src/webhooks"}
+
+	// Without history the folders are grouped by parent
+	assert.Equal(t, []string{"/src/api", "/src/web", "/src/webhooks", "/src/nested/tool"}, model.filteredFolders())
+
+	// Opening projects records visits and moves them to the recent section
+	model.frecency = frecency.New("")
+	model.handleProjectCreated(&domain.Project{ID: "p1", Type: domain.ProjectTypeLocal, Directory: "/src/webhooks"})
+	model.handleProjectCreated(&domain.Project{ID: "p2", Type: domain.ProjectTypeLocal, Directory: "/src/webhooks"})
+	model.handleProjectCreated(&domain.Project{ID: "p3", Type: domain.ProjectTypeLocal, Directory: "/src/nested/tool"})
+	assert.Equal(t, []string{"/src/webhooks", "/src/nested/tool"}, model.recentFolders())
+	assert.Equal(t, []string{"/src/webhooks", "/src/nested/tool", "/src/api", "/src/web"}, model.filteredFolders())
+
+	// Searches fuzzy match, ranking by match quality and then frecency
+	model.folderSearching = true
+	model.folderSearch.SetValue("web")
+	assert.Equal(t, []string{"/src/webhooks", "/src/web"}, model.filteredFolders())
+	model.folderSearch.SetValue("ntl")
+	assert.Equal(t, []string{"/src/nested/tool"}, model.filteredFolders())
+	model.folderSearch.SetValue("xyz")
+	assert.Empty(t, model.filteredFolders())
+}
+
+func TestCloneRepo(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "existing"), 0o755))
+
+	cfg := config.Default()
+	cfg.WorkspaceRoots = []string{root}
+	st := store.New(t.TempDir() + "/state.json")
+	gitClient := git.NewMockClient()
+	gitClient.CloneOutput = []string{"Cloning into 'repo'...", "Receiving objects:  50% (1/2)", "Receiving objects: 100% (2/2), done."}
+	model := NewModel(cfg, st, tmux.NewMockClient(), shed.NewMockClient(), 0, "", SkinTree)
+	model.git = gitClient
+	model.mode = ModeCloneRepo
+
+	// An existing directory is not cloned over
+	model.cloneRepo.SetValue("org/existing")
+	updated, cmd := model.startClone()
+	m := updated.(Model)
+	assert.Nil(t, cmd)
+	assert.Equal(t, ModeCloneRepo, m.mode)
+	assert.ErrorContains(t, m.err, "already exists")
+
+	m.cloneRepo.SetValue("org/repo")
+	updated, cmd = m.startClone()
+	m = updated.(Model)
+	require.NotNil(t, cmd)
+	assert.Equal(t, ModeCloning, m.mode)
+	dir := filepath.Join(root, "repo")
+
+	// Stream progress until the clone finishes
+	msg := cmd()
+	require.Len(t, gitClient.Calls, 1)
+	assert.Equal(t, []interface{}{"https://github.com/org/repo.git", dir}, gitClient.Calls[0].Args)
+	for {
+		if _, done := msg.(RepoClonedMsg); done {
+			break
+		}
+		updated, cmd = m.Update(msg)
+		m = updated.(Model)
+		msg = cmd()
+	}
+	assert.Equal(t, []string{"Cloning into 'repo'...", "Receiving objects: 100% (2/2), done."}, m.cloneOutput)
+
+	// The cloned directory becomes a local project
+	updated, cmd = m.Update(msg)
+	m = updated.(Model)
+	// A folder scan is already running, so no rescan is queued
+	created, ok := cmd().(ProjectCreatedMsg)
+	require.True(t, ok)
+	assert.Equal(t, dir, created.Project.Directory)
+	assert.Equal(t, domain.ProjectTypeLocal, created.Project.Type)
+
+	// A failed clone returns to normal mode with the error
+	updated, _ = m.Update(RepoClonedMsg{Directory: dir, Err: errors.New("git clone failed")})
+	m = updated.(Model)
+	assert.Equal(t, ModeNormal, m.mode)
+	assert.EqualError(t, m.err, "git clone failed")
+}
+
+func TestCloneRepoEscCancels(t *testing.T) {
+	cfg := config.Default()
+	cfg.WorkspaceRoots = []string{t.TempDir()}
+	model := NewModel(cfg, store.New(t.TempDir()+"/state.json"), tmux.NewMockClient(), shed.NewMockClient(), 0, "", SkinTree)
+	model.git = git.NewMockClient()
+	model.mode = ModeCloneRepo
+	model.cloneRepo.SetValue("org/repo")
+
+	updated, _ := model.startClone()
+	m := updated.(Model)
+	require.Equal(t, ModeCloning, m.mode)
+	cancel := m.cloneCancel
+	require.NotNil(t, cancel)
+
+	// Other keys are ignored while cloning
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	m = updated.(Model)
+	assert.Equal(t, ModeCloning, m.mode)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(Model)
+	assert.Equal(t, ModeNormal, m.mode)
+	assert.Nil(t, m.cloneCancel)
+
+	// The canceled clone's result is not shown as an error
+	updated, _ = m.Update(RepoClonedMsg{Err: fmt.Errorf("git clone: %w", context.Canceled)})
+	m = updated.(Model)
+	assert.NoError(t, m.err)
+	assert.Equal(t, ModeNormal, m.mode)
+}
@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/codely/internal/config"
+	"github.com/charliek/codely/internal/control"
+)
+
+func TestCommandPickerUsesProjectConfig(t *testing.T) {
+	model, st := renameTestModel(t, SkinTree, "claude", "Claude Code")
+	proj, err := st.GetProject("proj-1")
+	require.NoError(t, err)
+	proj.Directory = t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(proj.Directory, config.ProjectConfigFile), []byte(`
+default_command: aider
+commands:
+  aider:
+    display_name: Aider
+    exec: aider
+    status_detection: generic
+`), 0o644))
+
+	// New commands are ignored until the project is trusted
+	model.openCommandPicker(proj)
+	assert.NotContains(t, model.commandKeys, "aider")
+	assert.Contains(t, model.notice, "trusted_projects")
+
+	model.mode = ModeNormal
+	model.config.TrustedProjects = []string{proj.Directory}
+	model.openCommandPicker(proj)
+	assert.Equal(t, ModeCommandPicker, model.mode)
+	assert.Contains(t, model.commandKeys, "aider")
+	assert.Equal(t, "aider", model.commandKeys[model.commandIdx])
+	assert.NotContains(t, model.config.Commands, "aider")
+
+	updated, _ := model.handleCommandPickerKey(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(Model)
+
+	require.Len(t, proj.Sessions, 2)
+	launched := proj.Sessions[1].Command
+	assert.Equal(t, "aider", launched.ID)
+	assert.Equal(t, "generic", launched.StatusDetection)
+	assert.Equal(t, "generic", model.config.DetectionMode(launched))
+}
+
+func TestProjectConfigErrorFallsBackToGlobal(t *testing.T) {
+	model, st := renameTestModel(t, SkinTree, "claude", "Claude Code")
+	proj, err := st.GetProject("proj-1")
+	require.NoError(t, err)
+	proj.Directory = t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(proj.Directory, config.ProjectConfigFile), []byte("comands: {}\n"), 0o644))
+
+	_, resp := controlRequest(t, model, control.Request{Action: control.ActionSpawn, Project: "project"})
+	require.True(t, resp.OK, resp.Error)
+	assert.Equal(t, "claude", proj.Sessions[1].Command.ID)
+}
@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/charliek/codely/internal/config"
+	"github.com/charliek/codely/internal/debug"
+	"github.com/charliek/codely/internal/pathutil"
+)
+
+// configWatchInterval is how often the config file is checked for changes
+const configWatchInterval = 2 * time.Second
+
+// configWatchCmd checks the config files after configWatchInterval and
+// reloads the config if their latest modification time differs from since.
+func (m *Model) configWatchCmd(since time.Time) tea.Cmd {
+	if len(m.configSources.Files) == 0 {
+		return nil
+	}
+	sources := m.configSources
+	return tea.Tick(configWatchInterval, func(time.Time) tea.Msg {
+		return checkConfigFiles(sources, since)
+	})
+}
+
+// checkConfigFiles reloads the layered config if any file changed since the
+// given time. If no file exists the current config is kept.
+func checkConfigFiles(sources config.Sources, since time.Time) ConfigReloadedMsg {
+	modTime := configModTime(sources.Files)
+	if modTime.IsZero() || modTime.Equal(since) {
+		return ConfigReloadedMsg{ModTime: since}
+	}
+
+	cfg, err := config.LoadLayered(sources)
+	debug.Log("config reload: files=%v err=%v", sources.Files, err)
+	return ConfigReloadedMsg{ModTime: modTime, Config: cfg, Err: err}
+}
+
+// configModTime returns the latest modification time of the given files, or
+// the zero time if none can be read.
+func configModTime(paths []string) time.Time {
+	var latest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(pathutil.ExpandPath(path))
+		if err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// handleConfigReloaded applies a reloaded config and schedules the next check.
+func (m *Model) handleConfigReloaded(msg ConfigReloadedMsg) tea.Cmd {
+	m.configModTime = msg.ModTime
+
+	switch {
+	case msg.Err != nil:
+		// Keep running with the previous config
+		m.addNotice("config reload failed: " + strings.ReplaceAll(msg.Err.Error(), "\n  ", " "))
+	case msg.Config != nil:
+		oldDiscovery := m.discoveryOptions()
+		m.applyConfig(msg.Config)
+		m.addNotice("config reloaded")
+		for _, w := range msg.Config.Warnings {
+			m.addNotice("config: " + w.String())
+		}
+		// Rescan folders if the roots or discovery settings changed
+		if !oldDiscovery.Equal(m.discoveryOptions()) {
+			m.foldersScanning = false
+			return tea.Batch(m.configWatchCmd(m.configModTime), m.refreshFoldersCmd())
+		}
+	}
+
+	return m.configWatchCmd(m.configModTime)
+}
+
+// applyConfig swaps in a new config, updating the command picker, skin and
+// manager width. The status poll interval takes effect on the next tick.
+func (m *Model) applyConfig(cfg *config.Config) {
+	old := m.config
+	m.config = cfg
+	if m.mode == ModeCommandPicker && m.pendingProject != nil {
+		m.openCommandPicker(m.pendingProject)
+	}
+
+	if cfg.UI.AutoExpands() && !old.UI.AutoExpands() {
+		for _, p := range m.store.Projects() {
+			p.Expanded = true
+		}
+	}
+
+	// Rebuild the skin so it picks up UI settings, keeping the selection
+	proj := m.skin.SelectedProject()
+	sess := m.skin.SelectedSession()
+	sessionSelected := m.skin.IsSessionSelected()
+	m.skin = NewSkin(resolveSkin(m.skinOverride, cfg), m.store.Projects(), cfg, m.keys)
+	switch {
+	case sessionSelected && proj != nil && sess != nil:
+		m.skin.SelectBySessionID(proj.ID, sess.ID)
+	case proj != nil:
+		m.skin.SelectByProjectID(proj.ID)
+	}
+
+	// Only resize when the configured width changed, so a manual resize
+	// survives unrelated edits
+	if cfg.UI.ManagerWidth != old.UI.ManagerWidth && cfg.UI.ManagerWidth > 0 {
+		m.managerWidth = cfg.UI.ManagerWidth
+		if m.codelyPaneID >= 0 {
+			if err := m.tmux.ResizePane(m.codelyPaneID, cfg.UI.ManagerWidth); err != nil {
+				debug.Log("config reload: resize failed: %v", err)
+			}
+		}
+	}
+
+	debug.Log("config applied: commands=%d skin=%s width=%d poll=%s",
+		len(cfg.Commands), resolveSkin(m.skinOverride, cfg), cfg.UI.ManagerWidth, cfg.UI.StatusPollInterval)
+}
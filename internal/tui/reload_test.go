@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/codely/internal/config"
+	"github.com/charliek/codely/internal/tmux"
+)
+
+func TestCheckConfigFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	sources := config.Sources{Files: []string{path}}
+
+	// Missing file keeps the current config
+	msg := checkConfigFiles(sources, time.Time{})
+	assert.Nil(t, msg.Config)
+	assert.NoError(t, msg.Err)
+
+	require.NoError(t, os.WriteFile(path, []byte("default_command: bash\n"), 0o644))
+	msg = checkConfigFiles(sources, time.Time{})
+	require.NoError(t, msg.Err)
+	require.NotNil(t, msg.Config)
+	assert.Equal(t, "bash", msg.Config.DefaultCommand)
+	assert.False(t, msg.ModTime.IsZero())
+
+	// Unchanged modification time does not reload
+	again := checkConfigFiles(sources, msg.ModTime)
+	assert.Nil(t, again.Config)
+	assert.Equal(t, msg.ModTime, again.ModTime)
+
+	// Parse errors are reported, not fatal
+	require.NoError(t, os.WriteFile(path, []byte("ui:\n  skin: cards\n"), 0o644))
+	later := msg.ModTime.Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+	msg = checkConfigFiles(sources, msg.ModTime)
+	assert.Nil(t, msg.Config)
+	assert.ErrorContains(t, msg.Err, "unknown skin")
+}
+
+func TestHandleConfigReloaded(t *testing.T) {
+	model, _ := renameTestModel(t, SkinTree, "claude", "Claude Code")
+	model.codelyPaneID = 1
+	mock := model.tmux.(*tmux.MockClient)
+
+	cfg, err := config.Parse([]byte(`
+commands:
+  aider:
+    exec: aider
+ui:
+  manager_width: 45
+  status_poll_interval: 3s
+  skin: flat
+`))
+	require.NoError(t, err)
+
+	updated, _ := model.Update(ConfigReloadedMsg{ModTime: time.Now(), Config: cfg})
+	m := updated.(Model)
+
+	assert.Contains(t, m.config.Commands, "aider")
+	assert.IsType(t, &FlatSkin{}, m.skin)
+	assert.Equal(t, 45, m.managerWidth)
+	assert.Equal(t, 3*time.Second, m.config.StatusPollIntervalDuration())
+	assert.Contains(t, m.notice, "config reloaded")
+
+	last := mock.Calls[len(mock.Calls)-1]
+	assert.Equal(t, "ResizePane", last.Method)
+	assert.Equal(t, []interface{}{1, 45}, last.Args)
+}
+
+func TestHandleConfigReloadedKeepsSkinOverrideAndConfigOnError(t *testing.T) {
+	model, _ := renameTestModel(t, SkinTree, "claude", "Claude Code")
+	model.skinOverride = SkinTree
+	before := model.config
+
+	cfg, err := config.Parse([]byte("ui:\n  skin: flat\n"))
+	require.NoError(t, err)
+	updated, _ := model.Update(ConfigReloadedMsg{Config: cfg})
+	m := updated.(Model)
+	assert.IsType(t, &TreeSkin{}, m.skin)
+
+	updated, _ = m.Update(ConfigReloadedMsg{Err: &config.ValidationError{
+		Diagnostics: []config.Diagnostic{{Line: 2, Message: `unknown key "foo"`}},
+	}})
+	m = updated.(Model)
+	assert.Same(t, cfg, m.config)
+	assert.NotSame(t, before, m.config)
+	assert.Contains(t, m.notice, `config reload failed: invalid config: line 2: unknown key "foo"`)
+}
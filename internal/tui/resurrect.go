@@ -0,0 +1,238 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/charliek/codely/internal/debug"
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/pathutil"
+	"github.com/charliek/codely/internal/resume"
+)
+
+// lostSession is a stored session whose pane was gone at startup, e.g.
+// after the tmux server was restarted
+type lostSession struct {
+	Project  *domain.Project
+	Session  domain.Session
+	Command  domain.Command // Command to restart, refreshed from the config
+	Plan     resume.Plan
+	Selected bool
+	Shared   string // Adapter of another session that resumes in the same directory
+}
+
+// droppedSessions returns the sessions ReconnectSessions removed. before
+// holds each project's sessions as loaded.
+func (m *Model) droppedSessions(before map[string][]domain.Session) []lostSession {
+	var lost []lostSession
+	for _, p := range m.store.Projects() {
+		for _, sess := range before[p.ID] {
+			if slices.ContainsFunc(p.Sessions, func(s domain.Session) bool { return s.ID == sess.ID }) {
+				continue
+			}
+			cmd, plan := m.resurrectCommand(p, sess.Command)
+			lost = append(lost, lostSession{Project: p, Session: sess, Command: cmd, Plan: plan, Selected: true})
+		}
+	}
+
+	// Adapters continue the most recent conversation in a directory, so
+	// only the most recently started session per tool and directory
+	// resumes; the others would all open that same conversation
+	latest := make(map[string]int)
+	for i, l := range lost {
+		if l.Plan.Adapter == "" {
+			continue
+		}
+		k := resumeKey(l)
+		if j, ok := latest[k]; !ok || l.Session.StartedAt.After(lost[j].Session.StartedAt) {
+			latest[k] = i
+		}
+	}
+	for i, l := range lost {
+		if l.Plan.Adapter != "" && latest[resumeKey(l)] != i {
+			lost[i].Shared = l.Plan.Adapter
+			lost[i].Plan = resume.For(l.Command, []string{})
+		}
+	}
+	return lost
+}
+
+// resumeKey identifies the tool and directory a session's adapter resumes in
+func resumeKey(l lostSession) string {
+	dir := l.Session.WorkDir
+	if dir == "" {
+		dir = l.Project.Directory
+	}
+	return l.Plan.Adapter + "\x00" + l.Project.ShedName + "\x00" + pathutil.ExpandPath(dir)
+}
+
+// resurrectCommand returns the command a lost session restarts with and its
+// resume plan. A command still configured is taken from the config, since
+// env values are not stored, keeping the session's name.
+func (m *Model) resurrectCommand(proj *domain.Project, stored domain.Command) (domain.Command, resume.Plan) {
+	cmd := stored
+	var resumeArgs []string
+	if c, ok := m.projectConfig(proj).Commands[stored.ID]; ok {
+		cmd = c.ToDomainCommand(stored.ID)
+		cmd.DisplayName = stored.DisplayName
+		resumeArgs = c.ResumeArgs
+	}
+	return cmd, resume.For(cmd, resumeArgs)
+}
+
+// offerResurrect shows the restore dialog if sessions were lost at startup
+func (m *Model) offerResurrect(before map[string][]domain.Session) {
+	m.lost = m.droppedSessions(before)
+	if len(m.lost) == 0 {
+		return
+	}
+	debug.Log("offerResurrect: lost=%d", len(m.lost))
+	m.lostIdx = 0
+	m.mode = ModeResurrect
+}
+
+// handleResurrectKey handles keys in the restore dialog
+func (m Model) handleResurrectKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Type == tea.KeyEsc, key.Matches(msg, m.keys.Quit):
+		m.lost = nil
+		m.mode = ModeNormal
+		return m, nil
+
+	case key.Matches(msg, m.keys.Up):
+		if m.lostIdx > 0 {
+			m.lostIdx--
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Down):
+		if m.lostIdx < len(m.lost)-1 {
+			m.lostIdx++
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Space):
+		if m.lostIdx < len(m.lost) {
+			m.lost[m.lostIdx].Selected = !m.lost[m.lostIdx].Selected
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Enter):
+		for _, l := range m.lost {
+			if l.Selected {
+				m.resurrectQueue = append(m.resurrectQueue, l)
+			}
+		}
+		m.lost = nil
+		m.mode = ModeNormal
+		return m, m.resurrectNext()
+	}
+
+	return m, nil
+}
+
+// resurrectNext restores the next queued session with its ID and name and
+// starts its pane. Panes are created one at a time, as each creation
+// rearranges the visible pane; PaneCreatedMsg continues the queue.
+func (m *Model) resurrectNext() tea.Cmd {
+	for len(m.resurrectQueue) > 0 {
+		l := m.resurrectQueue[0]
+		m.resurrectQueue = m.resurrectQueue[1:]
+
+		proj, err := m.store.GetProject(l.Project.ID)
+		if err != nil {
+			continue
+		}
+
+		sess := l.Session
+		sess.Command = l.Command
+		sess.PaneID = 0
+		sess.Status = domain.StatusUnknown
+		sess.ExitCode = nil
+		sess.IsVisible = false
+		sess.StartedAt = time.Now()
+		if sess.WorkDir != "" {
+			if info, err := os.Stat(pathutil.ExpandPath(sess.WorkDir)); err != nil || !info.IsDir() {
+				sess.WorkDir = ""
+			}
+		}
+		proj.Expanded = true
+		if err := m.store.AddSession(proj.ID, &sess); err != nil {
+			continue
+		}
+		m.syncStore()
+		m.skin.SelectBySessionID(proj.ID, sess.ID)
+
+		debug.Log("resurrect: session=%s project=%s args=%v adapter=%s", sess.ID, proj.Name, l.Plan.Args, l.Plan.Adapter)
+		launched := sess
+		launched.Command.Args = l.Plan.Args
+		return m.createPaneCmd(proj, &launched)
+	}
+	return nil
+}
+
+// resumeLabel describes how a lost session will restart
+func resumeLabel(l lostSession) string {
+	switch {
+	case l.Plan.Adapter != "":
+		return "resume (" + l.Plan.Adapter + ")"
+	case l.Plan.Resume:
+		return "resume (resume_args)"
+	case l.Shared != "":
+		return "restart (shared dir)"
+	default:
+		return "restart"
+	}
+}
+
+// resurrectView renders the restore dialog
+func (m Model) resurrectView() string {
+	var b strings.Builder
+
+	b.WriteString(styleDialogTitle.Render("Restore Sessions"))
+	b.WriteString("\n\n")
+	b.WriteString(fmt.Sprintf("%d session(s) were not running when codely started:\n\n", len(m.lost)))
+
+	for i, l := range m.lost {
+		check := "[ ]"
+		if l.Selected {
+			check = "[x]"
+		}
+		line := fmt.Sprintf("%s %-16s %-14s %s", check,
+			truncate(l.Project.Name, 16),
+			truncate(l.Session.Command.Name(), 14),
+			resumeLabel(l))
+		if i == m.lostIdx {
+			b.WriteString(styleDialogOptionSelected.Render(line))
+		} else {
+			b.WriteString(styleDialogOption.Render(line))
+		}
+		b.WriteString("\n")
+	}
+
+	if m.lostIdx < len(m.lost) {
+		l := m.lost[m.lostIdx]
+		b.WriteString("\n")
+		dir := pathutil.ContractHome(l.Session.WorkDir)
+		if dir == "" {
+			dir = pathutil.ContractHome(l.Project.DisplayPath())
+		}
+		b.WriteString(styleProjectPath.Render(dir + "  $ " + strings.Join(append([]string{l.Command.Exec}, l.Plan.Args...), " ")))
+		b.WriteString("\n")
+		if l.Shared != "" {
+			b.WriteString(styleHelp.Render("A more recent " + l.Shared + " session resumes the last conversation here; this one starts fresh."))
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\n")
+	b.WriteString(styleHelp.Render("[space] toggle  [enter] restore selected  [esc] skip"))
+
+	return styleDialog.Render(b.String())
+}
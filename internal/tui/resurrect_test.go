@@ -0,0 +1,142 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/codely/internal/config"
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/shed"
+	"github.com/charliek/codely/internal/store"
+	"github.com/charliek/codely/internal/tmux"
+)
+
+func TestResurrectLostSessions(t *testing.T) {
+	dir := t.TempDir()
+	subdir := filepath.Join(dir, "web")
+	require.NoError(t, os.Mkdir(subdir, 0755))
+
+	st := store.New(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, st.AddProject(&domain.Project{
+		ID:        "proj-1",
+		Name:      "api",
+		Type:      domain.ProjectTypeLocal,
+		Directory: dir,
+		Sessions:  []domain.Session{},
+	}))
+	before := map[string][]domain.Session{"proj-1": {
+		{ID: "sess-1", ProjectID: "proj-1", Command: domain.Command{ID: "claude", DisplayName: "refactor", Exec: "claude"}},
+		{ID: "sess-2", ProjectID: "proj-1", Command: domain.Command{ID: "bash", DisplayName: "Bash Shell", Exec: "bash"}, WorkDir: subdir},
+		{ID: "sess-3", ProjectID: "proj-1", Command: domain.Command{ID: "codex", Exec: "codex"}},
+	}}
+
+	cfg := config.Default()
+	cfg.Commands["bash"] = config.Command{DisplayName: "Bash Shell", Exec: "bash", Args: []string{"-l"}}
+	tmuxClient := tmux.NewMockClient()
+	model := NewModel(cfg, st, tmuxClient, shed.NewMockClient(), 0, "", SkinTree)
+
+	model.offerResurrect(before)
+	require.Equal(t, ModeResurrect, model.mode)
+	require.Len(t, model.lost, 3)
+	assert.Equal(t, "resume (claude)", resumeLabel(model.lost[0]))
+	assert.Equal(t, "restart", resumeLabel(model.lost[1]))
+	assert.Contains(t, model.resurrectView(), "refactor")
+
+	// Skip the codex session
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	updated, _ = updated.Update(tea.KeyMsg{Type: tea.KeyDown})
+	updated, _ = updated.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	updated, cmd := updated.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m := updated.(Model)
+	assert.Equal(t, ModeNormal, m.mode)
+	require.NotNil(t, cmd)
+	require.Len(t, m.resurrectQueue, 1)
+
+	// The first session is restored with its ID and name, and resumed
+	proj, err := st.GetProject("proj-1")
+	require.NoError(t, err)
+	require.Len(t, proj.Sessions, 1)
+	assert.Equal(t, "sess-1", proj.Sessions[0].ID)
+	assert.Equal(t, "refactor", proj.Sessions[0].Command.Name())
+	assert.Equal(t, []string{"--dangerously-skip-permissions"}, proj.Sessions[0].Command.Args)
+
+	msg := cmd()
+	split := findCall(t, tmuxClient, "SplitPane")
+	assert.Equal(t, dir, split.Args[2])
+	assert.Equal(t, "claude", split.Args[4])
+	assert.Equal(t, []string{"--dangerously-skip-permissions", "--continue"}, split.Args[5])
+
+	// The next session starts once the first pane exists, in its last directory
+	tmuxClient.Calls = nil
+	updated, cmd = m.Update(msg)
+	m = updated.(Model)
+	assert.Empty(t, m.resurrectQueue)
+	require.Len(t, proj.Sessions, 2)
+	assert.Equal(t, "sess-2", proj.Sessions[1].ID)
+
+	runBatch(cmd)
+	split = findCall(t, tmuxClient, "SplitPane")
+	assert.Equal(t, subdir, split.Args[2])
+	assert.Equal(t, "bash", split.Args[4])
+	assert.Equal(t, []string{"-l"}, split.Args[5])
+}
+
+func TestResurrectOneResumePerDirectory(t *testing.T) {
+	dir := t.TempDir()
+	st := store.New(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, st.AddProject(&domain.Project{ID: "proj-1", Name: "api", Type: domain.ProjectTypeLocal, Directory: dir}))
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	claude := domain.Command{ID: "claude", Exec: "claude"}
+	before := map[string][]domain.Session{"proj-1": {
+		{ID: "sess-1", ProjectID: "proj-1", Command: claude, StartedAt: start},
+		{ID: "sess-2", ProjectID: "proj-1", Command: claude, StartedAt: start.Add(time.Hour)},
+		{ID: "sess-3", ProjectID: "proj-1", Command: claude, StartedAt: start, WorkDir: filepath.Join(dir, "web")},
+		{ID: "sess-4", ProjectID: "proj-1", Command: domain.Command{ID: "codex", Exec: "codex"}, StartedAt: start},
+	}}
+
+	model := NewModel(config.Default(), st, tmux.NewMockClient(), shed.NewMockClient(), 0, "", SkinTree)
+	lost := model.droppedSessions(before)
+	require.Len(t, lost, 4)
+
+	// The most recent claude session in the project directory resumes; the
+	// older one restarts. Other directories and tools resume on their own.
+	assert.Equal(t, "restart (shared dir)", resumeLabel(lost[0]))
+	assert.Equal(t, []string{"--dangerously-skip-permissions"}, lost[0].Plan.Args)
+	assert.Equal(t, "resume (claude)", resumeLabel(lost[1]))
+	assert.Equal(t, "resume (claude)", resumeLabel(lost[2]))
+	assert.Equal(t, "resume (codex)", resumeLabel(lost[3]))
+
+	model.lost = lost
+	model.lostIdx = 0
+	assert.Contains(t, model.resurrectView(), "A more recent claude session resumes")
+}
+
+// findCall returns the first recorded call of method
+func findCall(t *testing.T, m *tmux.MockClient, method string) tmux.MockCall {
+	t.Helper()
+	for _, c := range m.Calls {
+		if c.Method == method {
+			return c
+		}
+	}
+	require.Failf(t, "call not found", "no %s call in %v", method, m.Calls)
+	return tmux.MockCall{}
+}
+
+// runBatch runs cmd and, if it is a batch, each command in it
+func runBatch(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	if batch, ok := cmd().(tea.BatchMsg); ok {
+		for _, c := range batch {
+			runBatch(c)
+		}
+	}
+}
@@ -127,7 +127,7 @@ func (s *TreeSkin) renderProject(m *Model, proj *domain.Project) string {
 	name := styleProjectName.Render(proj.Name)
 	line := fmt.Sprintf("%s %s%s%s", indicator, name, countStr, stoppedStr)
 
-	if proj.Expanded && s.config.UI.ShowDirectory {
+	if proj.Expanded && s.config.UI.ShowsDirectory() {
 		path := pathutil.ContractHome(proj.DisplayPath())
 		line = fmt.Sprintf("%s\n    %s", line, styleProjectPath.Render(path))
 	}
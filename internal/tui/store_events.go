@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/charliek/codely/internal/debug"
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/status"
+	"github.com/charliek/codely/internal/statuslog"
+	"github.com/charliek/codely/internal/store"
+)
+
+// storeChanges records store events until the model syncs them. It is
+// shared by pointer because bubbletea copies the model on every update.
+type storeChanges struct {
+	save    bool // Persisted state changed
+	rebuild bool // The skin needs rebuilding
+	notify  bool // Tmux notifications need refreshing
+}
+
+// record is the model's store subscriber. Status is runtime state, so a
+// status change alone does not save the state.
+func (c *storeChanges) record(e store.Event) {
+	debug.Log("store event: type=%s project=%s session=%s", e.Type, e.ProjectID, e.SessionID)
+	if e.Type != store.EventStatusChanged {
+		c.save = true
+	}
+	c.rebuild = true
+	c.notify = true
+}
+
+// syncStore reacts to store changes since the last sync: the state is
+// saved, the skin rebuilt and tmux notifications refreshed. Update calls it
+// after every message; handlers that need the rebuilt skin right away, for
+// example to select a new session, call it directly.
+func (m *Model) syncStore() {
+	if m.changes == nil {
+		return
+	}
+	if m.changes.save {
+		m.changes.save = false
+		if err := m.store.Save(); err != nil {
+			debug.Log("saving state: %v", err)
+		}
+	}
+	if m.changes.rebuild {
+		m.changes.rebuild = false
+		m.skin.SetProjects(m.store.Projects())
+	}
+	if m.changes.notify {
+		m.changes.notify = false
+		m.updateTmuxNotifications()
+	}
+}
+
+// logStatusChanges returns a store subscriber that appends every session
+// status change to the status log at path. A session removed while active,
+// e.g. closed, is logged as exited so its last status stops counting.
+func logStatusChanges(st *store.Store, path string) func(store.Event) {
+	return func(e store.Event) {
+		from, to := e.OldStatus, e.Session.Status
+		switch {
+		case e.Type == store.EventStatusChanged:
+		case e.Type == store.EventSessionRemoved && statuslog.Active(e.Session.Status):
+			from, to = e.Session.Status, domain.StatusExited
+		default:
+			return
+		}
+		proj, err := st.GetProject(e.ProjectID)
+		if err != nil {
+			return
+		}
+		t := status.NewTransition(time.Now(), proj, &e.Session, from, to, e.Session.ExitCode)
+		if err := statuslog.Append(path, t); err != nil {
+			debug.Log("logging status change: %v", err)
+		}
+	}
+}
+
+// logShutdown records that codely stopped observing sessions: each active
+// session moves to unknown, so its status does not count while codely is
+// not running
+func logShutdown(st *store.Store, path string) {
+	at := time.Now()
+	var transitions []status.Transition
+	for _, proj := range st.Projects() {
+		for i := range proj.Sessions {
+			sess := &proj.Sessions[i]
+			if statuslog.Active(sess.Status) {
+				transitions = append(transitions, status.NewTransition(at, proj, sess, sess.Status, domain.StatusUnknown, nil))
+			}
+		}
+	}
+	if err := statuslog.Append(path, transitions...); err != nil {
+		debug.Log("logging shutdown: %v", err)
+	}
+}
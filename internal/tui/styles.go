@@ -36,6 +36,9 @@ var (
 			Foreground(colorError).
 			Bold(true)
 
+	styleNotice = lipgloss.NewStyle().
+			Foreground(colorWarning)
+
 	styleHelp = lipgloss.NewStyle().
 			Foreground(colorMuted)
 )
@@ -1,14 +1,23 @@
 package tui
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/charliek/codely/internal/config"
 	"github.com/charliek/codely/internal/debug"
 	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/git"
+	"github.com/charliek/codely/internal/pathutil"
 	"github.com/charliek/codely/internal/shed"
 )
 
@@ -16,13 +25,26 @@ import (
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		m.statusPollCmd(),
-		m.loadFoldersCmd(),
+		m.loadCachedFoldersCmd(),
+		m.scanFoldersCmd(),
 		m.syncVisibilityCmd(),
+		m.configWatchCmd(m.configModTime),
 	)
 }
 
-// Update handles messages and updates the model
+// Update handles messages and updates the model, then syncs any store
+// changes they made
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	if nm, ok := next.(Model); ok {
+		nm.syncStore()
+		next = nm
+	}
+	return next, cmd
+}
+
+// update dispatches a message to its handler
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
@@ -33,10 +55,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
-		// Clear error on any key
+		// Clear error and notice on any key
 		if m.err != nil {
 			m.err = nil
 		}
+		m.notice = ""
 
 		return m.handleKey(msg)
 
@@ -45,14 +68,29 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, m.pollStatusCmd(), m.statusPollCmd())
 
 	case StatusUpdateMsg:
-		m.applyStatusUpdates(msg.Updates)
-		m.applyExitCodeUpdates(msg.ExitCodes)
+		m.applyStatusUpdates(msg.Updates, msg.ExitCodes)
+		m.applyWorkDirs(msg.Dirs)
 
 	case FoldersLoadedMsg:
-		if msg.Err != nil {
+		switch {
+		case msg.Err != nil:
 			m.err = msg.Err
-		} else {
+		case !msg.Options.Equal(m.discoveryOptions()):
+			// Found with settings a config reload has since replaced; the
+			// scan started by the reload delivers the current folders
+			debug.Log("FoldersLoadedMsg: dropping folders from stale options")
+		case msg.Cached:
+			// A scan that already finished is fresher than the cache
+			if !m.foldersScanned {
+				m.folders = msg.Folders
+			}
+		default:
 			m.folders = msg.Folders
+			m.foldersScanned = true
+			m.foldersScanning = false
+		}
+		if m.folderIdx >= len(m.filteredFolders()) {
+			m.folderIdx = 0
 		}
 
 	case ShedsLoadedMsg:
@@ -115,8 +153,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case ProjectCreatedMsg:
 		m.handleProjectCreated(msg.Project)
 		// Immediately show command picker
-		m.mode = ModeCommandPicker
-		m.commandIdx = m.defaultCommandIndex()
+		m.openCommandPicker(msg.Project)
 
 	case PaneCreatedMsg:
 		if msg.Err != nil {
@@ -130,6 +167,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, m.focusPaneCmd(msg.PaneID))
 		}
 		m.mode = ModeNormal
+		if len(m.resurrectQueue) > 0 {
+			cmds = append(cmds, m.resurrectNext())
+		}
 
 	case PaneKilledMsg:
 		if msg.Err != nil {
@@ -196,6 +236,32 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// On success, just reload sheds — ShedsLoadedMsg will find the new shed
 		cmds = append(cmds, m.loadShedsCmd())
 
+	case cloneStartedMsg:
+		m.cloneCmd = msg.cmdLine
+		return m, waitForCloneOutput(msg.dir, msg.outputCh, msg.doneCh)
+
+	case cloneOutputMsg:
+		m.cloneOutput = appendCloneOutput(m.cloneOutput, msg.line)
+		const maxCloneOutputLines = 200
+		if len(m.cloneOutput) > maxCloneOutputLines {
+			m.cloneOutput = m.cloneOutput[len(m.cloneOutput)-maxCloneOutputLines:]
+		}
+		return m, waitForCloneOutput(msg.dir, msg.outputCh, msg.doneCh)
+
+	case RepoClonedMsg:
+		m.cloneCancel = nil
+		if errors.Is(msg.Err, context.Canceled) {
+			// Esc already left the clone screen
+			break
+		}
+		if msg.Err != nil {
+			m.err = msg.Err
+			m.mode = ModeNormal
+			break
+		}
+		// The new project opens the command picker once created
+		cmds = append(cmds, m.createProjectCmd(msg.Directory), m.refreshFoldersCmd())
+
 	case ShedDeletedMsg:
 		if msg.Err != nil {
 			m.err = msg.Err
@@ -203,6 +269,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, m.loadShedsCmd())
 		m.mode = ModeNormal
 
+	case controlRequestMsg:
+		return m.handleControlRequest(msg)
+
+	case ConfigReloadedMsg:
+		cmds = append(cmds, m.handleConfigReloaded(msg))
+
+	case WorkspaceSwitchedMsg:
+		if msg.Err != nil {
+			m.err = msg.Err
+		}
+
 	case ErrorMsg:
 		m.err = msg.Err
 
@@ -239,6 +316,24 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleHelpKey(msg)
 	case ModeNewProjectType:
 		return m.handleNewProjectTypeKey(msg)
+	case ModeSendInput:
+		return m.handleSendInputKey(msg)
+	case ModeCloneRepo:
+		return m.handleCloneRepoKey(msg)
+	case ModeCloning:
+		// Esc stops the clone; other keys are ignored while it runs
+		if msg.String() == "esc" && m.cloneCancel != nil {
+			m.cloneCancel()
+			m.cloneCancel = nil
+			m.mode = ModeNormal
+		}
+		return m, nil
+	case ModeHistory:
+		return m.handleHistoryKey(msg)
+	case ModeWorkspaces:
+		return m.handleWorkspaceKey(msg)
+	case ModeResurrect:
+		return m.handleResurrectKey(msg)
 	}
 	return m, nil
 }
@@ -268,29 +363,50 @@ func (m Model) handleNormalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleEnter()
 
 	case key.Matches(msg, m.keys.NewProject):
-		// Show project type selector if shed is available
-		if m.shed != nil && m.shed.Available() {
+		// Show project type selector if cloning or shed is available
+		shedReady := m.config.Shed.IsEnabled() && m.shed != nil && m.shed.Available()
+		canClone := m.git != nil && m.git.Available()
+		if shedReady || canClone {
+			m.newProjectTypes = []newProjectType{newProjectLocal}
+			if canClone {
+				m.newProjectTypes = append(m.newProjectTypes, newProjectClone)
+			}
 			m.mode = ModeNewProjectType
 			m.newProjectTypeIdx = 0
-			return m, m.loadShedsCmd()
+			if shedReady {
+				m.newProjectTypes = append(m.newProjectTypes, newProjectAttachShed, newProjectCreateShed)
+				return m, m.loadShedsCmd()
+			}
+			return m, nil
 		}
 		// Otherwise go straight to folder picker
 		m.mode = ModeFolderPicker
 		m.folderIdx = 0
+		return m, m.refreshFoldersCmd()
+
+	case key.Matches(msg, m.keys.History):
+		if m.historyPath != "" {
+			m.openHistory()
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Workspaces):
+		m.openWorkspaces()
 		return m, nil
 
 	case key.Matches(msg, m.keys.AddTerminal):
 		proj := m.SelectedProject()
 		if proj != nil {
-			m.pendingProject = proj
-			m.mode = ModeCommandPicker
-			m.commandIdx = m.defaultCommandIndex()
+			m.openCommandPicker(proj)
 		}
 		return m, nil
 
 	case key.Matches(msg, m.keys.Rename):
 		return m.startRenameSession()
 
+	case key.Matches(msg, m.keys.SendInput):
+		return m.startSendInput()
+
 	case key.Matches(msg, m.keys.Close):
 		return m.handleClose()
 
@@ -333,16 +449,7 @@ func (m Model) handleRenameKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		name := strings.TrimSpace(m.renameInput.Value())
-		if name == "" {
-			name = m.defaultSessionName(sess)
-		}
-
-		sess.Command.DisplayName = name
-		_ = m.store.Save()
-		m.skin.SetProjects(m.store.Projects())
-		m.skin.SelectBySessionID(proj.ID, sess.ID)
-		m.updateTmuxNotifications()
+		m.renameSession(proj, sess, m.renameInput.Value())
 		m.clearRenameState()
 		m.mode = ModeNormal
 		return m, nil
@@ -353,6 +460,29 @@ func (m Model) handleRenameKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 }
 
+// handleSendInputKey handles keys in the send input dialog.
+func (m Model) handleSendInputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.clearSendInputState()
+		m.mode = ModeNormal
+		return m, nil
+	case tea.KeyEnter:
+		_, sess := m.sendInputTarget()
+		text := m.sendInput.Value()
+		m.clearSendInputState()
+		m.mode = ModeNormal
+		if sess == nil || sess.PaneID == 0 {
+			return m, nil
+		}
+		return m, m.sendKeysCmd(sess.PaneID, text, true)
+	default:
+		var cmd tea.Cmd
+		m.sendInput, cmd = m.sendInput.Update(msg)
+		return m, cmd
+	}
+}
+
 // handleEnter processes Enter key in normal mode
 func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 	proj := m.skin.SelectedProject()
@@ -364,51 +494,56 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 
 	if m.skin.IsSessionSelected() && sess != nil {
 		if sess.Status == domain.StatusExited {
+			m.archiveSessions(proj, *sess)
 			_ = m.store.RemoveSession(proj.ID, sess.ID)
-			_ = m.store.Save()
-			m.skin.SetProjects(m.store.Projects())
 			return m, nil
 		}
 
-		// Check if session has a pane
-		if sess.PaneID == 0 {
-			return m, nil
-		}
+		return m, m.focusSessionCmd(proj, sess)
+	}
 
-		// If session is already visible, just focus it
-		if sess.IsVisible {
-			return m, m.focusPaneCmd(sess.PaneID)
-		}
+	// Toggle project expand/collapse
+	m.skin.ToggleProject()
+	return m, nil
+}
 
-		// Session is hidden - need to swap panes
-		// Find the currently visible session globally
-		var visibleSession *domain.Session
-		var visibleProject *domain.Project
-		for _, p := range m.store.Projects() {
-			for i := range p.Sessions {
-				s := &p.Sessions[i]
-				if s.ID != sess.ID && s.PaneID > 0 && s.IsVisible &&
-					s.Status != domain.StatusExited && s.Status != domain.StatusError {
-					visibleSession = s
-					visibleProject = p
-					break
-				}
-			}
-			if visibleSession != nil {
+// focusSessionCmd focuses a session's pane, swapping it into the main window
+// if it is currently hidden. Returns nil if the session has no pane.
+func (m *Model) focusSessionCmd(proj *domain.Project, sess *domain.Session) tea.Cmd {
+	// Check if session has a pane
+	if sess.PaneID == 0 {
+		return nil
+	}
+
+	// If session is already visible, just focus it
+	if sess.IsVisible {
+		return m.focusPaneCmd(sess.PaneID)
+	}
+
+	// Session is hidden - need to swap panes
+	// Find the currently visible session globally
+	var visibleSession *domain.Session
+	var visibleProject *domain.Project
+	for _, p := range m.store.Projects() {
+		for i := range p.Sessions {
+			s := &p.Sessions[i]
+			if s.ID != sess.ID && s.PaneID > 0 && s.IsVisible &&
+				s.Status != domain.StatusExited && s.Status != domain.StatusError {
+				visibleSession = s
+				visibleProject = p
 				break
 			}
 		}
-
 		if visibleSession != nil {
-			return m, m.swapPanesCmd(proj, sess, visibleProject, visibleSession)
+			break
 		}
+	}
 
-		return m, m.showPaneCmd(proj, sess)
+	if visibleSession != nil {
+		return m.swapPanesCmd(proj, sess, visibleProject, visibleSession)
 	}
 
-	// Toggle project expand/collapse
-	m.skin.ToggleProject()
-	return m, nil
+	return m.showPaneCmd(proj, sess)
 }
 
 // handleClose processes close action
@@ -422,9 +557,8 @@ func (m Model) handleClose() (tea.Model, tea.Cmd) {
 
 	if m.skin.IsSessionSelected() && sess != nil {
 		if sess.PaneID == 0 || !m.tmux.PaneExists(sess.PaneID) {
+			m.archiveSessions(proj, *sess)
 			_ = m.store.RemoveSession(proj.ID, sess.ID)
-			_ = m.store.Save()
-			m.skin.SetProjects(m.store.Projects())
 			return m, nil
 		}
 
@@ -442,8 +576,6 @@ func (m Model) handleClose() (tea.Model, tea.Cmd) {
 		} else {
 			// No sessions, just remove project
 			_ = m.store.RemoveProject(proj.ID)
-			_ = m.store.Save()
-			m.skin.SetProjects(m.store.Projects())
 		}
 	}
 
@@ -472,8 +604,6 @@ func (m Model) handleCloseAll() (tea.Model, tea.Cmd) {
 		m.mode = ModeConfirm
 	} else {
 		_ = m.store.RemoveProject(proj.ID)
-		_ = m.store.Save()
-		m.skin.SetProjects(m.store.Projects())
 	}
 
 	return m, nil
@@ -570,6 +700,7 @@ func (m Model) handleCommandPickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case key.Matches(msg, m.keys.Cancel):
 		m.mode = ModeNormal
 		m.pendingProject = nil
+		m.pickerConfig = nil
 		return m, nil
 
 	case key.Matches(msg, m.keys.Up):
@@ -592,26 +723,30 @@ func (m Model) handleCommandPickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 
 		// Create session with selected command
-		cmdID := m.commandKeys[m.commandIdx]
-		cmd := m.config.Commands[cmdID].ToDomainCommand(cmdID)
-		session := newSession(proj.ID, cmdID, cmd)
-
-		// Add to store
-		_ = m.store.AddSession(proj.ID, session)
-		_ = m.store.Save()
-
-		// Expand the project before rebuilding so new session is visible
-		proj.Expanded = true
-		m.skin.SetProjects(m.store.Projects())
-		m.skin.SelectBySessionID(proj.ID, session.ID)
-
+		_, cmd := m.launchSession(proj, m.commandKeys[m.commandIdx])
 		m.pendingProject = nil
-		return m, m.createPaneCmd(proj, session)
+		m.pickerConfig = nil
+		return m, cmd
 	}
 
 	return m, nil
 }
 
+// launchSession adds a new session running the given command to a project and
+// returns the command that creates its pane.
+func (m *Model) launchSession(proj *domain.Project, cmdID string) (*domain.Session, tea.Cmd) {
+	cmd := m.projectConfig(proj).Commands[cmdID].ToDomainCommand(cmdID)
+	session := newSession(proj.ID, cmdID, cmd)
+
+	// Expand the project before rebuilding so new session is visible
+	proj.Expanded = true
+	_ = m.store.AddSession(proj.ID, session)
+	m.syncStore()
+	m.skin.SelectBySessionID(proj.ID, session.ID)
+
+	return session, m.createPaneCmd(proj, session)
+}
+
 // handleShedPickerKey handles keys in shed picker mode
 func (m Model) handleShedPickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch {
@@ -784,9 +919,11 @@ func (m Model) handleShedCloseKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		switch m.shedCloseOption {
 		case 0:
 			// Close project only
+			m.archiveSessions(proj, proj.Sessions...)
 			_ = m.store.RemoveProject(proj.ID)
 		case 1:
 			// Close and stop shed
+			m.archiveSessions(proj, proj.Sessions...)
 			_ = m.store.RemoveProject(proj.ID)
 			cmds = append(cmds, m.stopShedCmd(proj.ShedName))
 		case 2:
@@ -796,8 +933,6 @@ func (m Model) handleShedCloseKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		_ = m.store.Save()
-		m.skin.SetProjects(m.store.Projects())
 		m.mode = ModeNormal
 		m.confirmProject = nil
 
@@ -849,20 +984,32 @@ func (m Model) handleNewProjectTypeKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case key.Matches(msg, m.keys.Down):
-		if m.newProjectTypeIdx < 2 {
+		if m.newProjectTypeIdx < len(m.newProjectTypes)-1 {
 			m.newProjectTypeIdx++
 		}
 		return m, nil
 
 	case key.Matches(msg, m.keys.Enter):
-		switch m.newProjectTypeIdx {
-		case 0:
+		if m.newProjectTypeIdx >= len(m.newProjectTypes) {
+			return m, nil
+		}
+		switch m.newProjectTypes[m.newProjectTypeIdx] {
+		case newProjectLocal:
 			m.mode = ModeFolderPicker
 			m.folderIdx = 0
-		case 1:
+			return m, m.refreshFoldersCmd()
+		case newProjectClone:
+			m.mode = ModeCloneRepo
+			m.cloneRepo.SetValue("")
+			m.cloneRoot = 0
+			m.cloneFocus = 0
+			m.cloneRepo.Focus()
+			m.err = nil
+			return m, nil
+		case newProjectAttachShed:
 			m.mode = ModeShedPicker
 			m.shedIdx = 0
-		case 2:
+		case newProjectCreateShed:
 			m.mode = ModeShedCreate
 			m.shedCreateFocus = 0
 			m.shedCreateBackend = 0
@@ -879,6 +1026,91 @@ func (m Model) handleNewProjectTypeKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// handleCloneRepoKey handles keys in the clone repository form
+func (m Model) handleCloneRepoKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	roots := m.config.WorkspaceRoots
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.mode = ModeNormal
+		return m, nil
+
+	case tea.KeyTab, tea.KeyShiftTab, tea.KeyDown, tea.KeyUp:
+		if len(roots) > 1 {
+			m.cloneFocus = 1 - m.cloneFocus
+			if m.cloneFocus == 0 {
+				m.cloneRepo.Focus()
+			} else {
+				m.cloneRepo.Blur()
+			}
+		}
+		return m, nil
+
+	case tea.KeyLeft, tea.KeyRight:
+		if m.cloneFocus == 1 {
+			step := 1
+			if msg.Type == tea.KeyLeft {
+				step = len(roots) - 1
+			}
+			m.cloneRoot = (m.cloneRoot + step) % len(roots)
+			return m, nil
+		}
+
+	case tea.KeyEnter:
+		return m.startClone()
+	}
+
+	var cmd tea.Cmd
+	if m.cloneFocus == 0 {
+		m.cloneRepo, cmd = m.cloneRepo.Update(msg)
+	}
+	return m, cmd
+}
+
+// startClone validates the clone form and starts cloning into the selected
+// workspace root. Problems are shown as errors and leave the form open.
+func (m Model) startClone() (tea.Model, tea.Cmd) {
+	url, err := git.ResolveURL(m.cloneRepo.Value())
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+	roots := m.config.WorkspaceRoots
+	if len(roots) == 0 {
+		m.err = fmt.Errorf("no workspace_roots configured to clone into")
+		return m, nil
+	}
+	root := pathutil.ExpandPath(roots[min(m.cloneRoot, len(roots)-1)])
+
+	dir := filepath.Join(root, git.RepoName(url))
+	if _, err := os.Stat(dir); err == nil {
+		m.err = fmt.Errorf("%s already exists", pathutil.ContractHome(dir))
+		return m, nil
+	}
+
+	m.err = nil
+	m.cloneDir = dir
+	m.cloneCmd = ""
+	m.cloneOutput = nil
+	m.mode = ModeCloning
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cloneCancel = cancel
+	return m, m.cloneRepoCmd(ctx, url, dir)
+}
+
+// appendCloneOutput adds a progress line, replacing the previous line when
+// both are updates of the same counter, e.g. "Receiving objects:  45%"
+func appendCloneOutput(lines []string, line string) []string {
+	if n := len(lines); n > 0 && strings.Contains(line, "%") {
+		label, _, _ := strings.Cut(line, ":")
+		if prevLabel, _, ok := strings.Cut(lines[n-1], ":"); ok && prevLabel == label {
+			lines[n-1] = line
+			return lines
+		}
+	}
+	return append(lines, line)
+}
+
 // executeConfirmedAction executes the confirmed action
 func (m Model) executeConfirmedAction() (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
@@ -886,10 +1118,7 @@ func (m Model) executeConfirmedAction() (tea.Model, tea.Cmd) {
 	switch m.confirmAction {
 	case ConfirmCloseSession:
 		if m.confirmSession != nil && m.confirmProject != nil {
-			cmds = append(cmds, m.killPaneCmd(m.confirmProject, m.confirmSession))
-			_ = m.store.RemoveSession(m.confirmProject.ID, m.confirmSession.ID)
-			_ = m.store.Save()
-			m.skin.SetProjects(m.store.Projects())
+			cmds = append(cmds, m.closeSession(m.confirmProject, m.confirmSession))
 		}
 
 	case ConfirmCloseProject:
@@ -900,9 +1129,8 @@ func (m Model) executeConfirmedAction() (tea.Model, tea.Cmd) {
 					cmds = append(cmds, m.killPaneCmd(m.confirmProject, &m.confirmProject.Sessions[i]))
 				}
 			}
+			m.archiveSessions(m.confirmProject, m.confirmProject.Sessions...)
 			_ = m.store.RemoveProject(m.confirmProject.ID)
-			_ = m.store.Save()
-			m.skin.SetProjects(m.store.Projects())
 		}
 
 	case ConfirmDeleteShed:
@@ -913,9 +1141,8 @@ func (m Model) executeConfirmedAction() (tea.Model, tea.Cmd) {
 					cmds = append(cmds, m.killPaneCmd(m.confirmProject, &m.confirmProject.Sessions[i]))
 				}
 			}
+			m.archiveSessions(m.confirmProject, m.confirmProject.Sessions...)
 			_ = m.store.RemoveProject(m.confirmProject.ID)
-			_ = m.store.Save()
-			m.skin.SetProjects(m.store.Projects())
 			cmds = append(cmds, m.deleteShedCmd(m.confirmProject.ShedName, true))
 		}
 	}
@@ -928,96 +1155,110 @@ func (m Model) executeConfirmedAction() (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// closeSession removes a session from its project and returns the command
+// that kills its pane.
+func (m *Model) closeSession(proj *domain.Project, sess *domain.Session) tea.Cmd {
+	// Copy before removal: sess points into the project's session slice
+	closed := *sess
+	m.archiveSessions(proj, closed)
+	cmd := m.killPaneCmd(proj, &closed)
+	_ = m.store.RemoveSession(proj.ID, closed.ID)
+	return cmd
+}
+
+// renameSession sets a session's display name. A blank name resets it to
+// the command's default name.
+func (m *Model) renameSession(proj *domain.Project, sess *domain.Session, name string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = m.defaultSessionName(sess)
+	}
+
+	_ = m.store.UpdateSession(proj.ID, sess.ID, func(s *domain.Session) {
+		s.Command.DisplayName = name
+	})
+	m.syncStore()
+	m.skin.SelectBySessionID(proj.ID, sess.ID)
+}
+
 // Helper methods
 
-func (m *Model) applyStatusUpdates(updates map[string]domain.Status) {
+// updateSessions applies fn to every stored session through the store, so
+// only sessions fn actually changes produce events
+func (m *Model) updateSessions(fn func(proj *domain.Project, sess *domain.Session)) {
 	for _, proj := range m.store.Projects() {
-		for i := range proj.Sessions {
-			if status, ok := updates[proj.Sessions[i].ID]; ok {
-				proj.Sessions[i].Status = status
-				if status != domain.StatusError {
-					proj.Sessions[i].ExitCode = nil
-				}
-				if status == domain.StatusExited {
-					proj.Sessions[i].IsVisible = false
-				}
-			}
+		for _, sess := range proj.Sessions {
+			_ = m.store.UpdateSession(proj.ID, sess.ID, func(s *domain.Session) {
+				fn(proj, s)
+			})
 		}
 	}
+}
 
-	m.updateTmuxNotifications()
+// applyStatusUpdates records detected statuses and exit codes together, so
+// a status change event carries the exit code that came with it
+func (m *Model) applyStatusUpdates(updates map[string]domain.Status, codes map[string]*int) {
+	m.updateSessions(func(_ *domain.Project, sess *domain.Session) {
+		if status, ok := updates[sess.ID]; ok {
+			sess.Status = status
+			if status != domain.StatusError {
+				sess.ExitCode = nil
+			}
+			if status == domain.StatusExited {
+				sess.IsVisible = false
+			}
+		}
+		if code, ok := codes[sess.ID]; ok {
+			sess.ExitCode = code
+		}
+	})
 }
 
-func (m *Model) applyExitCodeUpdates(codes map[string]*int) {
-	if len(codes) == 0 {
+// applyWorkDirs records where local sessions are working
+func (m *Model) applyWorkDirs(dirs map[string]string) {
+	if len(dirs) == 0 {
 		return
 	}
-	for _, proj := range m.store.Projects() {
-		for i := range proj.Sessions {
-			if code, ok := codes[proj.Sessions[i].ID]; ok {
-				proj.Sessions[i].ExitCode = code
-			}
+	m.updateSessions(func(proj *domain.Project, sess *domain.Session) {
+		if dir, ok := dirs[sess.ID]; ok && proj.Type == domain.ProjectTypeLocal {
+			sess.WorkDir = dir
 		}
-	}
+	})
 }
 
 func (m *Model) handleProjectCreated(proj *domain.Project) {
 	_ = m.store.AddProject(proj)
-	_ = m.store.Save()
-	m.skin.SetProjects(m.store.Projects())
+	if m.frecency != nil && proj.Type == domain.ProjectTypeLocal {
+		m.frecency.Visit(proj.Directory)
+		if err := m.frecency.Save(); err != nil {
+			debug.Log("saving frecency: %v", err)
+		}
+	}
+	m.syncStore()
 	m.skin.SelectByProjectID(proj.ID)
 	m.pendingProject = proj
 }
 
 func (m *Model) handlePaneCreated(msg PaneCreatedMsg) {
 	debug.Log("handlePaneCreated: session=%s paneID=%d hiddenSession=%s hiddenPaneID=%d detectedWidth=%d", msg.SessionID, msg.PaneID, msg.HiddenSessionID, msg.HiddenPaneID, msg.DetectedWidth)
-	proj, err := m.store.GetProject(msg.ProjectID)
-	if err != nil {
+	if _, err := m.store.GetProject(msg.ProjectID); err != nil {
 		return
 	}
 
-	// Clear visibility for all sessions (single visible pane)
-	for _, p := range m.store.Projects() {
-		for i := range p.Sessions {
-			p.Sessions[i].IsVisible = false
-		}
-	}
-
-	// Update the hidden session's pane ID and visibility (if any was hidden)
-	if msg.HiddenSessionID != "" {
-		if msg.HiddenProjectID != "" {
-			if hiddenProj, err := m.store.GetProject(msg.HiddenProjectID); err == nil {
-				for i := range hiddenProj.Sessions {
-					if hiddenProj.Sessions[i].ID == msg.HiddenSessionID {
-						hiddenProj.Sessions[i].PaneID = msg.HiddenPaneID
-						hiddenProj.Sessions[i].IsVisible = false
-						break
-					}
-				}
-			}
-		} else {
-			for _, p := range m.store.Projects() {
-				for i := range p.Sessions {
-					if p.Sessions[i].ID == msg.HiddenSessionID {
-						p.Sessions[i].PaneID = msg.HiddenPaneID
-						p.Sessions[i].IsVisible = false
-						break
-					}
-				}
-			}
-		}
-	}
-
-	// Update the new session's pane ID and mark it as visible
-	for i := range proj.Sessions {
-		if proj.Sessions[i].ID == msg.SessionID {
-			proj.Sessions[i].PaneID = msg.PaneID
-			proj.Sessions[i].IsVisible = true
-			break
+	// Single visible pane: the new session is shown and every other session
+	// hidden, including the one it replaced (if any)
+	m.updateSessions(func(proj *domain.Project, sess *domain.Session) {
+		switch {
+		case proj.ID == msg.ProjectID && sess.ID == msg.SessionID:
+			sess.PaneID = msg.PaneID
+			sess.IsVisible = true
+		case sess.ID == msg.HiddenSessionID && (msg.HiddenProjectID == "" || proj.ID == msg.HiddenProjectID):
+			sess.PaneID = msg.HiddenPaneID
+			sess.IsVisible = false
+		default:
+			sess.IsVisible = false
 		}
-	}
-	_ = m.store.Save()
-	m.skin.SetProjects(m.store.Projects())
+	})
 }
 
 func (m *Model) handlePaneKilled(msg PaneKilledMsg) {
@@ -1027,73 +1268,176 @@ func (m *Model) handlePaneKilled(msg PaneKilledMsg) {
 
 func (m *Model) handlePaneSwapped(msg PaneSwappedMsg) {
 	debug.Log("handlePaneSwapped: shown=%s shownPaneID=%d hidden=%s hiddenPaneID=%d detectedWidth=%d", msg.ShownSessionID, msg.ShownPaneID, msg.HiddenSessionID, msg.HiddenPaneID, msg.DetectedWidth)
-	// Clear visibility for all sessions (single visible pane)
-	for _, p := range m.store.Projects() {
-		for i := range p.Sessions {
-			p.Sessions[i].IsVisible = false
+	// Single visible pane: only the shown session stays visible
+	m.updateSessions(func(proj *domain.Project, sess *domain.Session) {
+		switch {
+		case msg.ShownProjectID != "" && proj.ID == msg.ShownProjectID && sess.ID == msg.ShownSessionID:
+			sess.PaneID = msg.ShownPaneID
+			sess.IsVisible = true
+		case msg.HiddenSessionID != "" && proj.ID == msg.HiddenProjectID && sess.ID == msg.HiddenSessionID:
+			sess.PaneID = msg.HiddenPaneID
+			sess.IsVisible = false
+		default:
+			sess.IsVisible = false
 		}
-	}
+	})
+}
 
-	if msg.ShownProjectID != "" {
-		if shownProj, err := m.store.GetProject(msg.ShownProjectID); err == nil {
-			for i := range shownProj.Sessions {
-				if shownProj.Sessions[i].ID == msg.ShownSessionID {
-					shownProj.Sessions[i].PaneID = msg.ShownPaneID
-					shownProj.Sessions[i].IsVisible = true
-					break
-				}
+func (m *Model) handleVisibilitySynced(msg VisibilitySyncedMsg) {
+	debug.Log("handleVisibilitySynced: visibleSession=%s", msg.VisibleSessionID)
+	m.updateSessions(func(_ *domain.Project, sess *domain.Session) {
+		sess.IsVisible = sess.ID == msg.VisibleSessionID
+	})
+}
+
+// Folder picker ranking
+const (
+	maxRecentFolders = 5  // Entries in the recent section
+	maxFrecencyBoost = 10 // Cap on the frecency added to a search match score
+)
+
+// filteredFolders returns the folder picker entries in display order.
+// Without a search, recently opened folders come first and the rest are
+// grouped by parent directory. A search fuzzy matches the paths and ranks the
+// results by match quality, boosted by frecency.
+func (m *Model) filteredFolders() []string {
+	if !m.folderSearching || m.folderSearch.Value() == "" {
+		recent := m.recentFolders()
+		rest := make([]string, 0, len(m.folders))
+		for _, f := range m.folders {
+			if !slices.Contains(recent, f) {
+				rest = append(rest, f)
 			}
 		}
+		return append(recent, groupByParent(rest)...)
 	}
 
-	if msg.HiddenSessionID != "" && msg.HiddenProjectID != "" {
-		if hiddenProj, err := m.store.GetProject(msg.HiddenProjectID); err == nil {
-			for i := range hiddenProj.Sessions {
-				if hiddenProj.Sessions[i].ID == msg.HiddenSessionID {
-					hiddenProj.Sessions[i].PaneID = msg.HiddenPaneID
-					hiddenProj.Sessions[i].IsVisible = false
-					break
-				}
-			}
+	type match struct {
+		folder string
+		score  float64
+	}
+	search := m.folderSearch.Value()
+	var matches []match
+	for _, f := range m.folders {
+		score, ok := fuzzyMatch(pathutil.ContractHome(f), search)
+		if !ok {
+			continue
 		}
+		matches = append(matches, match{f, float64(score) + min(m.frecencyScore(f), maxFrecencyBoost)})
+	}
+	slices.SortStableFunc(matches, func(a, b match) int {
+		return cmp.Compare(b.score, a.score)
+	})
+
+	filtered := make([]string, len(matches))
+	for i, mt := range matches {
+		filtered[i] = mt.folder
 	}
-	m.skin.SetProjects(m.store.Projects())
+	return filtered
 }
 
-func (m *Model) handleVisibilitySynced(msg VisibilitySyncedMsg) {
-	debug.Log("handleVisibilitySynced: visibleSession=%s", msg.VisibleSessionID)
-	for _, p := range m.store.Projects() {
-		for i := range p.Sessions {
-			p.Sessions[i].IsVisible = p.Sessions[i].ID == msg.VisibleSessionID
+// recentFolders returns the most frecent listed folders, best first. They
+// head the folder picker when it is not searching.
+func (m *Model) recentFolders() []string {
+	if m.frecency == nil {
+		return nil
+	}
+	listed := make(map[string]bool, len(m.folders))
+	for _, f := range m.folders {
+		listed[f] = true
+	}
+
+	var recent []string
+	for _, f := range m.frecency.Top(len(m.folders)) {
+		if len(recent) == maxRecentFolders {
+			break
+		}
+		if listed[f] {
+			recent = append(recent, f)
 		}
 	}
-	m.skin.SetProjects(m.store.Projects())
+	return recent
 }
 
-func (m *Model) filteredFolders() []string {
-	if !m.folderSearching || m.folderSearch.Value() == "" {
-		return m.folders
+// frecencyScore returns how often and recently a folder was opened
+func (m *Model) frecencyScore(folder string) float64 {
+	if m.frecency == nil {
+		return 0
 	}
+	return m.frecency.Score(folder)
+}
 
-	search := m.folderSearch.Value()
-	var filtered []string
-	for _, f := range m.folders {
-		if containsIgnoreCase(f, search) {
-			filtered = append(filtered, f)
-		}
+// groupByParent orders folders so that siblings are adjacent, keeping the
+// order in which parents first appear
+func groupByParent(folders []string) []string {
+	groups := make(map[string][]string)
+	var order []string
+	for _, f := range folders {
+		parent := filepath.Dir(f)
+		if _, ok := groups[parent]; !ok {
+			order = append(order, parent)
+		}
+		groups[parent] = append(groups[parent], f)
 	}
-	return filtered
+
+	grouped := make([]string, 0, len(folders))
+	for _, parent := range order {
+		grouped = append(grouped, groups[parent]...)
+	}
+	return grouped
 }
 
 func (m *Model) defaultCommandIndex() int {
 	for i, id := range m.commandKeys {
-		if id == m.config.DefaultCommand {
+		if id == m.commandPickerConfig().DefaultCommand {
 			return i
 		}
 	}
 	return 0
 }
 
+// openCommandPicker shows the command picker for a project, listing the
+// commands in effect for it.
+func (m *Model) openCommandPicker(proj *domain.Project) {
+	m.pendingProject = proj
+	m.pickerConfig = m.projectConfig(proj)
+	m.commands, m.commandKeys = commandList(m.pickerConfig)
+	m.commandIdx = m.defaultCommandIndex()
+	m.mode = ModeCommandPicker
+}
+
+// commandPickerConfig returns the config the command picker was opened with.
+func (m *Model) commandPickerConfig() *config.Config {
+	if m.pickerConfig != nil {
+		return m.pickerConfig
+	}
+	return m.config
+}
+
+// projectConfig returns the global config merged with the project's
+// .codely.yaml. Only local projects are read; problems are shown as notices
+// and fall back to the global config.
+func (m *Model) projectConfig(proj *domain.Project) *config.Config {
+	if proj == nil || proj.Type != domain.ProjectTypeLocal || proj.Directory == "" {
+		return m.config
+	}
+
+	pc, err := config.LoadProject(proj.Directory)
+	if err != nil {
+		debug.Log("projectConfig: project=%s err=%v", proj.ID, err)
+		m.addNotice(fmt.Sprintf("%s: %s", proj.Name, strings.ReplaceAll(err.Error(), "\n  ", " ")))
+		return m.config
+	}
+
+	cfg := m.config.WithProject(pc)
+	if pc != nil {
+		for _, w := range cfg.Warnings {
+			m.addNotice(fmt.Sprintf("%s: %s", proj.Name, w))
+		}
+	}
+	return cfg
+}
+
 func (m *Model) updateShedCreateFocus() {
 	m.shedCreateName.Blur()
 	m.shedCreateRepo.Blur()
@@ -1121,6 +1465,40 @@ func (m Model) startRenameSession() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m Model) startSendInput() (tea.Model, tea.Cmd) {
+	proj := m.SelectedProject()
+	sess := m.SelectedSession()
+	if proj == nil || sess == nil || sess.PaneID == 0 {
+		return m, nil
+	}
+
+	m.sendProjectID = proj.ID
+	m.sendSessionID = sess.ID
+	m.sendInput.SetValue("")
+	m.sendInput.Focus()
+	m.mode = ModeSendInput
+	return m, nil
+}
+
+func (m *Model) clearSendInputState() {
+	m.sendProjectID = ""
+	m.sendSessionID = ""
+	m.sendInput.SetValue("")
+	m.sendInput.Blur()
+}
+
+func (m Model) sendInputTarget() (*domain.Project, *domain.Session) {
+	proj, err := m.store.GetProject(m.sendProjectID)
+	if err != nil {
+		return nil, nil
+	}
+	sess, err := m.store.GetSession(m.sendProjectID, m.sendSessionID)
+	if err != nil {
+		return proj, nil
+	}
+	return proj, sess
+}
+
 func (m *Model) clearRenameState() {
 	m.renameProjectID = ""
 	m.renameSessionID = ""
@@ -1180,42 +1558,3 @@ func (m *Model) shedCreateServerIdx() int {
 	}
 	return -1
 }
-
-func containsIgnoreCase(s, substr string) bool {
-	return len(s) >= len(substr) &&
-		(s == substr ||
-			len(substr) == 0 ||
-			findIgnoreCase(s, substr) >= 0)
-}
-
-func findIgnoreCase(s, substr string) int {
-	if len(substr) == 0 {
-		return 0
-	}
-	if len(s) < len(substr) {
-		return -1
-	}
-
-	// Simple case-insensitive search
-	lowerS := toLower(s)
-	lowerSubstr := toLower(substr)
-
-	for i := 0; i <= len(lowerS)-len(lowerSubstr); i++ {
-		if lowerS[i:i+len(lowerSubstr)] == lowerSubstr {
-			return i
-		}
-	}
-	return -1
-}
-
-func toLower(s string) string {
-	b := make([]byte, len(s))
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= 'A' && c <= 'Z' {
-			c += 'a' - 'A'
-		}
-		b[i] = c
-	}
-	return string(b)
-}
@@ -38,6 +38,18 @@ func (m Model) View() string {
 		return m.confirmView()
 	case ModeNewProjectType:
 		return m.newProjectTypeView()
+	case ModeSendInput:
+		return m.sendInputView()
+	case ModeCloneRepo:
+		return m.cloneRepoView()
+	case ModeCloning:
+		return m.cloningView()
+	case ModeHistory:
+		return m.historyView()
+	case ModeWorkspaces:
+		return m.workspaceView()
+	case ModeResurrect:
+		return m.resurrectView()
 	default:
 		return m.normalView()
 	}
@@ -48,7 +60,7 @@ func (m Model) normalView() string {
 	var b strings.Builder
 
 	// Header
-	header := styleHeader.Width(m.width).Render(fmt.Sprintf("Codely%s", m.versionString()))
+	header := styleHeader.Width(m.width).Render(fmt.Sprintf("Codely%s%s", m.workspaceLabel(), m.versionString()))
 	b.WriteString(header)
 	b.WriteString("\n")
 
@@ -62,6 +74,12 @@ func (m Model) normalView() string {
 		b.WriteString(styleError.Render(fmt.Sprintf("⚠️  %s", m.err.Error())))
 	}
 
+	// Notice banner
+	if m.notice != "" {
+		b.WriteString("\n")
+		b.WriteString(styleNotice.Render(m.notice))
+	}
+
 	body := b.String()
 	footer := styleFooter.Width(m.width).Render(m.helpLine())
 
@@ -109,39 +127,54 @@ func (m Model) folderPickerView() string {
 	b.WriteString("Select directory:\n\n")
 
 	folders := m.filteredFolders()
+	searching := m.folderSearching && m.folderSearch.Value() != ""
 
-	// Group by parent directory
-	groups := make(map[string][]string)
-	var groupOrder []string
-
-	for _, f := range folders {
-		parent := filepath.Dir(f)
-		if _, ok := groups[parent]; !ok {
-			groupOrder = append(groupOrder, parent)
+	option := func(idx int, label string) {
+		prefix := "  ○ "
+		if idx == m.folderIdx {
+			b.WriteString(styleDialogOptionSelected.Render(prefix + label))
+		} else {
+			b.WriteString(styleDialogOption.Render(prefix + label))
 		}
-		groups[parent] = append(groups[parent], f)
+		b.WriteString("\n")
 	}
 
 	idx := 0
-	for _, parent := range groupOrder {
-		displayParent := pathutil.ContractHome(parent)
-
-		b.WriteString(styleProjectPath.Render(displayParent + "/"))
+	if searching {
+		// Ranked matches lose their grouping, so show full paths
+		for _, f := range folders {
+			option(idx, pathutil.ContractHome(f)+"/")
+			idx++
+		}
 		b.WriteString("\n")
+	} else if recent := len(m.recentFolders()); recent > 0 {
+		b.WriteString(styleProjectPath.Render("Recent"))
+		b.WriteString("\n")
+		for _, f := range folders[:recent] {
+			option(idx, pathutil.ContractHome(f)+"/")
+			idx++
+		}
+		b.WriteString("\n")
+	}
 
-		for _, f := range groups[parent] {
-			prefix := "  ○ "
-			name := filepath.Base(f) + "/"
-
-			if idx == m.folderIdx {
-				b.WriteString(styleDialogOptionSelected.Render(prefix + name))
-			} else {
-				b.WriteString(styleDialogOption.Render(prefix + name))
+	// Remaining folders arrive grouped by parent directory
+	if !searching {
+		parent := ""
+		for _, f := range folders[idx:] {
+			if dir := filepath.Dir(f); dir != parent {
+				if parent != "" {
+					b.WriteString("\n")
+				}
+				parent = dir
+				b.WriteString(styleProjectPath.Render(pathutil.ContractHome(parent) + "/"))
+				b.WriteString("\n")
 			}
-			b.WriteString("\n")
+			option(idx, filepath.Base(f)+"/")
 			idx++
 		}
-		b.WriteString("\n")
+		if parent != "" {
+			b.WriteString("\n")
+		}
 	}
 
 	// Search box
@@ -173,10 +206,11 @@ func (m Model) commandPickerView() string {
 	fmt.Fprintf(&b, "Path: %s\n\n", styleProjectPath.Render(projPath))
 	b.WriteString("Select command:\n\n")
 
+	cfg := m.commandPickerConfig()
 	for i, id := range m.commandKeys {
-		cmd := m.config.Commands[id]
+		cmd := cfg.Commands[id]
 		prefix := "○"
-		if id == m.config.DefaultCommand {
+		if id == cfg.DefaultCommand {
 			prefix = "●"
 		}
 
@@ -240,6 +274,32 @@ func (m Model) renameView() string {
 	return styleDialog.Render(b.String())
 }
 
+// sendInputView renders the dialog for typing text into a session.
+func (m Model) sendInputView() string {
+	var b strings.Builder
+
+	proj, sess := m.sendInputTarget()
+	sessionName := "Unknown"
+	projectName := "Unknown"
+	if sess != nil {
+		sessionName = sess.Command.Name()
+	}
+	if proj != nil {
+		projectName = proj.Name
+	}
+
+	b.WriteString(styleDialogTitle.Render("Send Input"))
+	b.WriteString("\n\n")
+	fmt.Fprintf(&b, "Session: %s\n", styleSessionName.Render(sessionName))
+	fmt.Fprintf(&b, "Project: %s\n", styleProjectName.Render(projectName))
+	b.WriteString("\n")
+	b.WriteString(m.sendInput.View())
+	b.WriteString("\n\n")
+	b.WriteString(styleHelp.Render("[enter] send + enter  [esc] cancel"))
+
+	return styleDialog.Render(b.String())
+}
+
 // shedPickerView renders the shed picker
 func (m Model) shedPickerView() string {
 	var b strings.Builder
@@ -403,6 +463,80 @@ func (m Model) shedCreatingView() string {
 	return styleDialog.Render(b.String())
 }
 
+// cloneRepoView renders the clone repository form
+func (m Model) cloneRepoView() string {
+	var b strings.Builder
+
+	b.WriteString(styleDialogTitle.Render("Clone Repository"))
+	b.WriteString("\n\n")
+
+	repoLabel := "Repository: "
+	if m.cloneFocus == 0 {
+		repoLabel = styleDialogOptionSelected.Render(repoLabel)
+	}
+	b.WriteString(repoLabel)
+	b.WriteString(m.cloneRepo.View())
+	b.WriteString("\n\n")
+
+	// Workspace root, a cycle selector when there are several
+	roots := m.config.WorkspaceRoots
+	rootLabel := "Clone into: "
+	if m.cloneFocus == 1 {
+		rootLabel = styleDialogOptionSelected.Render(rootLabel)
+	}
+	b.WriteString(rootLabel)
+	switch {
+	case len(roots) > 1:
+		fmt.Fprintf(&b, "< %s >", roots[min(m.cloneRoot, len(roots)-1)])
+	case len(roots) == 1:
+		b.WriteString(roots[0])
+	default:
+		b.WriteString("(no workspace_roots configured)")
+	}
+	b.WriteString("\n\n")
+
+	if m.err != nil {
+		b.WriteString(styleError.Render(m.err.Error()))
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString(styleHelp.Render("[tab] next field  [enter] clone  [esc] cancel"))
+
+	return styleDialog.Render(b.String())
+}
+
+// cloningView renders the clone-in-progress screen
+func (m Model) cloningView() string {
+	var b strings.Builder
+
+	b.WriteString(styleDialogTitle.Render("Clone Repository"))
+	b.WriteString("\n\n")
+
+	fmt.Fprintf(&b, "Cloning into %s...\n\n", pathutil.ContractHome(m.cloneDir))
+
+	if m.cloneCmd != "" {
+		b.WriteString(styleProjectPath.Render("$ " + m.cloneCmd))
+		b.WriteString("\n\n")
+	}
+
+	// Show last ~10 lines of output
+	lines := m.cloneOutput
+	if len(lines) > 10 {
+		lines = lines[len(lines)-10:]
+	}
+	for _, line := range lines {
+		b.WriteString(styleProjectPath.Render("> " + line))
+		b.WriteString("\n")
+	}
+	if len(lines) > 0 {
+		b.WriteString("\n")
+	}
+
+	b.WriteString(styleHelp.Render("[esc] cancel"))
+
+	return styleDialog.Render(b.String())
+}
+
 // shedCloseView renders the shed close options dialog
 func (m Model) shedCloseView() string {
 	var b strings.Builder
@@ -494,25 +628,24 @@ func (m Model) newProjectTypeView() string {
 	b.WriteString("\n\n")
 	b.WriteString("Select project type:\n\n")
 
-	options := []struct {
-		label string
-		desc  string
-	}{
-		{"Local Directory", "Create project from a local folder"},
-		{"Attach to Shed", "Connect to an existing remote shed"},
-		{"Create New Shed", "Create a new remote development container"},
+	labels := map[newProjectType][2]string{
+		newProjectLocal:      {"Local Directory", "Create project from a local folder"},
+		newProjectClone:      {"Clone Repository", "Clone a git repository into a workspace root"},
+		newProjectAttachShed: {"Attach to Shed", "Connect to an existing remote shed"},
+		newProjectCreateShed: {"Create New Shed", "Create a new remote development container"},
 	}
 
-	for i, opt := range options {
+	for i, t := range m.newProjectTypes {
+		label, desc := labels[t][0], labels[t][1]
 		prefix := "○"
 		if i == m.newProjectTypeIdx {
 			prefix = "●"
-			b.WriteString(styleDialogOptionSelected.Render(fmt.Sprintf("%s %s", prefix, opt.label)))
+			b.WriteString(styleDialogOptionSelected.Render(fmt.Sprintf("%s %s", prefix, label)))
 		} else {
-			b.WriteString(styleDialogOption.Render(fmt.Sprintf("%s %s", prefix, opt.label)))
+			b.WriteString(styleDialogOption.Render(fmt.Sprintf("%s %s", prefix, label)))
 		}
 		b.WriteString("\n")
-		b.WriteString(styleProjectPath.Render("    " + opt.desc))
+		b.WriteString(styleProjectPath.Render("    " + desc))
 		b.WriteString("\n\n")
 	}
 
@@ -0,0 +1,209 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/charliek/codely/internal/store"
+	"github.com/charliek/codely/internal/workspace"
+)
+
+// workspaceInfo summarizes a workspace for the picker
+type workspaceInfo struct {
+	Name     string
+	Projects int
+	Sessions int
+	Running  bool  // Its tmux session exists
+	Err      error // Why its state could not be read
+}
+
+// openWorkspaces lists the workspaces and shows the workspace picker
+func (m *Model) openWorkspaces() {
+	names, err := workspace.List()
+	if err != nil {
+		m.addNotice(fmt.Sprintf("workspaces: %v", err))
+		names = []string{m.workspace.Name}
+	}
+
+	m.workspaces = make([]workspaceInfo, 0, len(names)+1)
+	m.workspaceIdx = 0
+	current := false
+	for _, name := range names {
+		if name == m.workspace.Name {
+			m.workspaceIdx = len(m.workspaces)
+			current = true
+		}
+		m.workspaces = append(m.workspaces, m.workspaceInfo(name))
+	}
+	if !current {
+		// A new workspace has no directory until its first save
+		m.workspaceIdx = len(m.workspaces)
+		m.workspaces = append(m.workspaces, m.workspaceInfo(m.workspace.Name))
+	}
+
+	m.workspaceNaming = false
+	m.workspaceInput.SetValue("")
+	m.workspaceInput.Blur()
+	m.mode = ModeWorkspaces
+}
+
+// workspaceInfo reads the project and session counts of a workspace. The
+// current workspace is counted from memory; others are loaded read-only.
+func (m *Model) workspaceInfo(name string) workspaceInfo {
+	info := workspaceInfo{Name: name}
+	ws, err := workspace.Resolve(name)
+	if err != nil {
+		info.Err = err
+		return info
+	}
+
+	st := m.store
+	if name != m.workspace.Name {
+		st = store.New(ws.StatePath)
+		if err := st.Load(); err != nil {
+			info.Err = err
+			return info
+		}
+	}
+	for _, p := range st.Projects() {
+		info.Projects++
+		info.Sessions += len(p.Sessions)
+	}
+	info.Running = name == m.workspace.Name || m.tmux.HasSession(ws.TmuxSession)
+	return info
+}
+
+// handleWorkspaceKey handles keys in the workspace picker
+func (m Model) handleWorkspaceKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.workspaceNaming {
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.workspaceNaming = false
+			m.workspaceInput.Blur()
+			m.err = nil
+			return m, nil
+		case tea.KeyEnter:
+			name := strings.TrimSpace(m.workspaceInput.Value())
+			if err := workspace.Validate(name); err != nil {
+				m.err = err
+				return m, nil
+			}
+			return m.switchWorkspace(name)
+		}
+		var cmd tea.Cmd
+		m.workspaceInput, cmd = m.workspaceInput.Update(msg)
+		return m, cmd
+	}
+
+	switch {
+	case msg.Type == tea.KeyEsc, key.Matches(msg, m.keys.Quit), key.Matches(msg, m.keys.Workspaces):
+		m.mode = ModeNormal
+		m.workspaces = nil
+		return m, nil
+
+	case key.Matches(msg, m.keys.Up):
+		if m.workspaceIdx > 0 {
+			m.workspaceIdx--
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Down):
+		if m.workspaceIdx < len(m.workspaces)-1 {
+			m.workspaceIdx++
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.NewProject):
+		m.workspaceNaming = true
+		m.workspaceInput.SetValue("")
+		m.workspaceInput.Focus()
+		return m, nil
+
+	case key.Matches(msg, m.keys.Enter):
+		if m.workspaceIdx < len(m.workspaces) {
+			return m.switchWorkspace(m.workspaces[m.workspaceIdx].Name)
+		}
+	}
+
+	return m, nil
+}
+
+// switchWorkspace moves the tmux client to the named workspace's tmux
+// session, starting codely there first if it is not running. This codely
+// keeps running in its own session.
+func (m Model) switchWorkspace(name string) (tea.Model, tea.Cmd) {
+	m.mode = ModeNormal
+	m.workspaces = nil
+	m.workspaceNaming = false
+	m.workspaceInput.Blur()
+	if name == m.workspace.Name {
+		return m, nil
+	}
+
+	ws, err := workspace.Resolve(name)
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+	return m, m.switchWorkspaceCmd(ws)
+}
+
+// workspaceLabel returns the header suffix naming a non-default workspace
+func (m Model) workspaceLabel() string {
+	if m.workspace.Name == "" || m.workspace.IsDefault() {
+		return ""
+	}
+	return " · " + m.workspace.Name
+}
+
+// workspaceView renders the workspace picker
+func (m Model) workspaceView() string {
+	var b strings.Builder
+
+	b.WriteString(styleDialogTitle.Render("Workspaces"))
+	b.WriteString("\n\n")
+
+	for i, w := range m.workspaces {
+		var detail string
+		switch {
+		case w.Err != nil:
+			detail = "unreadable"
+		default:
+			detail = fmt.Sprintf("%d project(s), %d session(s)", w.Projects, w.Sessions)
+		}
+		if w.Name == m.workspace.Name {
+			detail += " (current)"
+		} else if w.Running {
+			detail += " (running)"
+		}
+
+		line := fmt.Sprintf("%-16s %s", truncate(w.Name, 16), detail)
+		if i == m.workspaceIdx {
+			b.WriteString(styleDialogOptionSelected.Render("● " + line))
+		} else {
+			b.WriteString(styleDialogOption.Render("○ " + line))
+		}
+		b.WriteString("\n")
+	}
+
+	if m.workspaceNaming {
+		b.WriteString("\n")
+		b.WriteString("New workspace:\n")
+		b.WriteString(m.workspaceInput.View())
+		b.WriteString("\n")
+		if m.err != nil {
+			b.WriteString(styleError.Render(m.err.Error()))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+		b.WriteString(styleHelp.Render("[enter] create and switch  [esc] cancel"))
+	} else {
+		b.WriteString("\n")
+		b.WriteString(styleHelp.Render("[enter] switch  [n] new workspace  [esc] back"))
+	}
+
+	return styleDialog.Render(b.String())
+}
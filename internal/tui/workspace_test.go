@@ -0,0 +1,101 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/codely/internal/config"
+	"github.com/charliek/codely/internal/domain"
+	"github.com/charliek/codely/internal/shed"
+	"github.com/charliek/codely/internal/store"
+	"github.com/charliek/codely/internal/tmux"
+	"github.com/charliek/codely/internal/workspace"
+)
+
+func TestWorkspacePickerSwitches(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// A named workspace with one project
+	other, err := workspace.Resolve("client-a")
+	require.NoError(t, err)
+	otherStore := store.New(other.StatePath)
+	require.NoError(t, otherStore.Load())
+	require.NoError(t, otherStore.AddProject(&domain.Project{
+		ID:       "proj-a",
+		Name:     "portal",
+		Sessions: []domain.Session{{ID: "sess-a"}},
+	}))
+	require.NoError(t, otherStore.Save())
+
+	current := workspace.Default()
+	st := store.New(current.StatePath)
+	tmuxClient := tmux.NewMockClient()
+	model := NewModel(config.Default(), st, tmuxClient, shed.NewMockClient(), 0, "", SkinTree)
+	model.codelyExe = "/usr/bin/codely"
+	model.codelyArgs = []string{"--skin", "flat"}
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("W")})
+	m := updated.(Model)
+	require.Equal(t, ModeWorkspaces, m.mode)
+	require.Len(t, m.workspaces, 2)
+	assert.Equal(t, "default", m.workspaces[0].Name)
+	assert.Equal(t, 0, m.workspaceIdx)
+	assert.Equal(t, workspaceInfo{Name: "client-a", Projects: 1, Sessions: 1}, m.workspaces[1])
+	assert.Contains(t, m.workspaceView(), "1 project(s), 1 session(s)")
+
+	// Switching starts codely in the workspace's tmux session
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = updated.(Model)
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	assert.Equal(t, ModeNormal, m.mode)
+	require.NotNil(t, cmd)
+
+	tmuxClient.Calls = nil
+	msg := cmd()
+	assert.Equal(t, WorkspaceSwitchedMsg{Name: "client-a"}, msg)
+	assert.Equal(t, []tmux.MockCall{
+		{Method: "HasSession", Args: []interface{}{"codely-client-a"}},
+		{Method: "CreateSession", Args: []interface{}{"codely-client-a", "/usr/bin/codely", []string{"--skin", "flat", "--workspace", "client-a"}}},
+		{Method: "SwitchClient", Args: []interface{}{"codely-client-a"}},
+	}, tmuxClient.Calls)
+}
+
+func TestWorkspacePickerNewWorkspace(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	st := store.New(t.TempDir() + "/state.json")
+	tmuxClient := tmux.NewMockClient()
+	tmuxClient.HasSessionResult = true
+	model := NewModel(config.Default(), st, tmuxClient, shed.NewMockClient(), 0, "", SkinTree)
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("W")})
+	updated, _ = updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	m := updated.(Model)
+	require.True(t, m.workspaceNaming)
+
+	// Invalid names are rejected in place
+	m.workspaceInput.SetValue("a/b")
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	assert.Nil(t, cmd)
+	assert.Error(t, m.err)
+	assert.Equal(t, ModeWorkspaces, m.mode)
+
+	m.workspaceInput.SetValue("side")
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	assert.Equal(t, ModeNormal, m.mode)
+	require.NotNil(t, cmd)
+
+	// A running workspace is switched to without starting it
+	tmuxClient.Calls = nil
+	cmd()
+	assert.Equal(t, []tmux.MockCall{
+		{Method: "HasSession", Args: []interface{}{"codely-side"}},
+		{Method: "SwitchClient", Args: []interface{}{"codely-side"}},
+	}, tmuxClient.Calls)
+}
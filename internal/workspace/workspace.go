@@ -0,0 +1,119 @@
+// Package workspace resolves named workspaces. Each workspace has its own
+// state file, control socket, session history, status event log and tmux
+// session, so unrelated efforts keep separate project lists.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/charliek/codely/internal/constants"
+	"github.com/charliek/codely/internal/pathutil"
+)
+
+// State, socket, history and events file names inside a named workspace's
+// directory
+const (
+	stateFile   = "session.json"
+	socketFile  = "control.sock"
+	historyFile = "history.jsonl"
+	eventsFile  = "events.jsonl"
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// Workspace is a resolved workspace and the paths it owns
+type Workspace struct {
+	Name        string
+	StatePath   string
+	SocketPath  string
+	HistoryPath string
+	EventsPath  string
+	TmuxSession string
+}
+
+// Resolve returns the workspace called name. An empty name is the default
+// workspace, which keeps the paths codely used before workspaces existed.
+func Resolve(name string) (Workspace, error) {
+	return resolveIn(constants.WorkspacesDir, name)
+}
+
+// Default returns the default workspace
+func Default() Workspace {
+	return Workspace{
+		Name:        constants.DefaultWorkspace,
+		StatePath:   constants.DefaultStatePath,
+		SocketPath:  constants.DefaultSocketPath,
+		HistoryPath: constants.DefaultHistoryPath,
+		EventsPath:  constants.DefaultEventsPath,
+		TmuxSession: constants.DefaultTmuxSession,
+	}
+}
+
+// resolveIn resolves name with named workspaces kept under dir
+func resolveIn(dir, name string) (Workspace, error) {
+	if name == "" || name == constants.DefaultWorkspace {
+		return Default(), nil
+	}
+	if err := Validate(name); err != nil {
+		return Workspace{}, err
+	}
+
+	root := filepath.Join(dir, name)
+	return Workspace{
+		Name:        name,
+		StatePath:   filepath.Join(root, stateFile),
+		SocketPath:  filepath.Join(root, socketFile),
+		HistoryPath: filepath.Join(root, historyFile),
+		EventsPath:  filepath.Join(root, eventsFile),
+		TmuxSession: constants.DefaultTmuxSession + "-" + name,
+	}, nil
+}
+
+// Validate reports whether name can be used as a workspace name. Names start
+// with a letter or digit and contain only letters, digits, '-' and '_',
+// since they become a directory and part of a tmux session name. tmux
+// replaces '.' and ':' in session names, so those are not allowed.
+func Validate(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid workspace name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// IsDefault reports whether w is the default workspace
+func (w Workspace) IsDefault() bool {
+	return w.Name == constants.DefaultWorkspace
+}
+
+// List returns the names of all workspaces: the default workspace first,
+// then each named workspace that has been used, sorted.
+func List() ([]string, error) {
+	return listIn(constants.WorkspacesDir)
+}
+
+// listIn lists the workspaces kept under dir
+func listIn(dir string) ([]string, error) {
+	names := []string{constants.DefaultWorkspace}
+
+	entries, err := os.ReadDir(pathutil.ExpandPath(dir))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return names, nil
+		}
+		return nil, fmt.Errorf("reading workspaces: %w", err)
+	}
+
+	var named []string
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != constants.DefaultWorkspace && Validate(e.Name()) == nil {
+			named = append(named, e.Name())
+		}
+	}
+	slices.Sort(named)
+	return append(names, named...), nil
+}
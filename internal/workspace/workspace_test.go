@@ -0,0 +1,65 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charliek/codely/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve_Default(t *testing.T) {
+	for _, name := range []string{"", "default"} {
+		ws, err := resolveIn("/ws", name)
+		require.NoError(t, err)
+		assert.True(t, ws.IsDefault())
+		assert.Equal(t, constants.DefaultStatePath, ws.StatePath)
+		assert.Equal(t, constants.DefaultSocketPath, ws.SocketPath)
+		assert.Equal(t, constants.DefaultHistoryPath, ws.HistoryPath)
+		assert.Equal(t, constants.DefaultEventsPath, ws.EventsPath)
+		assert.Equal(t, "codely", ws.TmuxSession)
+	}
+}
+
+func TestResolve_Named(t *testing.T) {
+	ws, err := resolveIn("/ws", "client-a")
+	require.NoError(t, err)
+	assert.False(t, ws.IsDefault())
+	assert.Equal(t, Workspace{
+		Name:        "client-a",
+		StatePath:   "/ws/client-a/session.json",
+		SocketPath:  "/ws/client-a/control.sock",
+		HistoryPath: "/ws/client-a/history.jsonl",
+		EventsPath:  "/ws/client-a/events.jsonl",
+		TmuxSession: "codely-client-a",
+	}, ws)
+}
+
+func TestValidate(t *testing.T) {
+	for _, name := range []string{"work", "client-a", "side_project", "v2"} {
+		assert.NoError(t, Validate(name), name)
+	}
+	// tmux would rename a session with '.' or ':' in it
+	for _, name := range []string{"", ".hidden", "-flag", "a/b", "..", "a b", "a:b", "v2.1"} {
+		assert.Error(t, Validate(name), name)
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+
+	names, err := listIn(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default"}, names)
+
+	for _, name := range []string{"work", "client-a", ".trash", "default"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, name), 0700))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0600))
+
+	names, err = listIn(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default", "client-a", "work"}, names)
+}